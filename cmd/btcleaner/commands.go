@@ -0,0 +1,527 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/client"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/events"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/Celedhrim/btcleaner/internal/notify"
+	"github.com/Celedhrim/btcleaner/internal/server"
+	"github.com/Celedhrim/btcleaner/pkg/models"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultConfigPath is where init-config writes the example configuration
+const defaultConfigPath = "btcleaner.yaml"
+
+// usage prints the commands and flags
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  btcleaner [flags]                  Run a cleanup check, or the daemon with --daemon
+  btcleaner run [flags]              Run one cleanup now (--dry-run to only list the torrents)
+  btcleaner daemon [flags]           Run the cleanup checks periodically
+  btcleaner serve [flags]            Run the daemon with the web UI and API
+  btcleaner candidates [flags]       List the torrents a cleanup would remove now
+  btcleaner stats [flags]            Show the free space and torrents per tracker
+  btcleaner history [flags]          List the deleted torrents (persisted with --data-dir)
+  btcleaner delete <id|hash>...      Delete torrents and their data
+  btcleaner init-config [path]       Write an example config file (default: %s)
+  btcleaner config validate [flags]  Check the configuration, reporting every invalid setting
+  btcleaner config show [flags]      Print the effective configuration, secrets redacted
+
+Commands listing data print tables, or JSON with --json.
+
+With --server, run, candidates, stats, history, delete and config show call
+the API of a running instance instead of Transmission, authenticated with
+the %s environment variable (--token also works, with a warning:
+other users can read it in the process list).
+
+Flags:
+`, defaultConfigPath, tokenEnv)
+	pflag.PrintDefaults()
+}
+
+// runCommand runs a subcommand given as positional arguments
+func runCommand(args []string) error {
+	if serverURL() != "" {
+		switch args[0] {
+		case "daemon", "serve", "init-config":
+			return fmt.Errorf("%s runs locally and cannot be used with --server", args[0])
+		}
+	}
+
+	switch args[0] {
+	case "run":
+		return runCleanupCommand()
+	case "daemon":
+		pflag.Set("daemon", "true")
+		return runService()
+	case "serve":
+		pflag.Set("daemon", "true")
+		pflag.Set("web-ui", "true")
+		return runService()
+	case "candidates":
+		return runCandidatesCommand()
+	case "stats":
+		return runStatsCommand()
+	case "history":
+		return runHistoryCommand()
+	case "delete":
+		return runDeleteCommand(args[1:])
+	case "init-config":
+		return runInitConfigCommand(args[1:])
+	case "config":
+		return runConfigCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, see btcleaner --help", args[0])
+	}
+}
+
+// command holds what the commands working on torrents need
+type command struct {
+	cfg   *config.Config
+	log   *logger.Logger
+	clean *cleaner.Cleaner
+}
+
+// newCommand loads the configuration and creates the Transmission client and
+// cleaner. Logs go to stderr, so the output can be piped.
+func newCommand() (*command, error) {
+	cfg, err := config.Load(Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	log, err := newLogger(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	clean := cleaner.New(newTransmissionClient(cfg), policyFromConfig(cfg), cfg.DryRun, log)
+	if cfg.DataDir != "" {
+		if err := clean.SetDataDir(cfg.DataDir); err != nil {
+			return nil, fmt.Errorf("failed to load data directory: %w", err)
+		}
+	}
+	return &command{cfg: cfg, log: log, clean: clean}, nil
+}
+
+// jsonOutput reports whether --json is set
+func jsonOutput() bool {
+	enabled, _ := pflag.CommandLine.GetBool("json")
+	return enabled
+}
+
+// printJSON prints v as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// newTable returns a writer aligning tab-separated columns
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+// formatGB formats a size in bytes as GB
+func formatGB(bytes int64) string {
+	return fmt.Sprintf("%.2f GB", float64(bytes)/(1024*1024*1024))
+}
+
+// runCleanupCommand runs one cleanup and prints its result
+func runCleanupCommand() error {
+	api, err := remoteClient()
+	if err != nil {
+		return err
+	}
+
+	var result *cleaner.CleanupResult
+	if api != nil {
+		result, err = runRemoteCleanup(api)
+	} else {
+		result, err = runLocalCleanup()
+	}
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+	if jsonOutput() {
+		return printJSON(result)
+	}
+
+	if !result.NeedCleanup {
+		fmt.Printf("No cleanup needed: %s free (minimum %s)\n", formatGB(result.InitialFreeSpace), formatGB(result.MinFreeSpace))
+		return nil
+	}
+	verb := "Removed"
+	if result.DryRun {
+		verb = "Dry run, would remove"
+	}
+	fmt.Printf("%s %d torrents (%s)\n\n", verb, result.RemovedCount, formatGB(result.RemovedSize))
+	if len(result.RemovedTorrents) > 0 {
+		printTorrents(result.RemovedTorrents)
+	}
+	if result.Blocked != nil {
+		fmt.Printf("\n%s\n", result.Blocked.Message)
+	}
+	for _, e := range result.Errors {
+		fmt.Printf("Error: %s\n", e)
+	}
+	return nil
+}
+
+// runLocalCleanup runs one cleanup with Transmission, sending the notifications
+// of the config file
+func runLocalCleanup() (*cleaner.CleanupResult, error) {
+	cmd, err := newCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	bus := events.NewBus()
+	cmd.clean.SetEventBus(bus)
+	if len(cmd.cfg.Notifications) > 0 {
+		notifier, err := notify.New(cmd.cfg.Notifications, cmd.log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notifications: %w", err)
+		}
+		notifier.Watch(bus)
+		defer notifier.Close()
+	}
+
+	return cmd.clean.RunWithOptions(cleaner.RunOptions{Trigger: cleaner.TriggerCLI})
+}
+
+// runRemoteCleanup requests a cleanup from the instance and waits for its
+// result. --dry-run and --min-free-space override its settings for this run.
+func runRemoteCleanup(api *client.Client) (*cleaner.CleanupResult, error) {
+	var req server.CleanupRunRequest
+	if pflag.Lookup("dry-run").Changed {
+		dryRun, _ := pflag.CommandLine.GetBool("dry-run")
+		req.DryRun = &dryRun
+	}
+	if pflag.Lookup("min-free-space").Changed {
+		gb, _ := pflag.CommandLine.GetInt64("min-free-space")
+		req.MinFreeSpace = gb * 1024 * 1024 * 1024
+	}
+
+	record, err := api.RunCleanup(req)
+	if err != nil {
+		return nil, err
+	}
+	return &record.CleanupResult, nil
+}
+
+// printTorrents prints a table of torrents
+func printTorrents(torrents []models.Torrent) {
+	w := newTable()
+	fmt.Fprintln(w, "ID\tNAME\tTRACKER\tSIZE\tADDED")
+	for _, t := range torrents {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.NormalizedTracker, formatGB(t.TotalSize), t.AddedDate.Format("2006-01-02"))
+	}
+	w.Flush()
+}
+
+// runCandidatesCommand lists the torrents a cleanup would remove now
+func runCandidatesCommand() error {
+	api, err := remoteClient()
+	if err != nil {
+		return err
+	}
+
+	var candidates []*models.Torrent
+	if api != nil {
+		var remote *server.CandidatesResponse
+		if remote, err = api.Candidates(); err == nil {
+			candidates = remote.Candidates
+		}
+	} else {
+		var cmd *command
+		if cmd, err = newCommand(); err != nil {
+			return err
+		}
+		candidates, err = cmd.clean.GetCandidates()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get candidates: %w", err)
+	}
+	resp := server.CandidatesResponse{Candidates: candidates, Count: len(candidates)}
+	torrents := make([]models.Torrent, len(candidates))
+	for i, t := range candidates {
+		resp.TotalSizeBytes += t.TotalSize
+		torrents[i] = *t
+	}
+	if jsonOutput() {
+		return printJSON(resp)
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("No torrents to remove")
+		return nil
+	}
+	printTorrents(torrents)
+	fmt.Printf("\n%d torrents, %s\n", resp.Count, formatGB(resp.TotalSizeBytes))
+	return nil
+}
+
+// runStatsCommand shows the free space and torrents per tracker
+func runStatsCommand() error {
+	api, err := remoteClient()
+	if err != nil {
+		return err
+	}
+
+	var stats *cleaner.Stats
+	if api != nil {
+		stats, err = api.Stats()
+	} else {
+		var cmd *command
+		if cmd, err = newCommand(); err != nil {
+			return err
+		}
+		stats, err = cmd.clean.GetStats()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stats: %w", err)
+	}
+	if jsonOutput() {
+		return printJSON(stats)
+	}
+
+	w := newTable()
+	fmt.Fprintf(w, "Free space:\t%s\n", formatGB(stats.FreeSpaceBytes))
+	if stats.PendingDownloadBytes > 0 || stats.Predictive {
+		fmt.Fprintf(w, "Projected free space:\t%s (%s downloading)\n",
+			formatGB(stats.ProjectedFreeSpaceBytes), formatGB(stats.PendingDownloadBytes))
+	}
+	fmt.Fprintf(w, "Minimum free space:\t%s\n", formatGB(stats.MinFreeSpaceBytes))
+	fmt.Fprintf(w, "Torrents:\t%d (%s)\n", stats.TotalTorrents, formatGB(stats.TotalSpaceBytes))
+	if trend := stats.Trend; trend.FillRateBytesPerHour > 0 {
+		fmt.Fprintf(w, "Fill rate:\t%s/h (last %v)\n", formatGB(trend.FillRateBytesPerHour),
+			(time.Duration(trend.WindowSeconds) * time.Second).Round(time.Minute))
+		if trend.TimeToThresholdSeconds != nil {
+			fmt.Fprintf(w, "Minimum reached:\tin %v (%s)\n",
+				(time.Duration(*trend.TimeToThresholdSeconds) * time.Second).Round(time.Minute),
+				trend.ThresholdAt.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	if stats.NeedsCleanup {
+		fmt.Fprintf(w, "Cleanup needed:\t%d candidates, %s to recover\n", stats.CandidatesCount, formatGB(stats.SpaceToRecoverBytes))
+	} else {
+		fmt.Fprintf(w, "Cleanup needed:\tno\n")
+	}
+	w.Flush()
+	if stats.Blocked != nil {
+		fmt.Printf("\n%s\n", stats.Blocked.Message)
+	}
+
+	fmt.Println()
+	w = newTable()
+	fmt.Fprintln(w, "TRACKER\tTORRENTS\tSIZE")
+	sort.Slice(stats.TrackerStats, func(i, j int) bool { return stats.TrackerStats[i].Name < stats.TrackerStats[j].Name })
+	for _, ts := range stats.TrackerStats {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", ts.Name, ts.Count, formatGB(ts.SizeBytes))
+	}
+	w.Flush()
+	return nil
+}
+
+// runHistoryCommand lists the deleted torrents
+func runHistoryCommand() error {
+	api, err := remoteClient()
+	if err != nil {
+		return err
+	}
+
+	var history []cleaner.DeletedTorrent
+	persisted := true
+	if api != nil {
+		if history, err = api.History(); err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+	} else {
+		cmd, err := newCommand()
+		if err != nil {
+			return err
+		}
+		history = cmd.clean.GetHistory()
+		persisted = cmd.cfg.DataDir != ""
+	}
+	if jsonOutput() {
+		return printJSON(server.HistoryResponse{History: history})
+	}
+
+	if len(history) == 0 {
+		if !persisted {
+			fmt.Println("No history: set data_dir to keep it across runs")
+		} else {
+			fmt.Println("No torrents deleted yet")
+		}
+		return nil
+	}
+	w := newTable()
+	fmt.Fprintln(w, "DELETED\tID\tNAME\tTRACKER\tSIZE\tREASON\tRUN")
+	for _, d := range history {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", d.DeletedAt.Local().Format(time.DateTime), d.ID, d.Name, d.Tracker, formatGB(d.Size), d.Reason, d.RunID)
+	}
+	w.Flush()
+	return nil
+}
+
+// runDeleteCommand deletes torrents by ID or hash
+func runDeleteCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: btcleaner delete <id|hash>...")
+	}
+	api, err := remoteClient()
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	var hashes []string
+	for _, arg := range args {
+		if id, err := strconv.Atoi(arg); err == nil {
+			ids = append(ids, id)
+		} else {
+			hashes = append(hashes, arg)
+		}
+	}
+
+	var results []cleaner.DeleteResult
+	if api != nil {
+		results, err = api.DeleteTorrents(ids, hashes)
+	} else {
+		var cmd *command
+		if cmd, err = newCommand(); err != nil {
+			return err
+		}
+		results, err = cmd.clean.DeleteTorrents(ids, hashes, true)
+	}
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Status != cleaner.DeleteStatusDeleted {
+			failed++
+		}
+	}
+	if jsonOutput() {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		w := newTable()
+		fmt.Fprintln(w, "REF\tID\tNAME\tSIZE\tSTATUS")
+		for _, r := range results {
+			status := r.Status
+			if r.Error != "" && r.Status == cleaner.DeleteStatusFailed {
+				status += ": " + r.Error
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", r.Ref, r.ID, r.Name, formatGB(r.SizeBytes), status)
+		}
+		w.Flush()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d torrents not deleted", failed, len(results))
+	}
+	return nil
+}
+
+// runInitConfigCommand writes an example config file
+func runInitConfigCommand(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: btcleaner init-config [path]")
+	}
+	path := defaultConfigPath
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := config.GenerateExampleConfig(path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Example configuration written to %s\n", path)
+	return nil
+}
+
+// runConfigCommand runs "btcleaner config validate|show"
+func runConfigCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: btcleaner config validate|show [flags]")
+	}
+
+	api, err := remoteClient()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "validate":
+		if api != nil {
+			return fmt.Errorf("config validate checks the local configuration and cannot be used with --server")
+		}
+		// Every invalid setting is reported with its source, for CI checks
+		cfg, err := config.Load(Version)
+		if err != nil {
+			return err
+		}
+		if cfg.File != "" {
+			fmt.Printf("Configuration is valid (config file: %s)\n", cfg.File)
+		} else {
+			fmt.Println("Configuration is valid (no config file)")
+		}
+		return nil
+
+	case "show":
+		// The effective configuration, merged from the config file,
+		// environment variables and flags, or the one of the instance
+		var file string
+		var redacted map[string]interface{}
+		if api != nil {
+			remote, err := api.Config()
+			if err != nil {
+				return fmt.Errorf("failed to get configuration: %w", err)
+			}
+			file, redacted = remote.File, remote.Config
+		} else {
+			cfg, err := config.Load(Version)
+			if err != nil {
+				return err
+			}
+			file, redacted = cfg.File, cfg.Redacted()
+		}
+		if jsonOutput() {
+			return printJSON(redacted)
+		}
+		if file != "" {
+			fmt.Printf("# Config file: %s\n", file)
+		}
+		fmt.Println("# Secrets are redacted")
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(redacted); err != nil {
+			return fmt.Errorf("failed to encode configuration: %w", err)
+		}
+		return enc.Close()
+
+	default:
+		return fmt.Errorf("unknown config command %q (expected validate or show)", args[0])
+	}
+}
@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Celedhrim/btcleaner/internal/cleaner"
 	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/events"
 	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/Celedhrim/btcleaner/internal/metrics"
+	"github.com/Celedhrim/btcleaner/internal/notify"
 	"github.com/Celedhrim/btcleaner/internal/server"
 	"github.com/Celedhrim/btcleaner/internal/transmission"
+	"github.com/Celedhrim/btcleaner/internal/watch"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -19,6 +30,9 @@ var (
 	Version = "dev"
 )
 
+// maxConnectDelay is the longest delay between two connection attempts at startup
+const maxConnectDelay = 30 * time.Second
+
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -27,6 +41,28 @@ func main() {
 }
 
 func run() error {
+	pflag.Usage = usage
+	config.ParseFlags(Version)
+	if pflag.NArg() > 0 {
+		return runCommand(pflag.Args())
+	}
+	if serverURL() != "" {
+		return fmt.Errorf("--server needs a command, see btcleaner --help")
+	}
+	return runService()
+}
+
+// runService runs a cleanup check, or the daemon, with the optional web server
+func runService() error {
+	if healthcheck, _ := pflag.CommandLine.GetBool("healthcheck"); healthcheck {
+		// Run often by Docker: the password command must not run every time
+		cfg, err := config.LoadUnresolved(Version)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return runHealthcheck(cfg)
+	}
+
 	// Load configuration
 	cfg, err := config.Load(Version)
 	if err != nil {
@@ -34,76 +70,246 @@ func run() error {
 	}
 
 	// Initialize logger
-	log, err := logger.New(cfg.LogLevel)
+	log, err := newLogger(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
+	if cfg.LogFile != "" {
+		if err := log.SetFile(cfg.LogFile, cfg.LogMaxSize, cfg.LogMaxAge, cfg.LogMaxBackups); err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+	}
 
 	log.Infof("BTCleaner %s starting...", Version)
 	log.Infof("Transmission URL: %s", cfg.Transmission.URL)
 	log.Infof("Min free space: %.2f GB", float64(cfg.Cleaner.MinFreeSpace)/(1024*1024*1024))
 	log.Infof("Min torrents per tracker: %d", cfg.Cleaner.MinTorrentsPerTracker)
+	for _, tm := range cfg.Cleaner.TrackerMinimums {
+		log.Infof("Min torrents for tracker %s: %d", tm.Tracker, tm.MinTorrents)
+	}
+	log.Infof("Removal strategy: %s", cfg.Cleaner.Strategy)
+	if cfg.Cleaner.Predictive {
+		log.Info("Predictive cleanup: active downloads are counted as used space")
+	}
+	// Secrets, notification URLs and headers are redacted
+	log.Debugf("Effective configuration: %v", cfg.Redacted())
 	
 	if cfg.DryRun {
 		log.Warn("DRY RUN MODE: No torrents will be actually removed")
 	}
 
 	// Create Transmission client
-	client := transmission.NewClient(
-		cfg.Transmission.URL,
-		cfg.Transmission.Username,
-		cfg.Transmission.Password,
-	)
-
-	// Test connection
-	log.Info("Testing connection to Transmission...")
-	if err := client.TestConnection(); err != nil {
-		return fmt.Errorf("failed to connect to Transmission: %w", err)
+	client := newTransmissionClient(cfg)
+	if cfg.Transmission.PasswordFile != "" {
+		stopWatch, err := config.WatchFile(cfg.Transmission.PasswordFile, func() {
+			password, err := cfg.Transmission.ResolvePassword()
+			if err != nil {
+				log.Errorf("Failed to reload Transmission password: %v", err)
+				return
+			}
+			client.SetCredentials(cfg.Transmission.Username, password)
+			log.Info("Transmission password reloaded from file")
+		})
+		if err != nil {
+			log.Warnf("Cannot watch password file, changes will need a restart: %v", err)
+		} else {
+			defer stopWatch()
+		}
 	}
-	log.Info("Successfully connected to Transmission")
 
 	// Create cleaner
-	clean := cleaner.New(
-		client,
-		cfg.Cleaner.MinFreeSpace,
-		cfg.Cleaner.MinTorrentsPerTracker,
-		cfg.DryRun,
-		log,
-	)
+	clean := cleaner.New(client, policyFromConfig(cfg), cfg.DryRun, log)
+	bus := events.NewBus()
+	clean.SetEventBus(bus)
+	if len(cfg.Notifications) > 0 {
+		notifier, err := notify.New(cfg.Notifications, log)
+		if err != nil {
+			return fmt.Errorf("failed to create notifications: %w", err)
+		}
+		notifier.Watch(bus)
+		defer notifier.Close() // Sends the pending notifications, e.g. of a one-shot run
+		log.Infof("Notifications enabled: %d sinks", len(cfg.Notifications))
+	}
+	if cfg.DataDir != "" {
+		if err := clean.SetDataDir(cfg.DataDir); err != nil {
+			return fmt.Errorf("failed to load data directory: %w", err)
+		}
+	}
 
 	// Start web server if enabled
 	var webServer *server.Server
 	if cfg.Server.Enabled {
-		webServer = server.New(cfg.Server.Port, cfg.Server.WebRoot, Version, clean, client, log)
+		webServer, err = server.New(cfg.Server, Version, clean, client, log)
+		if err != nil {
+			return fmt.Errorf("failed to create web server: %w", err)
+		}
+		webServer.SetEventBus(bus)
+		if cfg.Server.Metrics {
+			m := metrics.New(clean, log)
+			m.Watch(bus)
+			defer m.Close()
+			client.SetRequestObserver(m.ObserveRequest)
+			webServer.SetMetricsHandler(m.Handler())
+		}
+		webServer.SetReadyMaxAge(readyMaxAge(cfg))
 		// Connect logger to server for WebSocket broadcasting
 		log.SetCallback(func(entry logger.LogEntry) {
 			webServer.BroadcastLog(entry)
 		})
 		go func() {
-			if err := webServer.Start(); err != nil {
+			if err := webServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				log.Errorf("Web server error: %v", err)
 			}
 		}()
 	}
 
+	// Apply configuration changes without restart
+	reload := newReloader(cfg, clean, log, bus, webServer)
+	defer reload.watch()()
+	if webServer != nil {
+		webServer.SetSettingsManager(reload)
+	}
+
+	// Wait for Transmission, the web server already answers health checks
+	if err := waitForTransmission(client, cfg.Transmission.StartupTimeout, log); err != nil {
+		if webServer != nil {
+			webServer.Stop()
+		}
+		return err
+	}
+
 	// Run in appropriate mode
 	if cfg.Daemon.Enabled {
-		return runDaemon(clean, cfg, log, webServer)
+		var watcher *watch.Watcher
+		if cfg.Daemon.Watch.Enabled {
+			watcher = watch.New(client, clean, cfg.Daemon.Watch, log)
+			defer watcher.Start()()
+		}
+		return runDaemon(clean, cfg, log, webServer, reload.schedules, watcher)
+	}
+	if cfg.Daemon.Watch.Enabled {
+		log.Warn("daemon.watch is enabled but daemon mode is not, ignoring it")
 	}
 	
 	return runOneShot(clean, log, webServer)
 }
 
+// waitForTransmission tests the connection to Transmission, retrying with a
+// growing delay until it answers, the timeout expires (0: never) or a signal is received
+func waitForTransmission(client *transmission.Client, timeout time.Duration, log *logger.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	log.Info("Testing connection to Transmission...")
+	delay := time.Second
+	for {
+		err := client.TestConnection()
+		if err == nil {
+			log.Info("Successfully connected to Transmission")
+			return nil
+		}
+		log.Warnf("Transmission is not reachable, retrying in %v: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("failed to connect to Transmission after %v: %w", timeout, err)
+			}
+			return fmt.Errorf("interrupted while waiting for Transmission: %w", err)
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxConnectDelay {
+			delay = maxConnectDelay
+		}
+	}
+}
+
+// readyMaxAge returns the maximum age of the last successful check for the
+// server to be ready: the configured one, or 3 check intervals in daemon mode
+func readyMaxAge(cfg *config.Config) time.Duration {
+	if cfg.Server.ReadyMaxAge > 0 {
+		return cfg.Server.ReadyMaxAge
+	}
+	if cfg.Daemon.Enabled {
+		if w := cfg.Daemon.Watch; w.Enabled && w.MaxInterval > cfg.Daemon.CheckInterval && cfg.Daemon.Schedule == "" {
+			// Idle checks back off up to the maximum interval
+			return 3 * w.MaxInterval
+		}
+		if sched, err := newSchedule(cfg.Daemon); err == nil {
+			// Longest time between two checks of a cron schedule over its next runs
+			return 3 * sched.LongestGap(time.Now(), 32)
+		}
+		return 3 * cfg.Daemon.CheckInterval
+	}
+	return 0 // One-shot mode: the only check never gets more recent
+}
+
+// newTransmissionClient creates the Transmission client of a configuration
+func newTransmissionClient(cfg *config.Config) *transmission.Client {
+	client := transmission.NewClient(
+		cfg.Transmission.URL,
+		cfg.Transmission.Username,
+		cfg.Transmission.Password.Value(),
+	)
+
+	// Re-read credentials from their source when they are rotated
+	if cfg.Transmission.PasswordFile != "" || cfg.Transmission.PasswordCommand != "" {
+		client.SetCredentialSource(func() (string, string, error) {
+			password, err := cfg.Transmission.ResolvePassword()
+			return cfg.Transmission.Username, password, err
+		})
+	}
+	return client
+}
+
+// runHealthcheck queries /readyz on the local web server and fails if it is
+// not ready. Without web server, there is nothing to check.
+func runHealthcheck(cfg *config.Config) error {
+	if !cfg.Server.Enabled {
+		fmt.Println("Web server disabled, nothing to check")
+		return nil
+	}
+
+	webRoot := strings.TrimSuffix(cfg.Server.WebRoot, "/")
+	if webRoot != "" && !strings.HasPrefix(webRoot, "/") {
+		webRoot = "/" + webRoot
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d%s/readyz", cfg.Server.Port, webRoot)
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Println(strings.TrimSpace(string(body)))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("not ready (HTTP %d)", resp.StatusCode)
+	}
+	return nil
+}
+
 func runOneShot(clean *cleaner.Cleaner, log *logger.Logger, webServer *server.Server) error {
 	log.Info("Running in one-shot mode")
 	
-	result, err := clean.Run()
+	result, err := clean.RunWithOptions(cleaner.RunOptions{Trigger: cleaner.TriggerStartup})
 	if err != nil {
 		return fmt.Errorf("cleanup failed: %w", err)
 	}
 
 	if result.NeedCleanup {
-		log.Infof("Cleanup completed: removed %d torrents (%.2f GB freed)",
+		log.WithFields(logrus.Fields{
+			logger.FieldRunID: result.RunID,
+			logger.FieldBytes: result.RemovedSize,
+		}).Infof("Cleanup completed: removed %d torrents (%.2f GB freed)",
 			result.RemovedCount,
 			float64(result.RemovedSize)/(1024*1024*1024))
 	} else {
@@ -123,31 +329,79 @@ func runOneShot(clean *cleaner.Cleaner, log *logger.Logger, webServer *server.Se
 	return nil
 }
 
-func runDaemon(clean *cleaner.Cleaner, cfg *config.Config, log *logger.Logger, webServer *server.Server) error {
-	log.Infof("Running in daemon mode (check interval: %v)", cfg.Daemon.CheckInterval)
+func runDaemon(clean *cleaner.Cleaner, cfg *config.Config, log *logger.Logger, webServer *server.Server, schedules <-chan config.DaemonConfig, watcher *watch.Watcher) error {
+	sched, err := newSchedule(cfg.Daemon)
+	if err != nil {
+		return fmt.Errorf("invalid daemon schedule: %w", err)
+	}
+	daemon := &daemonSchedule{sched: sched}
+	clean.SetScheduleSource(daemon.state)
+
+	log.Infof("Running in daemon mode (checks %s)", sched)
+	if sched.Windowed() {
+		log.Infof("Deletion windows: %v, blocked windows: %v, emergency free space: %.2f GB",
+			cfg.Daemon.DeleteWindows, cfg.Daemon.BlockedWindows, float64(cfg.Daemon.EmergencyFreeSpace)/(1024*1024*1024))
+	}
+
+	// Event-driven checks, periodic ones backing off while nothing happens
+	var triggers <-chan struct{}
+	if watcher != nil {
+		triggers = watcher.Triggers()
+		daemon.setBackoff(watcher.LastActivity, cfg.Daemon.Watch.MaxInterval)
+		log.Infof("Event-driven checks enabled (idle checks back off up to %v)", cfg.Daemon.Watch.MaxInterval)
+	}
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Create ticker
-	ticker := time.NewTicker(cfg.Daemon.CheckInterval)
-	defer ticker.Stop()
-
 	// Run immediately on start
 	log.Info("Running initial cleanup check...")
-	if err := runCleanupCheck(clean, log); err != nil {
+	if err := runCleanupCheck(clean, log, daemon.runOptions(cleaner.TriggerStartup, time.Now())); err != nil {
 		log.Errorf("Initial cleanup check failed: %v", err)
 	}
 
+	// Checks follow the schedule from the end of the previous one
+	timer := time.NewTimer(daemon.advance(time.Now()))
+	defer timer.Stop()
+
 	// Main daemon loop
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
 			log.Debug("Running periodic cleanup check...")
-			if err := runCleanupCheck(clean, log); err != nil {
+			if err := runCleanupCheck(clean, log, daemon.runOptions(cleaner.TriggerTimer, time.Now())); err != nil {
 				log.Errorf("Cleanup check failed: %v", err)
 			}
+			timer.Reset(daemon.advance(time.Now()))
+
+		case <-triggers:
+			if err := runCleanupCheck(clean, log, daemon.runOptions(cleaner.TriggerEvent, time.Now())); err != nil {
+				log.Errorf("Cleanup check failed: %v", err)
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(daemon.advance(time.Now()))
+
+		case settings := <-schedules:
+			sched, err := newSchedule(settings)
+			if err != nil {
+				log.Errorf("Invalid daemon schedule, keeping the current one: %v", err)
+				continue
+			}
+			daemon.set(sched)
+			log.Debugf("Check schedule changed to %s", sched)
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(daemon.advance(time.Now()))
 
 		case sig := <-sigChan:
 			log.Infof("Received signal %v, shutting down gracefully...", sig)
@@ -159,14 +413,30 @@ func runDaemon(clean *cleaner.Cleaner, cfg *config.Config, log *logger.Logger, w
 	}
 }
 
-func runCleanupCheck(clean *cleaner.Cleaner, log *logger.Logger) error {
-	result, err := clean.Run()
+// newLogger creates the logger of the log settings, logging to stderr
+func newLogger(cfg *config.Config) (*logger.Logger, error) {
+	log, err := logger.New(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	if err := log.SetFormat(cfg.LogFormat); err != nil {
+		return nil, err
+	}
+	log.SetBufferSize(cfg.LogBufferSize)
+	return log, nil
+}
+
+func runCleanupCheck(clean *cleaner.Cleaner, log *logger.Logger, opts cleaner.RunOptions) error {
+	result, err := clean.RunWithOptions(opts)
 	if err != nil {
 		return err
 	}
 
 	if result.NeedCleanup && result.RemovedCount > 0 {
-		log.Infof("Cleanup completed: removed %d torrents (%.2f GB freed)",
+		log.WithFields(logrus.Fields{
+			logger.FieldRunID: result.RunID,
+			logger.FieldBytes: result.RemovedSize,
+		}).Infof("Cleanup completed: removed %d torrents (%.2f GB freed)",
 			result.RemovedCount,
 			float64(result.RemovedSize)/(1024*1024*1024))
 	}
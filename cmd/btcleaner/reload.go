@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/events"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/Celedhrim/btcleaner/internal/server"
+	"github.com/sirupsen/logrus"
+)
+
+// Configuration reload triggers
+const (
+	reloadSourceFile   = "file"
+	reloadSourceSignal = "signal"
+	reloadSourceAPI    = "api"
+)
+
+// reloadable lists the settings applied without restart, by key prefix
+var reloadable = []string{
+	"cleaner.", "log_level:", "log_format:", "log_buffer_size:", "dry_run:",
+	"daemon.check_interval:", "daemon.schedule:", "daemon.delete_windows:", "daemon.blocked_windows:", "daemon.emergency_free_space:",
+	"daemon.policy_schedules:",
+}
+
+// reloader applies configuration changes to the running cleaner, logger and
+// daemon schedule
+type reloader struct {
+	mu        sync.Mutex
+	cfg       *config.Config // Last configuration applied
+	clean     *cleaner.Cleaner
+	log       *logger.Logger
+	bus       *events.Bus
+	webServer *server.Server           // nil without web server
+	schedules chan config.DaemonConfig // New schedules for the daemon loop
+	unsaved   bool                     // Settings changed through the API and not written to the config file
+	saved     [sha256.Size]byte        // Hash of the config file last written by UpdateSettings
+}
+
+// newReloader creates a reloader for the configuration in use
+func newReloader(cfg *config.Config, clean *cleaner.Cleaner, log *logger.Logger, bus *events.Bus, webServer *server.Server) *reloader {
+	return &reloader{
+		cfg:       cfg,
+		clean:     clean,
+		log:       log,
+		bus:       bus,
+		webServer: webServer,
+		schedules: make(chan config.DaemonConfig, 1),
+	}
+}
+
+// policyFromConfig returns the cleanup policy of a configuration
+func policyFromConfig(cfg *config.Config) cleaner.Policy {
+	return cleaner.Policy{
+		MinFreeSpace:          cfg.Cleaner.MinFreeSpace,
+		MinTorrentsPerTracker: cfg.Cleaner.MinTorrentsPerTracker,
+		TrackerMinimums:       cfg.Cleaner.TrackerMinimumsMap(),
+		Strategy:              cfg.Cleaner.Strategy,
+		Predictive:            cfg.Cleaner.Predictive,
+	}
+}
+
+// watch reloads the configuration when the config file changes or SIGHUP is
+// received. The returned function stops watching.
+func (r *reloader) watch() func() {
+	stopFile := func() {}
+	if r.cfg.File != "" {
+		stop, err := config.WatchFile(r.cfg.File, func() { r.reload(reloadSourceFile) })
+		if err != nil {
+			r.log.Warnf("Cannot watch config file, send SIGHUP to reload it: %v", err)
+		} else {
+			stopFile = stop
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hup:
+				r.reload(reloadSourceSignal)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(hup)
+		close(done)
+		stopFile()
+	}
+}
+
+// reload reads and validates the configuration again, then applies the
+// cleanup policy and log level together. An invalid configuration is
+// rejected and the current one stays active.
+func (r *reloader) reload(source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The settings saved through the API are already applied
+	if source == reloadSourceFile {
+		if data, err := os.ReadFile(r.cfg.File); err == nil && sha256.Sum256(data) == r.saved {
+			r.log.Debug("Config file written by the settings API, not reloaded")
+			return
+		}
+	}
+
+	cfg, err := config.Reload(r.cfg)
+	if err == nil {
+		err = r.apply(cfg)
+	}
+	if err != nil {
+		r.log.Errorf("Configuration reload rejected, keeping the current configuration: %v", err)
+		if r.webServer != nil {
+			r.webServer.SetConfigError(fmt.Errorf("configuration reload rejected: %w", err))
+		}
+		return
+	}
+	if r.webServer != nil {
+		r.webServer.SetConfigError(nil)
+	}
+	if r.unsaved {
+		r.log.Warn("Settings changed through the API without saving them are replaced by the config file")
+		r.unsaved = false
+	}
+
+	r.commit(cfg, source)
+}
+
+// Config returns a copy of the configuration in use
+func (r *reloader) Config() config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.cfg
+}
+
+// UpdateSettings changes the runtime settings with update, applies them and
+// writes them to the config file when persist is set. It returns the new
+// settings and the changes.
+func (r *reloader) UpdateSettings(update func(*config.Settings), persist bool) (config.Settings, []string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.cfg.Settings()
+	update(&s)
+	if err := s.Validate(); err != nil {
+		return s, nil, err
+	}
+	if persist && r.cfg.File == "" {
+		return s, nil, fmt.Errorf("%w: no config file to save the settings to", config.ErrInvalidSettings)
+	}
+	if overridden := r.cfg.OverriddenSettings(s); persist && len(overridden) > 0 {
+		return s, nil, fmt.Errorf("%w: %s set by environment variables or flags, which take precedence over the config file: change them there, or apply without saving",
+			config.ErrInvalidSettings, strings.Join(overridden, ", "))
+	}
+
+	cfg := *r.cfg
+	cfg.ApplySettings(s)
+	if err := r.apply(&cfg); err != nil {
+		return s, nil, fmt.Errorf("%w: %v", config.ErrInvalidSettings, err)
+	}
+	// Written once applied, so that the file never holds settings the
+	// running configuration rejected
+	if persist {
+		if err := config.WriteSettings(r.cfg.File, s); err != nil {
+			// Restore the previous settings, schedule included
+			previous := r.cfg
+			r.cfg = &cfg
+			if rollbackErr := r.apply(previous); rollbackErr != nil {
+				r.log.Errorf("Failed to restore the configuration: %v", rollbackErr)
+			}
+			r.cfg = previous
+			return s, nil, err
+		}
+		if data, err := os.ReadFile(r.cfg.File); err == nil {
+			r.saved = sha256.Sum256(data)
+		}
+	}
+	changes := r.commit(&cfg, reloadSourceAPI)
+	// Saving writes all the runtime settings, unsaved ones included
+	r.unsaved = !persist && (r.unsaved || len(changes) > 0)
+	return s, changes, nil
+}
+
+// commit records an applied configuration, then logs and publishes its changes
+func (r *reloader) commit(cfg *config.Config, source string) []string {
+	changes := config.Diff(r.cfg, cfg)
+	r.cfg = cfg
+	if len(changes) == 0 {
+		r.log.Debugf("Configuration reloaded (%s), no changes", source)
+		return changes
+	}
+
+	r.log.Infof("Configuration reloaded (%s)", source)
+	for _, change := range changes {
+		if isReloadable(change) {
+			r.log.Infof("  %s", change)
+		} else {
+			r.log.Warnf("  %s (restart required)", change)
+		}
+	}
+	r.bus.Publish(events.TopicConfigReloaded, events.ConfigReloaded{Source: source, Changes: changes})
+	return changes
+}
+
+// apply swaps the cleanup policy, dry-run mode, log settings and daemon schedule
+// of a validated configuration. Nothing is changed if any of them is invalid.
+func (r *reloader) apply(cfg *config.Config) error {
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	formatter, err := logger.NewFormatter(cfg.LogFormat)
+	if err != nil {
+		return err
+	}
+	policy := policyFromConfig(cfg)
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	// Nothing fails past this point, the configuration is applied whole
+	if err := r.clean.SetPolicy(policy); err != nil {
+		return err
+	}
+	r.clean.SetDryRun(cfg.DryRun)
+	r.log.SetLevel(level)
+	r.log.SetFormatter(formatter)
+	r.log.SetBufferSize(cfg.LogBufferSize)
+	current, next := r.cfg.Daemon, cfg.Daemon
+	// Restart-only settings do not change the schedule
+	current.Enabled, next.Enabled = false, false
+	current.Watch, next.Watch = config.WatchConfig{}, config.WatchConfig{}
+	if !reflect.DeepEqual(current, next) {
+		// Replace a pending schedule the daemon loop has not read yet
+		select {
+		case <-r.schedules:
+		default:
+		}
+		r.schedules <- cfg.Daemon
+	}
+	return nil
+}
+
+// isReloadable reports whether a change returned by config.Diff is applied without restart
+func isReloadable(change string) bool {
+	for _, prefix := range reloadable {
+		if strings.HasPrefix(change, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Celedhrim/btcleaner/internal/client"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/spf13/pflag"
+)
+
+// tokenEnv holds the API token for --server. Unlike --token, which is still
+// accepted with a warning, it stays out of the process list.
+const tokenEnv = "BTCLEANER_TOKEN"
+
+// serverURL returns the instance set with --server, empty for the local mode
+func serverURL() string {
+	server, _ := pflag.CommandLine.GetString("server")
+	return server
+}
+
+// remoteClient returns the API client of the instance set with --server, nil
+// in local mode. Without a path in the URL, the server.webroot setting of the
+// configuration is used, so the same config file works for both modes.
+func remoteClient() (*client.Client, error) {
+	server := serverURL()
+	if server == "" {
+		return nil, nil
+	}
+
+	webRoot := ""
+	if u, err := url.Parse(server); err == nil && strings.Trim(u.Path, "/") == "" {
+		cfg, err := config.LoadUnresolved(Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		webRoot = cfg.Server.WebRoot
+	}
+
+	token, _ := pflag.CommandLine.GetString("token")
+	if token != "" {
+		fmt.Fprintf(os.Stderr, "Warning: --token is visible to other users in the process list, set %s instead\n", tokenEnv)
+	} else {
+		token = os.Getenv(tokenEnv)
+	}
+	return client.NewClient(server, webRoot, token)
+}
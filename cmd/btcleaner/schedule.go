@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/schedule"
+)
+
+// newSchedule returns the schedule of the daemon settings
+func newSchedule(d config.DaemonConfig) (*schedule.Schedule, error) {
+	return schedule.New(d.CheckInterval, d.Schedule, d.DeleteWindows, d.BlockedWindows, d.EmergencyFreeSpace, d.PolicySchedules)
+}
+
+// daemonSchedule is the schedule of the daemon loop, also reported in the stats
+type daemonSchedule struct {
+	mu    sync.RWMutex
+	sched *schedule.Schedule
+	next  time.Time // Time of the next check
+
+	// Backoff of the interval checks while no download happens, with the
+	// event-driven checks
+	activity    func() time.Time // Time of the last download activity, nil without backoff
+	maxInterval time.Duration
+	delay       time.Duration // Current delay between checks, 0 before the first one
+	advancedAt  time.Time
+}
+
+// set replaces the schedule
+func (d *daemonSchedule) set(sched *schedule.Schedule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sched = sched
+	d.delay = 0
+}
+
+// setBackoff doubles the delay between interval checks, up to maxInterval,
+// for as long as activity reports nothing new
+func (d *daemonSchedule) setBackoff(activity func() time.Time, maxInterval time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.activity = activity
+	d.maxInterval = maxInterval
+}
+
+// advance computes the time of the next check after now and returns the
+// delay until then
+func (d *daemonSchedule) advance(now time.Time) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if interval := d.sched.Interval(); d.activity != nil && interval > 0 && d.maxInterval > interval {
+		switch {
+		case d.delay == 0 || d.activity().After(d.advancedAt):
+			d.delay = interval
+		case d.delay < d.maxInterval:
+			d.delay = min(2*d.delay, d.maxInterval)
+		}
+		d.advancedAt = now
+		d.next = now.Add(d.delay)
+		return d.delay
+	}
+	d.next = d.sched.Next(now)
+	if d.next.IsZero() {
+		// The cron expression no longer matches, validation should prevent it
+		return 24 * time.Hour
+	}
+	return d.next.Sub(now)
+}
+
+// runOptions returns the options of a check at now. Outside the deletion
+// windows, the deletions are deferred.
+func (d *daemonSchedule) runOptions(trigger string, now time.Time) cleaner.RunOptions {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	sched := d.sched
+	opts := cleaner.RunOptions{
+		Trigger: trigger,
+		PolicyDue: func(policy string, last time.Time) bool {
+			return sched.PolicyDue(policy, last, now)
+		},
+	}
+	if !d.sched.DeletionAllowed(now) {
+		opts.Deferral = &cleaner.Deferral{EmergencyFloor: d.sched.EmergencyFloor()}
+		if until, ok := d.sched.NextDeletionWindow(now); ok {
+			opts.Deferral.Until = until
+		}
+	}
+	return opts
+}
+
+// state reports the schedule in the stats
+func (d *daemonSchedule) state() *cleaner.ScheduleState {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := time.Now()
+	state := &cleaner.ScheduleState{
+		Schedule:                d.sched.String(),
+		NextRunAt:               d.next,
+		DeletionAllowed:         d.sched.DeletionAllowed(now),
+		EmergencyFreeSpaceBytes: d.sched.EmergencyFloor(),
+	}
+	if !state.DeletionAllowed {
+		if until, ok := d.sched.NextDeletionWindow(now); ok {
+			state.NextDeletionAt = &until
+		}
+	}
+	return state
+}
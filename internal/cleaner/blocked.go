@@ -0,0 +1,146 @@
+package cleaner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Celedhrim/btcleaner/pkg/models"
+)
+
+// Blocked describes a cleanup that cannot reach the minimum free space,
+// usually because tracker minimums keep the torrents it would need to remove
+type Blocked struct {
+	ShortfallBytes int64                `json:"shortfall_bytes"` // Space still missing after removing every removable torrent
+	Constraints    []BlockingConstraint `json:"constraints"`     // Tracker minimums that kept torrents, most kept space first
+	Suggestions    []MinimumSuggestion  `json:"suggestions"`     // Tracker minimums to lower to reach the minimum free space
+	Resolvable     bool                 `json:"resolvable"`      // Whether the suggestions free enough space
+	Message        string               `json:"message"`
+}
+
+// BlockingConstraint is a tracker minimum that kept torrents a cleanup needed
+type BlockingConstraint struct {
+	Tracker      string `json:"tracker"`
+	MinTorrents  int    `json:"min_torrents"`
+	Torrents     int    `json:"torrents"`      // Torrents of the tracker
+	KeptTorrents int    `json:"kept_torrents"` // Torrents kept because of the minimum
+	KeptBytes    int64  `json:"kept_bytes"`
+}
+
+// MinimumSuggestion proposes a lower minimum for a tracker
+type MinimumSuggestion struct {
+	Tracker    string `json:"tracker"`
+	Current    int    `json:"current"`
+	Suggested  int    `json:"suggested"`
+	FreedBytes int64  `json:"freed_bytes"` // Additional space freed with the suggested minimum
+}
+
+// blockedBy analyzes a selection that could not free spaceNeeded. It returns
+// nil when the selection is sufficient.
+//
+// Suggestions take the kept torrents in the removal order of the strategy
+// until the shortfall is covered, so the cleanup removes the same torrents
+// with the suggested minimums.
+func blockedBy(policy Policy, torrents []models.Torrent, sel selection, spaceNeeded int64) *Blocked {
+	if sel.Freed >= spaceNeeded {
+		return nil
+	}
+
+	selected := make(map[int]bool, len(sel.Selected))
+	for _, t := range sel.Selected {
+		selected[t.ID] = true
+	}
+
+	// Torrents kept by the minimum of their tracker, in removal order
+	kept := make([]models.Torrent, 0, len(torrents))
+	for _, t := range torrents {
+		if !selected[t.ID] && policy.minimumFor(t.NormalizedTracker) > 0 {
+			kept = append(kept, t)
+		}
+	}
+	policy.sortForRemoval(kept)
+
+	counts := make(map[string]int)
+	for _, t := range torrents {
+		counts[t.NormalizedTracker]++
+	}
+
+	constraints := make(map[string]*BlockingConstraint)
+	var order []string
+	for _, t := range kept {
+		bc, ok := constraints[t.NormalizedTracker]
+		if !ok {
+			bc = &BlockingConstraint{
+				Tracker:     t.NormalizedTracker,
+				MinTorrents: policy.minimumFor(t.NormalizedTracker),
+				Torrents:    counts[t.NormalizedTracker],
+			}
+			constraints[t.NormalizedTracker] = bc
+			order = append(order, t.NormalizedTracker)
+		}
+		bc.KeptTorrents++
+		bc.KeptBytes += t.TotalSize
+	}
+
+	b := &Blocked{
+		ShortfallBytes: spaceNeeded - sel.Freed,
+		Constraints:    []BlockingConstraint{},
+		Suggestions:    []MinimumSuggestion{},
+	}
+
+	// Lower the minimums until the kept torrents taken cover the shortfall
+	suggestions := make(map[string]*MinimumSuggestion)
+	var freed int64
+	for _, t := range kept {
+		if freed >= b.ShortfallBytes {
+			break
+		}
+		s, ok := suggestions[t.NormalizedTracker]
+		if !ok {
+			bc := constraints[t.NormalizedTracker]
+			s = &MinimumSuggestion{Tracker: bc.Tracker, Current: bc.MinTorrents, Suggested: bc.KeptTorrents}
+			suggestions[t.NormalizedTracker] = s
+		}
+		s.Suggested--
+		s.FreedBytes += t.TotalSize
+		freed += t.TotalSize
+	}
+	b.Resolvable = freed >= b.ShortfallBytes
+
+	for _, tracker := range order {
+		b.Constraints = append(b.Constraints, *constraints[tracker])
+		if s, ok := suggestions[tracker]; ok {
+			b.Suggestions = append(b.Suggestions, *s)
+		}
+	}
+	sort.SliceStable(b.Constraints, func(i, j int) bool {
+		return b.Constraints[i].KeptBytes > b.Constraints[j].KeptBytes
+	})
+
+	b.Message = b.describe()
+	return b
+}
+
+// describe returns a human-readable summary of the blocked state
+func (b *Blocked) describe() string {
+	if len(b.Constraints) == 0 {
+		return fmt.Sprintf("Cannot free %.2f GB: there are no more torrents to remove. Lower the minimum free space.",
+			float64(b.ShortfallBytes)/(1024*1024*1024))
+	}
+
+	trackers := make([]string, len(b.Constraints))
+	for i, bc := range b.Constraints {
+		trackers[i] = fmt.Sprintf("%s (min %d)", bc.Tracker, bc.MinTorrents)
+	}
+	msg := fmt.Sprintf("Cannot free %.2f GB: tracker minimums keep the remaining torrents of %s.",
+		float64(b.ShortfallBytes)/(1024*1024*1024), strings.Join(trackers, ", "))
+
+	suggestions := make([]string, len(b.Suggestions))
+	for i, s := range b.Suggestions {
+		suggestions[i] = fmt.Sprintf("%s from %d to %d", s.Tracker, s.Current, s.Suggested)
+	}
+	if b.Resolvable {
+		return msg + " Lower the minimum of " + strings.Join(suggestions, ", ") + "."
+	}
+	return msg + " Even without these minimums, not enough space can be freed: lower the minimum free space."
+}
@@ -1,14 +1,21 @@
 package cleaner
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Celedhrim/btcleaner/internal/events"
 	"github.com/Celedhrim/btcleaner/internal/logger"
 	"github.com/Celedhrim/btcleaner/internal/transmission"
 	"github.com/Celedhrim/btcleaner/pkg/models"
+	"github.com/sirupsen/logrus"
 )
 
 const MaxHistorySize = 50
@@ -22,136 +29,387 @@ type DeletedTorrent struct {
 	Tracker   string    `json:"tracker"`
 	DeletedAt time.Time `json:"deleted_at"`
 	Reason    string    `json:"reason"` // "auto" or "manual"
+	RunID     string    `json:"run_id,omitempty"` // Cleanup run that deleted the torrent
 }
 
 // Cleaner handles torrent cleanup logic
 type Cleaner struct {
-	client                *transmission.Client
-	minFreeSpace          int64
-	minTorrentsPerTracker int
-	dryRun                bool
-	logger                *logger.Logger
-	history               []DeletedTorrent
-	historyMutex          sync.RWMutex
+	client        *transmission.Client
+	policy        Policy
+	policyMutex   sync.RWMutex
+	dryRun        atomic.Bool
+	logger        *logger.Logger
+	history       []DeletedTorrent
+	historyMutex  sync.RWMutex
+	runMutex      sync.Mutex  // Serializes cleanup runs (daemon, API)
+	running       atomic.Bool // True while a cleanup run is in progress
+	runs          runStore
+	dataDir       string // Persistence directory, empty to keep records in memory (guarded by runs.mu)
+	events        *events.Bus
+	schedule      ScheduleSource // Reports the daemon schedule, nil outside daemon mode
+	scheduleMutex sync.RWMutex
+	space         spaceStore           // Free space measured by the checks
+	sweptAt       map[string]time.Time // Last run of each sweep (guarded by runMutex)
 }
 
 // New creates a new Cleaner
-func New(client *transmission.Client, minFreeSpace int64, minTorrentsPerTracker int, dryRun bool, log *logger.Logger) *Cleaner {
-	return &Cleaner{
-		client:                client,
-		minFreeSpace:          minFreeSpace,
-		minTorrentsPerTracker: minTorrentsPerTracker,
-		dryRun:                dryRun,
-		logger:                log,
-		history:               make([]DeletedTorrent, 0, MaxHistorySize),
+func New(client *transmission.Client, policy Policy, dryRun bool, log *logger.Logger) *Cleaner {
+	if policy.Strategy == "" {
+		policy.Strategy = StrategyOldest
 	}
+	c := &Cleaner{
+		client:  client,
+		policy:  policy.clone(),
+		logger:  log,
+		history: make([]DeletedTorrent, 0, MaxHistorySize),
+	}
+	c.dryRun.Store(dryRun)
+	return c
 }
 
 // CleanupResult contains information about cleanup operation
 type CleanupResult struct {
-	InitialFreeSpace int64
-	FinalFreeSpace   int64
-	RemovedCount     int
-	RemovedSize      int64
-	RemovedTorrents  []models.Torrent
-	NeedCleanup      bool
+	RunID                string           `json:"run_id"`
+	DryRun               bool             `json:"dry_run"`
+	MinFreeSpace         int64            `json:"min_free_space"`
+	InitialFreeSpace     int64            `json:"initial_free_space"`
+	FinalFreeSpace       int64            `json:"final_free_space"`
+	RemovedCount         int              `json:"removed_count"`
+	RemovedSize          int64            `json:"removed_size"`
+	RemovedTorrents      []models.Torrent `json:"removed_torrents"`
+	NeedCleanup          bool             `json:"need_cleanup"`
+	CandidatesConsidered int              `json:"candidates_considered"`
+	Skipped              []SkippedTorrent `json:"skipped"`
+	Errors               []string         `json:"errors"`
+	Blocked              *Blocked         `json:"blocked,omitempty"`  // Set when tracker minimums prevent reaching the minimum free space
+	Deferred             bool             `json:"deferred,omitempty"` // Deletions postponed to the next deletion window
+	DeferredUntil        *time.Time       `json:"deferred_until,omitempty"`
+	Emergency            bool             `json:"emergency,omitempty"`         // Deletions outside the deletion windows, free space being below the emergency floor
+	PendingDownloads     int64            `json:"pending_downloads,omitempty"` // Bytes left to download by the active torrents, subtracted from the free space by predictive cleanups
+}
+
+// Skip reasons of torrents that were considered but not selected for removal
+const (
+	SkipTrackerMinimum = "tracker_minimum" // Tracker would drop below its minimum number of torrents
+	SkipRemoveFailed   = "remove_failed"   // Transmission failed to remove the torrent
+)
+
+// SkippedTorrent is a torrent considered during a cleanup but not removed
+type SkippedTorrent struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Tracker   string `json:"tracker"`
+	SizeBytes int64  `json:"size_bytes"`
+	Reason    string `json:"reason"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// RunOptions overrides the configured settings for a single cleanup run
+type RunOptions struct {
+	ID           string    // Run ID, generated if empty
+	Trigger      string    // What started the run (TriggerStartup, TriggerTimer...)
+	DryRun       *bool     // Overrides the configured dry-run mode
+	MinFreeSpace *int64    // Overrides the configured target free space (bytes)
+	Deferral     *Deferral // Set outside the deletion windows of the daemon schedule
+	Started      func()    // Called when the run starts, after waiting for the one in progress
+
+	// PolicyDue reports whether the sweep of a policy, last run at last, is
+	// due. Nil runs all the sweeps.
+	PolicyDue func(policy string, last time.Time) bool
+}
+
+// Deferral postpones the deletions of a run outside the deletion windows.
+// The run still checks the free space and selects the torrents to remove.
+type Deferral struct {
+	Until          time.Time // Start of the next deletion window, zero if unknown
+	EmergencyFloor int64     // Deletions still happen below this free space (bytes), 0 for none
+}
+
+// NewRunID returns a new unique cleanup run ID
+func NewRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// SetEventBus sets the bus on which cleanup and deletion events are published
+func (c *Cleaner) SetEventBus(bus *events.Bus) {
+	c.events = bus
+}
+
+// Running reports whether a cleanup run is in progress
+func (c *Cleaner) Running() bool {
+	return c.running.Load()
 }
 
-// Run executes the cleanup process
+// Run executes the cleanup process with the configured settings
 func (c *Cleaner) Run() (*CleanupResult, error) {
-	result := &CleanupResult{}
+	return c.RunWithOptions(RunOptions{})
+}
+
+// RunWithOptions executes the cleanup process and records the run.
+// Concurrent runs are serialized: a run waits for the one in progress to finish.
+func (c *Cleaner) RunWithOptions(opts RunOptions) (*CleanupResult, error) {
+	c.runMutex.Lock()
+	defer c.runMutex.Unlock()
+	c.running.Store(true)
+	defer c.running.Store(false)
+	if opts.Started != nil {
+		opts.Started()
+	}
+
+	if opts.ID == "" {
+		opts.ID = NewRunID()
+	}
+	dryRun := c.dryRun.Load()
+	if opts.DryRun != nil {
+		dryRun = *opts.DryRun
+	}
+	policy := c.Policy()
+	if opts.MinFreeSpace != nil {
+		policy.MinFreeSpace = *opts.MinFreeSpace
+	}
+	minFreeSpace := policy.MinFreeSpace
+
+	result := &CleanupResult{
+		RunID:        opts.ID,
+		DryRun:       dryRun,
+		MinFreeSpace: minFreeSpace,
+		Skipped:      []SkippedTorrent{},
+		Errors:       []string{},
+	}
+
+	log := c.logger.WithField(logger.FieldRunID, opts.ID)
+	log.Debugf("Starting cleanup run %s (trigger: %s)", opts.ID, opts.Trigger)
+	if opts.DryRun != nil || opts.MinFreeSpace != nil {
+		log.Infof("Cleanup run %s with overrides: dry run %v, min free space %.2f GB",
+			opts.ID, dryRun, float64(minFreeSpace)/(1024*1024*1024))
+	}
+
+	c.events.Publish(events.TopicCleanupStarted, events.CleanupStarted{
+		RunID:        opts.ID,
+		Trigger:      opts.Trigger,
+		DryRun:       dryRun,
+		MinFreeSpace: minFreeSpace,
+	})
+
+	startedAt := time.Now()
+	run := c.dueSweeps(opts.PolicyDue, startedAt)
+	err := c.execute(log, policy, run, opts.Deferral, result)
+	c.recordRun(opts.Trigger, startedAt, result, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
 
+// execute performs a cleanup run with the given sweeps, filling in result.
+// Entries are logged with the fields of the run.
+func (c *Cleaner) execute(log *logrus.Entry, policy Policy, run []Sweep, deferral *Deferral, result *CleanupResult) error {
 	// Get current free space
 	freeSpace, err := c.client.GetFreeSpace()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get free space: %w", err)
+		return fmt.Errorf("failed to get free space: %w", err)
 	}
 
 	result.InitialFreeSpace = freeSpace
 	result.FinalFreeSpace = freeSpace
 
-	c.logger.Debugf("Current free space: %.2f GB", float64(freeSpace)/(1024*1024*1024))
-	c.logger.Debugf("Minimum required: %.2f GB", float64(c.minFreeSpace)/(1024*1024*1024))
+	c.recordSpace(freeSpace)
+
+	log.WithField(logger.FieldBytes, freeSpace).Debugf("Current free space: %.2f GB", float64(freeSpace)/(1024*1024*1024))
+	log.WithField(logger.FieldBytes, result.MinFreeSpace).Debugf("Minimum required: %.2f GB", float64(result.MinFreeSpace)/(1024*1024*1024))
+
+	// Predictive cleanups also make room for the active downloads
+	var torrents []models.Torrent
+	available := freeSpace
+	if policy.Predictive {
+		if torrents, err = c.client.GetTorrents(); err != nil {
+			return fmt.Errorf("failed to get torrents: %w", err)
+		}
+		result.PendingDownloads = PendingDownloads(torrents)
+		available -= result.PendingDownloads
+		log.WithField(logger.FieldBytes, result.PendingDownloads).Debugf("Pending downloads: %.2f GB, projected free space: %.2f GB",
+			float64(result.PendingDownloads)/(1024*1024*1024), float64(available)/(1024*1024*1024))
+	}
+
+	// Sweeps remove their torrents whatever the free space
+	var swept []models.Torrent
+	if len(run) > 0 {
+		if torrents == nil {
+			if torrents, err = c.client.GetTorrents(); err != nil {
+				return fmt.Errorf("failed to get torrents: %w", err)
+			}
+		}
+		swept = c.sweep(log, run, torrents, time.Now())
+	}
 
 	// Check if cleanup is needed
-	if freeSpace >= c.minFreeSpace {
-		c.logger.Debug("Free space is sufficient, no cleanup needed")
+	var spaceNeeded int64
+	if available >= result.MinFreeSpace {
+		log.Debug("Free space is sufficient, no cleanup needed")
 		result.NeedCleanup = false
-		return result, nil
+		if len(swept) == 0 {
+			return nil
+		}
+	} else {
+		result.NeedCleanup = true
+		spaceNeeded = result.MinFreeSpace - available
+		log.WithField(logger.FieldBytes, spaceNeeded).Warnf("Need to free up %.2f GB", float64(spaceNeeded)/(1024*1024*1024))
 	}
 
-	result.NeedCleanup = true
-	spaceNeeded := c.minFreeSpace - freeSpace
-	c.logger.Warnf("Need to free up %.2f GB", float64(spaceNeeded)/(1024*1024*1024))
+	if deferral != nil {
+		if deferral.EmergencyFloor > 0 && freeSpace < deferral.EmergencyFloor {
+			log.Warnf("Free space is below the emergency floor of %.2f GB, cleaning up outside the deletion windows",
+				float64(deferral.EmergencyFloor)/(1024*1024*1024))
+			result.Emergency = true
+		} else {
+			result.Deferred = true
+			if !deferral.Until.IsZero() {
+				until := deferral.Until
+				result.DeferredUntil = &until
+			}
+		}
+	}
 
 	// Get all torrents
-	torrents, err := c.client.GetTorrents()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	if torrents == nil {
+		if torrents, err = c.client.GetTorrents(); err != nil {
+			return fmt.Errorf("failed to get torrents: %w", err)
+		}
 	}
 
-	c.logger.Infof("Found %d torrents", len(torrents))
+	log.Infof("Found %d torrents", len(torrents))
 
-	// Select torrents to remove
-	toRemove, err := c.selectTorrentsToRemove(torrents, spaceNeeded)
-	if err != nil {
-		return nil, err
+	// Select torrents to remove, in addition to those of the sweeps
+	toRemove := swept
+	for _, t := range swept {
+		spaceNeeded -= t.TotalSize
 	}
+	if spaceNeeded > 0 {
+		remaining := withoutTorrents(torrents, swept)
+		sel := c.selectTorrentsToRemove(log, policy, remaining, spaceNeeded)
+		toRemove = append(toRemove, sel.Selected...)
+		result.CandidatesConsidered = sel.Considered
+		result.Skipped = append(result.Skipped, sel.Skipped...)
+
+		// Check if we could free enough space
+		if sel.Freed < spaceNeeded {
+			log.WithField(logger.FieldBytes, sel.Freed).Warnf("Could only free %.2f GB out of %.2f GB needed", 
+				float64(sel.Freed)/(1024*1024*1024), 
+				float64(spaceNeeded)/(1024*1024*1024))
+			result.Blocked = blockedBy(policy, remaining, sel, spaceNeeded)
+			if result.Blocked != nil {
+				log.Warn(result.Blocked.Message)
+			}
+		}
 
-	if len(toRemove) == 0 {
-		c.logger.Warn("Cannot free enough space while respecting minimum torrents per tracker constraint")
-		return result, nil
+		if len(sel.Selected) == 0 {
+			log.Warn("Cannot free enough space while respecting minimum torrents per tracker constraint")
+			result.Errors = append(result.Errors, "cannot free enough space while respecting minimum torrents per tracker")
+			if len(toRemove) == 0 {
+				return nil
+			}
+		}
 	}
 
-	result.RemovedTorrents = toRemove
-	result.RemovedCount = len(toRemove)
-
-	// Calculate total size to be freed
+	var selectedSize int64
 	for _, t := range toRemove {
-		result.RemovedSize += t.TotalSize
+		selectedSize += t.TotalSize
 	}
 
-	c.logger.Infof("Selected %d torrents to remove (will free %.2f GB)", 
-		len(toRemove), float64(result.RemovedSize)/(1024*1024*1024))
+	log.WithField(logger.FieldBytes, selectedSize).Infof("Selected %d torrents to remove (will free %.2f GB)", 
+		len(toRemove), float64(selectedSize)/(1024*1024*1024))
+
+	// Outside the deletion windows, the selection is only logged
+	if result.Deferred {
+		when := "the next deletion window"
+		if result.DeferredUntil != nil {
+			when = result.DeferredUntil.Format("2006-01-02 15:04")
+		}
+		log.Infof("Outside the deletion windows, cleanup deferred until %s", when)
+		for _, t := range toRemove {
+			log.WithFields(logger.TorrentFields(t)).Debugf("  Would remove [%s] %s (%.2f GB, added %s)",
+				t.NormalizedTracker, t.Name,
+				float64(t.TotalSize)/(1024*1024*1024),
+				t.AddedDate.Format("2006-01-02"))
+		}
+		return nil
+	}
 
 	// Remove torrents
-	if c.dryRun {
-		c.logger.Info("DRY RUN: Would remove the following torrents:")
+	if result.DryRun {
+		log.Info("DRY RUN: Would remove the following torrents:")
 		for _, t := range toRemove {
-			c.logger.Infof("  - [%s] %s (%.2f GB, added %s)",
+			log.WithFields(logger.TorrentFields(t)).Infof("  - [%s] %s (%.2f GB, added %s)",
 				t.NormalizedTracker, t.Name, 
 				float64(t.TotalSize)/(1024*1024*1024),
 				t.AddedDate.Format("2006-01-02"))
 		}
-	} else {
-		for _, t := range toRemove {
-			c.logger.Infof("Removing torrent: [%s] %s (%.2f GB)", 
-				t.NormalizedTracker, t.Name, float64(t.TotalSize)/(1024*1024*1024))
-			
-			if err := c.client.RemoveTorrent(t.ID, true); err != nil {
-				c.logger.Errorf("Failed to remove torrent %s: %v", t.Name, err)
-				continue
-			}
+		result.RemovedTorrents = toRemove
+		result.RemovedCount = len(toRemove)
+		result.RemovedSize = selectedSize
+		return nil
+	}
 
-			// Add to history
-			c.addToHistory(t, "auto")
+	for _, t := range toRemove {
+		tlog := log.WithFields(logger.TorrentFields(t))
+		tlog.Infof("Removing torrent: [%s] %s (%.2f GB)", 
+			t.NormalizedTracker, t.Name, float64(t.TotalSize)/(1024*1024*1024))
+		
+		if err := c.client.RemoveTorrent(t.ID, true); err != nil {
+			tlog.Errorf("Failed to remove torrent %s: %v", t.Name, err)
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to remove torrent %d: %v", t.ID, err))
+			result.Skipped = append(result.Skipped, newSkippedTorrent(t, SkipRemoveFailed, err.Error()))
+			continue
 		}
 
-		// Update final free space
-		finalFreeSpace, err := c.client.GetFreeSpace()
-		if err != nil {
-			c.logger.Warnf("Failed to get final free space: %v", err)
-		} else {
-			result.FinalFreeSpace = finalFreeSpace
-			c.logger.Infof("Final free space: %.2f GB", float64(finalFreeSpace)/(1024*1024*1024))
-		}
+		result.RemovedTorrents = append(result.RemovedTorrents, t)
+		result.RemovedCount++
+		result.RemovedSize += t.TotalSize
+
+		// Add to history
+		c.addToHistory(t, "auto", result.RunID)
 	}
 
-	return result, nil
+	// Update final free space
+	finalFreeSpace, err := c.client.GetFreeSpace()
+	if err != nil {
+		log.Warnf("Failed to get final free space: %v", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to get final free space: %v", err))
+	} else {
+		result.FinalFreeSpace = finalFreeSpace
+		c.recordSpace(finalFreeSpace)
+		log.WithField(logger.FieldBytes, finalFreeSpace).Infof("Final free space: %.2f GB", float64(finalFreeSpace)/(1024*1024*1024))
+	}
+
+	return nil
+}
+
+// newSkippedTorrent describes a torrent that was not removed
+func newSkippedTorrent(t models.Torrent, reason, detail string) SkippedTorrent {
+	return SkippedTorrent{
+		ID:        t.ID,
+		Name:      t.Name,
+		Tracker:   t.NormalizedTracker,
+		SizeBytes: t.TotalSize,
+		Reason:    reason,
+		Detail:    detail,
+	}
+}
+
+// selection is the outcome of the torrent selection algorithm
+type selection struct {
+	Selected   []models.Torrent // Torrents to remove, in removal order
+	Skipped    []SkippedTorrent // Torrents examined but kept
+	Considered int              // Number of torrents examined
+	Freed      int64            // Total size of the selected torrents
 }
 
-// selectTorrentsToRemove selects torrents to remove based on age while respecting tracker minimums
-func (c *Cleaner) selectTorrentsToRemove(torrents []models.Torrent, spaceNeeded int64) ([]models.Torrent, error) {
+// selectTorrentsToRemove selects torrents to remove in the order of the policy strategy
+// while respecting tracker minimums. The selection is logged to log.
+func (c *Cleaner) selectTorrentsToRemove(log logrus.FieldLogger, policy Policy, torrents []models.Torrent, spaceNeeded int64) selection {
 	// Group torrents by tracker
 	trackerMap := make(map[string][]models.Torrent)
 	for _, t := range torrents {
@@ -163,13 +421,13 @@ func (c *Cleaner) selectTorrentsToRemove(torrents []models.Torrent, spaceNeeded
 		sort.Sort(models.TorrentsByAge(trackerMap[tracker]))
 	}
 
-	c.logger.Debug("Torrent distribution by tracker:")
+	log.Debug("Torrent distribution by tracker:")
 	for tracker, tList := range trackerMap {
-		c.logger.Debugf("  %s: %d torrents", tracker, len(tList))
+		log.WithField(logger.FieldTracker, tracker).Debugf("  %s: %d torrents", tracker, len(tList))
 	}
 
 	// Select torrents to remove
-	var toRemove []models.Torrent
+	var sel selection
 	var totalFreed int64
 
 	// Create a copy of the tracker map to track remaining torrents
@@ -178,43 +436,40 @@ func (c *Cleaner) selectTorrentsToRemove(torrents []models.Torrent, spaceNeeded
 		remainingMap[tracker] = len(tList)
 	}
 
-	// Sort all torrents by age
+	// Sort all torrents in removal order
 	allTorrents := make([]models.Torrent, len(torrents))
 	copy(allTorrents, torrents)
-	sort.Sort(models.TorrentsByAge(allTorrents))
+	policy.sortForRemoval(allTorrents)
 
-	// Select oldest torrents that can be removed
+	// Select torrents that can be removed
 	for _, t := range allTorrents {
 		// Check if we've freed enough space
 		if totalFreed >= spaceNeeded {
 			break
 		}
+		sel.Considered++
 
 		// Check if we can remove this torrent (tracker has more than minimum)
-		if remainingMap[t.NormalizedTracker] <= c.minTorrentsPerTracker {
-			c.logger.Debugf("Cannot remove %s: tracker %s at minimum (%d torrents)", 
+		if remainingMap[t.NormalizedTracker] <= policy.minimumFor(t.NormalizedTracker) {
+			log.WithFields(logger.TorrentFields(t)).Debugf("Cannot remove %s: tracker %s at minimum (%d torrents)", 
 				t.Name, t.NormalizedTracker, remainingMap[t.NormalizedTracker])
+			sel.Skipped = append(sel.Skipped, newSkippedTorrent(t, SkipTrackerMinimum,
+				fmt.Sprintf("tracker %s at minimum (%d torrents)", t.NormalizedTracker, remainingMap[t.NormalizedTracker])))
 			continue
 		}
 
 		// Add to removal list
-		toRemove = append(toRemove, t)
+		sel.Selected = append(sel.Selected, t)
 		totalFreed += t.TotalSize
 		remainingMap[t.NormalizedTracker]--
 
-		c.logger.Debugf("Selected for removal: %s (tracker: %s, size: %.2f GB, remaining: %d)",
+		log.WithFields(logger.TorrentFields(t)).Debugf("Selected for removal: %s (tracker: %s, size: %.2f GB, remaining: %d)",
 			t.Name, t.NormalizedTracker, float64(t.TotalSize)/(1024*1024*1024), 
 			remainingMap[t.NormalizedTracker])
 	}
 
-	// Check if we could free enough space
-	if totalFreed < spaceNeeded {
-		c.logger.Warnf("Could only free %.2f GB out of %.2f GB needed", 
-			float64(totalFreed)/(1024*1024*1024), 
-			float64(spaceNeeded)/(1024*1024*1024))
-	}
-
-	return toRemove, nil
+	sel.Freed = totalFreed
+	return sel
 }
 
 // GetCandidates returns torrents that would be deleted in a cleanup
@@ -225,23 +480,30 @@ func (c *Cleaner) GetCandidates() ([]*models.Torrent, error) {
 	}
 
 	// Check if cleanup is needed
-	if freeSpace >= c.minFreeSpace {
+	policy := c.Policy()
+	var torrents []models.Torrent
+	available := freeSpace
+	if policy.Predictive {
+		if torrents, err = c.client.GetTorrents(); err != nil {
+			return nil, fmt.Errorf("failed to get torrents: %w", err)
+		}
+		available -= PendingDownloads(torrents)
+	}
+	if available >= policy.MinFreeSpace {
 		return []*models.Torrent{}, nil
 	}
 
-	torrents, err := c.client.GetTorrents()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	if torrents == nil {
+		if torrents, err = c.client.GetTorrents(); err != nil {
+			return nil, fmt.Errorf("failed to get torrents: %w", err)
+		}
 	}
 
 	// Calculate space needed to reach minimum
-	spaceNeeded := c.minFreeSpace - freeSpace
+	spaceNeeded := policy.MinFreeSpace - available
 
 	// Select torrents to remove (without actually removing them)
-	candidates, err := c.selectTorrentsToRemove(torrents, spaceNeeded)
-	if err != nil {
-		return nil, fmt.Errorf("failed to select candidates: %w", err)
-	}
+	candidates := c.selectTorrentsToRemove(c.logger, policy, torrents, spaceNeeded).Selected
 
 	// Convert to pointers
 	result := make([]*models.Torrent, len(candidates))
@@ -252,8 +514,74 @@ func (c *Cleaner) GetCandidates() ([]*models.Torrent, error) {
 	return result, nil
 }
 
+// TrackerStat contains torrent count and size for a tracker
+type TrackerStat struct {
+	Name      string  `json:"name"`
+	Count     int     `json:"count"`
+	SizeBytes int64   `json:"size_bytes"`
+	SizeGB    float64 `json:"size_gb"`
+}
+
+// Stats contains current disk and torrent statistics
+type Stats struct {
+	FreeSpaceBytes          int64          `json:"free_space_bytes"`
+	FreeSpaceGB             float64        `json:"free_space_gb"`
+	MinFreeSpaceBytes       int64          `json:"min_free_space_bytes"`
+	MinFreeSpaceGB          float64        `json:"min_free_space_gb"`
+	TotalTorrents           int            `json:"total_torrents"`
+	TotalSpaceBytes         int64          `json:"total_space_bytes"`
+	TotalSpaceGB            float64        `json:"total_space_gb"`
+	TrackerStats            []TrackerStat  `json:"tracker_stats"`
+	NeedsCleanup            bool           `json:"needs_cleanup"`
+	CandidatesCount         int            `json:"candidates_count"`
+	SpaceToRecoverBytes     int64          `json:"space_to_recover_bytes"`
+	SpaceToRecoverGB        float64        `json:"space_to_recover_gb"`
+	PendingDownloadBytes    int64          `json:"pending_download_bytes"`     // Bytes left to download by the active torrents
+	ProjectedFreeSpaceBytes int64          `json:"projected_free_space_bytes"` // Free space once the active downloads complete
+	ProjectedFreeSpaceGB    float64        `json:"projected_free_space_gb"`
+	Predictive              bool           `json:"predictive"`         // Cleanups make room for the active downloads: needs_cleanup uses the projected free space
+	Trend                   SpaceTrend     `json:"trend"`              // Fill rate measured by the checks
+	Blocked                 *Blocked       `json:"blocked,omitempty"`  // Set when tracker minimums prevent reaching the minimum free space
+	Schedule                *ScheduleState `json:"schedule,omitempty"` // Set in daemon mode
+}
+
+// ScheduleState describes the daemon schedule
+type ScheduleState struct {
+	Schedule                string     `json:"schedule"` // "every 1m0s" or "cron <expression>"
+	NextRunAt               time.Time  `json:"next_run_at"`
+	DeletionAllowed         bool       `json:"deletion_allowed"`                     // False within a blocked window or outside the delete windows
+	NextDeletionAt          *time.Time `json:"next_deletion_at,omitempty"`           // Start of the next delete window, when deletions are not allowed now
+	EmergencyFreeSpaceBytes int64      `json:"emergency_free_space_bytes,omitempty"` // Windows are ignored below this free space
+}
+
+// ScheduleSource returns the current state of the daemon schedule
+type ScheduleSource func() *ScheduleState
+
+// SetScheduleSource sets the function reporting the daemon schedule in the stats
+func (c *Cleaner) SetScheduleSource(source ScheduleSource) {
+	c.scheduleMutex.Lock()
+	defer c.scheduleMutex.Unlock()
+	c.schedule = source
+}
+
+// FreeSpace returns the free space of the download directory
+func (c *Cleaner) FreeSpace() (int64, error) {
+	return c.client.GetFreeSpace()
+}
+
+// PendingDownloads returns the bytes the active downloads still have to write
+func PendingDownloads(torrents []models.Torrent) int64 {
+	var pending int64
+	for _, t := range torrents {
+		if t.Downloading() {
+			pending += t.LeftUntilDone
+		}
+	}
+	return pending
+}
+
 // GetStats returns current statistics
-func (c *Cleaner) GetStats() (map[string]interface{}, error) {
+func (c *Cleaner) GetStats() (*Stats, error) {
 	freeSpace, err := c.client.GetFreeSpace()
 	if err != nil {
 		return nil, err
@@ -276,49 +604,72 @@ func (c *Cleaner) GetStats() (map[string]interface{}, error) {
 	}
 
 	// Build tracker stats with count and space
-	trackerStats := make([]map[string]interface{}, 0)
+	trackerStats := make([]TrackerStat, 0, len(trackerCounts))
 	for tracker, count := range trackerCounts {
-		trackerStats = append(trackerStats, map[string]interface{}{
-			"name":  tracker,
-			"count": count,
-			"size_gb": float64(trackerSpace[tracker]) / (1024 * 1024 * 1024),
+		trackerStats = append(trackerStats, TrackerStat{
+			Name:      tracker,
+			Count:     count,
+			SizeBytes: trackerSpace[tracker],
+			SizeGB:    float64(trackerSpace[tracker]) / (1024 * 1024 * 1024),
 		})
 	}
 
-	needsCleanup := freeSpace < c.minFreeSpace
+	policy := c.Policy()
+	minFreeSpace := policy.MinFreeSpace
+	pending := PendingDownloads(torrents)
+	projected := freeSpace - pending
+	available := freeSpace
+	if policy.Predictive {
+		available = projected
+	}
+	needsCleanup := available < minFreeSpace
 	var spaceToRecover int64 = 0
 	var candidatesCount int = 0
+	var blocked *Blocked
 
 	// Get candidates if cleanup is needed
 	if needsCleanup {
-		candidates, err := c.GetCandidates()
-		if err == nil {
-			candidatesCount = len(candidates)
-			for _, t := range candidates {
-				spaceToRecover += t.TotalSize
-			}
-		}
+		spaceNeeded := minFreeSpace - available
+		sel := c.selectTorrentsToRemove(c.logger, policy, torrents, spaceNeeded)
+		candidatesCount = len(sel.Selected)
+		spaceToRecover = sel.Freed
+		blocked = blockedBy(policy, torrents, sel, spaceNeeded)
 	}
 
-	stats := map[string]interface{}{
-		"free_space_bytes":     freeSpace,
-		"free_space_gb":        float64(freeSpace) / (1024 * 1024 * 1024),
-		"min_free_space_gb":    float64(c.minFreeSpace) / (1024 * 1024 * 1024),
-		"total_torrents":       len(torrents),
-		"total_space_gb":       float64(totalSpace) / (1024 * 1024 * 1024),
-		"tracker_stats":        trackerStats,
-		"needs_cleanup":        needsCleanup,
-		"candidates_count":     candidatesCount,
-		"space_to_recover_gb":  float64(spaceToRecover) / (1024 * 1024 * 1024),
+	stats := &Stats{
+		FreeSpaceBytes:          freeSpace,
+		FreeSpaceGB:             float64(freeSpace) / (1024 * 1024 * 1024),
+		MinFreeSpaceBytes:       minFreeSpace,
+		MinFreeSpaceGB:          float64(minFreeSpace) / (1024 * 1024 * 1024),
+		TotalTorrents:           len(torrents),
+		TotalSpaceBytes:         totalSpace,
+		TotalSpaceGB:            float64(totalSpace) / (1024 * 1024 * 1024),
+		TrackerStats:            trackerStats,
+		NeedsCleanup:            needsCleanup,
+		CandidatesCount:         candidatesCount,
+		SpaceToRecoverBytes:     spaceToRecover,
+		SpaceToRecoverGB:        float64(spaceToRecover) / (1024 * 1024 * 1024),
+		PendingDownloadBytes:    pending,
+		ProjectedFreeSpaceBytes: projected,
+		ProjectedFreeSpaceGB:    float64(projected) / (1024 * 1024 * 1024),
+		Predictive:              policy.Predictive,
+		Trend:                   c.SpaceTrend(),
+		Blocked:                 blocked,
+	}
+
+	c.scheduleMutex.RLock()
+	source := c.schedule
+	c.scheduleMutex.RUnlock()
+	if source != nil {
+		stats.Schedule = source()
 	}
 
 	return stats, nil
 }
 
 // addToHistory adds a deleted torrent to the history
-func (c *Cleaner) addToHistory(t models.Torrent, reason string) {
+func (c *Cleaner) addToHistory(t models.Torrent, reason, runID string) {
 	c.historyMutex.Lock()
-	defer c.historyMutex.Unlock()
 
 	deleted := DeletedTorrent{
 		ID:        t.ID,
@@ -328,6 +679,7 @@ func (c *Cleaner) addToHistory(t models.Torrent, reason string) {
 		Tracker:   t.NormalizedTracker,
 		DeletedAt: time.Now(),
 		Reason:    reason,
+		RunID:     runID,
 	}
 
 	// Add to front of history
@@ -337,6 +689,111 @@ func (c *Cleaner) addToHistory(t models.Torrent, reason string) {
 	if len(c.history) > MaxHistorySize {
 		c.history = c.history[:MaxHistorySize]
 	}
+	c.historyMutex.Unlock()
+
+	c.events.Publish(events.TopicTorrentDeleted, events.TorrentDeleted{
+		ID:        t.ID,
+		Name:      t.Name,
+		Hash:      t.Hash,
+		Tracker:   t.NormalizedTracker,
+		SizeBytes: t.TotalSize,
+		Reason:    reason,
+		RunID:     runID,
+	})
+}
+
+// Manual deletion statuses
+const (
+	DeleteStatusDeleted  = "deleted"
+	DeleteStatusNotFound = "not_found"
+	DeleteStatusFailed   = "failed"
+)
+
+// DeleteResult is the outcome of a manual deletion for one requested torrent
+type DeleteResult struct {
+	Ref       string `json:"ref"` // Requested ID or hash
+	ID        int    `json:"id,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Name      string `json:"name,omitempty"`
+	SizeBytes int64  `json:"size_bytes"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DeleteTorrents manually removes the torrents matching the given IDs or hashes
+// with a single Transmission call, and records them in history.
+// It returns one result per requested ID or hash.
+func (c *Cleaner) DeleteTorrents(ids []int, hashes []string, deleteData bool) ([]DeleteResult, error) {
+	torrents, err := c.client.GetTorrentsByIDs(ids, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+
+	byID := make(map[int]models.Torrent, len(torrents))
+	byHash := make(map[string]models.Torrent, len(torrents))
+	for _, t := range torrents {
+		byID[t.ID] = t
+		byHash[strings.ToLower(t.Hash)] = t
+	}
+
+	results := make([]DeleteResult, 0, len(ids)+len(hashes))
+	toRemove := make(map[int]models.Torrent)
+	addResult := func(ref string, t models.Torrent, found bool) {
+		if !found {
+			results = append(results, DeleteResult{Ref: ref, Status: DeleteStatusNotFound, Error: "torrent not found"})
+			return
+		}
+		results = append(results, DeleteResult{
+			Ref:       ref,
+			ID:        t.ID,
+			Hash:      t.Hash,
+			Name:      t.Name,
+			SizeBytes: t.TotalSize,
+		})
+		toRemove[t.ID] = t
+	}
+	for _, id := range ids {
+		t, found := byID[id]
+		addResult(strconv.Itoa(id), t, found)
+	}
+	for _, hash := range hashes {
+		t, found := byHash[strings.ToLower(hash)]
+		addResult(hash, t, found)
+	}
+
+	removeIDs := make([]int, 0, len(toRemove))
+	for id := range toRemove {
+		removeIDs = append(removeIDs, id)
+	}
+	sort.Ints(removeIDs)
+
+	removeErr := c.client.RemoveTorrents(removeIDs, deleteData)
+	if removeErr != nil {
+		c.logger.Errorf("Failed to delete %d torrents: %v", len(removeIDs), removeErr)
+	}
+
+	for i := range results {
+		if results[i].Status == DeleteStatusNotFound {
+			continue
+		}
+		if removeErr != nil {
+			results[i].Status = DeleteStatusFailed
+			results[i].Error = removeErr.Error()
+			continue
+		}
+		results[i].Status = DeleteStatusDeleted
+	}
+
+	if removeErr == nil {
+		for _, id := range removeIDs {
+			t := toRemove[id]
+			c.addToHistory(t, "manual", "")
+			c.logger.WithFields(logger.TorrentFields(t)).Infof("Manually deleted torrent: %s (ID: %d)", t.Name, t.ID)
+		}
+		c.saveHistory()
+	}
+
+	return results, nil
 }
 
 // AddManualDeletion adds a manually deleted torrent to history
@@ -346,7 +803,8 @@ func (c *Cleaner) AddManualDeletion(id int, name, tracker string, size int64) {
 		Name:             name,
 		NormalizedTracker: tracker,
 		TotalSize:        size,
-	}, "manual")
+	}, "manual", "")
+	c.saveHistory()
 }
 
 // GetHistory returns the deletion history
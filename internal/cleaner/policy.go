@@ -0,0 +1,115 @@
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Celedhrim/btcleaner/pkg/models"
+)
+
+// Selection strategies: the order in which torrents are removed
+const (
+	StrategyOldest  = "oldest"  // Oldest added torrents first
+	StrategyLargest = "largest" // Largest torrents first, oldest first on ties
+)
+
+// ErrInvalidPolicy is returned when policy settings are invalid
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Policy holds the settings that decide when and which torrents are removed
+type Policy struct {
+	MinFreeSpace          int64          `json:"min_free_space"`           // Bytes
+	MinTorrentsPerTracker int            `json:"min_torrents_per_tracker"` // Default minimum kept per tracker
+	TrackerMinimums       map[string]int `json:"tracker_minimums"`         // Per-tracker minimums, by normalized tracker
+	Strategy              string         `json:"strategy"`
+	Predictive            bool           `json:"predictive"` // Subtract the bytes left to download by the active torrents from the free space
+}
+
+// Validate checks the policy settings
+func (p Policy) Validate() error {
+	if p.MinFreeSpace <= 0 {
+		return fmt.Errorf("%w: min free space must be positive", ErrInvalidPolicy)
+	}
+	if p.MinTorrentsPerTracker < 0 {
+		return fmt.Errorf("%w: min torrents per tracker must not be negative", ErrInvalidPolicy)
+	}
+	for tracker, min := range p.TrackerMinimums {
+		if tracker == "" {
+			return fmt.Errorf("%w: tracker minimum without tracker name", ErrInvalidPolicy)
+		}
+		if min < 0 {
+			return fmt.Errorf("%w: minimum for tracker %s must not be negative", ErrInvalidPolicy, tracker)
+		}
+	}
+	if p.Strategy != StrategyOldest && p.Strategy != StrategyLargest {
+		return fmt.Errorf("%w: unknown strategy %q (expected %s or %s)", ErrInvalidPolicy, p.Strategy, StrategyOldest, StrategyLargest)
+	}
+	return nil
+}
+
+// minimumFor returns the minimum number of torrents to keep for a tracker
+func (p Policy) minimumFor(tracker string) int {
+	if min, ok := p.TrackerMinimums[strings.ToLower(tracker)]; ok {
+		return min
+	}
+	return p.MinTorrentsPerTracker
+}
+
+// clone returns a copy of the policy that does not share the tracker minimums map
+func (p Policy) clone() Policy {
+	minimums := make(map[string]int, len(p.TrackerMinimums))
+	for tracker, min := range p.TrackerMinimums {
+		minimums[strings.ToLower(tracker)] = min
+	}
+	p.TrackerMinimums = minimums
+	return p
+}
+
+// sortForRemoval sorts torrents in the order the strategy removes them
+func (p Policy) sortForRemoval(torrents []models.Torrent) {
+	switch p.Strategy {
+	case StrategyLargest:
+		sort.SliceStable(torrents, func(i, j int) bool {
+			if torrents[i].TotalSize != torrents[j].TotalSize {
+				return torrents[i].TotalSize > torrents[j].TotalSize
+			}
+			return torrents[i].AddedDate.Before(torrents[j].AddedDate)
+		})
+	default:
+		sort.Sort(models.TorrentsByAge(torrents))
+	}
+}
+
+// Policy returns the current cleanup policy
+func (c *Cleaner) Policy() Policy {
+	c.policyMutex.RLock()
+	defer c.policyMutex.RUnlock()
+	return c.policy.clone()
+}
+
+// SetPolicy replaces the cleanup policy, used by the next runs
+func (c *Cleaner) SetPolicy(p Policy) error {
+	if p.Strategy == "" {
+		p.Strategy = StrategyOldest
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	c.policyMutex.Lock()
+	defer c.policyMutex.Unlock()
+	c.policy = p.clone()
+	return nil
+}
+
+// DryRun reports whether cleanup runs only log the torrents they would remove
+func (c *Cleaner) DryRun() bool {
+	return c.dryRun.Load()
+}
+
+// SetDryRun enables or disables the dry-run mode of the next runs
+func (c *Cleaner) SetDryRun(dryRun bool) {
+	c.dryRun.Store(dryRun)
+}
@@ -0,0 +1,319 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/events"
+)
+
+// MaxRunRecords is the number of cleanup runs kept in the run history
+const MaxRunRecords = 500
+
+// Files written to the data directory
+const (
+	runsFileName    = "runs.json"
+	historyFileName = "history.json"
+)
+
+// What started a cleanup run
+const (
+	TriggerStartup = "startup" // Initial check when btcleaner starts
+	TriggerTimer   = "timer"   // Periodic check in daemon mode
+	TriggerAPI     = "api"     // Requested through the API or web UI
+	TriggerEvent   = "event"   // Started by a disk or Transmission event
+	TriggerCLI     = "cli"     // Started with the run command
+)
+
+// Run record statuses
+const (
+	RunStatusSuccess = "success" // Run completed without errors
+	RunStatusPartial = "partial" // Run completed, but some steps failed
+	RunStatusFailed  = "failed"  // Run aborted
+)
+
+// RunRecord is the persisted record of a cleanup run
+type RunRecord struct {
+	CleanupResult
+	Trigger    string    `json:"trigger"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// RunSummary is a run record without the per-torrent details
+type RunSummary struct {
+	RunID                string    `json:"run_id"`
+	Trigger              string    `json:"trigger"`
+	Status               string    `json:"status"`
+	StartedAt            time.Time `json:"started_at"`
+	FinishedAt           time.Time `json:"finished_at"`
+	DurationMs           int64     `json:"duration_ms"`
+	DryRun               bool      `json:"dry_run"`
+	Deferred             bool      `json:"deferred,omitempty"`
+	Emergency            bool      `json:"emergency,omitempty"`
+	NeedCleanup          bool      `json:"need_cleanup"`
+	InitialFreeSpace     int64     `json:"initial_free_space"`
+	FinalFreeSpace       int64     `json:"final_free_space"`
+	CandidatesConsidered int       `json:"candidates_considered"`
+	RemovedCount         int       `json:"removed_count"`
+	RemovedSize          int64     `json:"removed_size"`
+	SkippedCount         int       `json:"skipped_count"`
+	ErrorCount           int       `json:"error_count"`
+}
+
+// Summary returns the record without the per-torrent details
+func (r *RunRecord) Summary() RunSummary {
+	return RunSummary{
+		RunID:                r.RunID,
+		Trigger:              r.Trigger,
+		Status:               r.Status,
+		StartedAt:            r.StartedAt,
+		FinishedAt:           r.FinishedAt,
+		DurationMs:           r.DurationMs,
+		DryRun:               r.DryRun,
+		Deferred:             r.Deferred,
+		Emergency:            r.Emergency,
+		NeedCleanup:          r.NeedCleanup,
+		InitialFreeSpace:     r.InitialFreeSpace,
+		FinalFreeSpace:       r.FinalFreeSpace,
+		CandidatesConsidered: r.CandidatesConsidered,
+		RemovedCount:         r.RemovedCount,
+		RemovedSize:          r.RemovedSize,
+		SkippedCount:         len(r.Skipped),
+		ErrorCount:           len(r.Errors),
+	}
+}
+
+// runStore keeps the most recent run records, newest first
+type runStore struct {
+	records []RunRecord
+	mu      sync.RWMutex
+}
+
+// SetDataDir enables persistence of the run records, deletion history and
+// free space samples in dir, and loads the records saved by a previous instance
+func (c *Cleaner) SetDataDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	var records []RunRecord
+	if err := readJSONFile(filepath.Join(dir, runsFileName), &records); err != nil {
+		return err
+	}
+	var history []DeletedTorrent
+	if err := readJSONFile(filepath.Join(dir, historyFileName), &history); err != nil {
+		return err
+	}
+	samples, sampleLines, err := loadSpaceSamples(dir)
+	if err != nil {
+		return err
+	}
+
+	c.runs.mu.Lock()
+	c.dataDir = dir
+	if records != nil {
+		c.runs.records = records
+	}
+	c.runs.mu.Unlock()
+
+	if history != nil {
+		c.historyMutex.Lock()
+		c.history = history
+		c.historyMutex.Unlock()
+	}
+
+	c.space.mu.Lock()
+	if samples != nil {
+		c.space.samples = samples
+	}
+	c.space.fileLines = sampleLines
+	c.space.mu.Unlock()
+
+	c.logger.Infof("Loaded %d cleanup runs, %d deleted torrents and %d free space samples from %s",
+		len(records), len(history), len(samples), dir)
+	return nil
+}
+
+// recordRun stores the record of a finished cleanup run
+func (c *Cleaner) recordRun(trigger string, startedAt time.Time, result *CleanupResult, runErr error) {
+	finishedAt := time.Now()
+	record := RunRecord{
+		CleanupResult: *result,
+		Trigger:       trigger,
+		Status:        RunStatusSuccess,
+		StartedAt:     startedAt,
+		FinishedAt:    finishedAt,
+		DurationMs:    finishedAt.Sub(startedAt).Milliseconds(),
+	}
+	if runErr != nil {
+		record.Status = RunStatusFailed
+		record.Errors = append(record.Errors, runErr.Error())
+	} else if len(result.Errors) > 0 {
+		record.Status = RunStatusPartial
+	}
+
+	c.runs.mu.Lock()
+	c.runs.records = append([]RunRecord{record}, c.runs.records...)
+	if len(c.runs.records) > MaxRunRecords {
+		c.runs.records = c.runs.records[:MaxRunRecords]
+	}
+	dir := c.dataDir
+	var data []RunRecord
+	if dir != "" {
+		data = make([]RunRecord, len(c.runs.records))
+		copy(data, c.runs.records)
+	}
+	c.runs.mu.Unlock()
+
+	if dir != "" {
+		if err := writeJSONFile(filepath.Join(dir, runsFileName), data); err != nil {
+			c.logger.Warnf("Failed to save cleanup runs: %v", err)
+		}
+		if !result.DryRun && result.RemovedCount > 0 {
+			c.saveHistory()
+		}
+	}
+
+	finished := events.CleanupFinished{
+		RunID:            record.RunID,
+		Trigger:          record.Trigger,
+		Status:           record.Status,
+		DryRun:           record.DryRun,
+		Deferred:         record.Deferred,
+		NeedCleanup:      record.NeedCleanup,
+		InitialFreeSpace: record.InitialFreeSpace,
+		FinalFreeSpace:   record.FinalFreeSpace,
+		RemovedCount:     record.RemovedCount,
+		RemovedSize:      record.RemovedSize,
+		DurationMs:       record.DurationMs,
+	}
+	if runErr != nil {
+		finished.Error = runErr.Error()
+	} else if shortfall := record.MinFreeSpace - (record.InitialFreeSpace - record.PendingDownloads + record.RemovedSize); record.NeedCleanup && shortfall > 0 {
+		finished.SpaceShortfall = shortfall
+	}
+	c.events.Publish(events.TopicCleanupFinished, finished)
+
+	if runErr == nil && result.Blocked != nil {
+		blocked := events.CleanupBlocked{
+			RunID:          record.RunID,
+			DryRun:         record.DryRun,
+			ShortfallBytes: result.Blocked.ShortfallBytes,
+			Trackers:       make([]string, len(result.Blocked.Constraints)),
+			Resolvable:     result.Blocked.Resolvable,
+			Message:        result.Blocked.Message,
+		}
+		for i, bc := range result.Blocked.Constraints {
+			blocked.Trackers[i] = bc.Tracker
+		}
+		c.events.Publish(events.TopicCleanupBlocked, blocked)
+	}
+}
+
+// saveHistory writes the deletion history to the data directory, if any
+func (c *Cleaner) saveHistory() {
+	c.runs.mu.RLock()
+	dir := c.dataDir
+	c.runs.mu.RUnlock()
+	if dir == "" {
+		return
+	}
+
+	if err := writeJSONFile(filepath.Join(dir, historyFileName), c.GetHistory()); err != nil {
+		c.logger.Warnf("Failed to save deletion history: %v", err)
+	}
+}
+
+// GetRuns returns the summaries of the recorded cleanup runs, newest first
+func (c *Cleaner) GetRuns() []RunSummary {
+	c.runs.mu.RLock()
+	defer c.runs.mu.RUnlock()
+
+	summaries := make([]RunSummary, len(c.runs.records))
+	for i := range c.runs.records {
+		summaries[i] = c.runs.records[i].Summary()
+	}
+	return summaries
+}
+
+// LastCompletedRun returns the end time of the most recent run that completed
+// its check, with or without removal errors
+func (c *Cleaner) LastCompletedRun() (time.Time, bool) {
+	c.runs.mu.RLock()
+	defer c.runs.mu.RUnlock()
+
+	for _, r := range c.runs.records {
+		if r.Status != RunStatusFailed {
+			return r.FinishedAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// GetRun returns the record of a cleanup run
+func (c *Cleaner) GetRun(id string) (*RunRecord, bool) {
+	c.runs.mu.RLock()
+	defer c.runs.mu.RUnlock()
+
+	for i := range c.runs.records {
+		if c.runs.records[i].RunID == id {
+			record := c.runs.records[i]
+			return &record, true
+		}
+	}
+	return nil, false
+}
+
+// readJSONFile decodes a JSON file into v. A missing file is not an error.
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSONFile atomically replaces a file with the JSON encoding of v
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile atomically replaces a file with data
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,114 @@
+package cleaner
+
+import (
+	"fmt"
+
+	"github.com/Celedhrim/btcleaner/pkg/models"
+)
+
+// SimulationParams are hypothetical settings for a simulation. Unset fields
+// use the current policy.
+type SimulationParams struct {
+	MinFreeSpace          *int64         // Bytes
+	MinTorrentsPerTracker *int           // Default minimum per tracker
+	TrackerMinimums       map[string]int // Replaces the configured per-tracker minimums if not nil
+	Strategy              string
+	Predictive            *bool // Overrides whether the active downloads must fit
+	IncomingSize          int64 // Size of an upcoming download that must fit (bytes)
+}
+
+// SimulationResult is the outcome of a cleanup simulation
+type SimulationResult struct {
+	Policy             Policy           `json:"policy"`
+	FreeSpace          int64            `json:"free_space"`
+	IncomingSize       int64            `json:"incoming_size"`
+	PendingDownloads   int64            `json:"pending_downloads"` // Bytes left to download by the active torrents, counted with a predictive policy
+	SpaceNeeded        int64            `json:"space_needed"`
+	NeedCleanup        bool             `json:"need_cleanup"`
+	Selected           []models.Torrent `json:"selected"` // In removal order
+	SelectedSize       int64            `json:"selected_size"`
+	ProjectedFreeSpace int64            `json:"projected_free_space"` // After the removals and the incoming download
+	Sufficient         bool             `json:"sufficient"`           // Whether enough space can be freed
+	Considered         int              `json:"considered"`
+	Skipped            []SkippedTorrent `json:"skipped"`
+	Blocked            *Blocked         `json:"blocked,omitempty"` // Set when tracker minimums prevent reaching the minimum free space
+}
+
+// Simulate computes which torrents a cleanup would remove with hypothetical
+// settings, using the current free space and torrents. Nothing is removed.
+func (c *Cleaner) Simulate(params SimulationParams) (*SimulationResult, error) {
+	policy := c.Policy()
+	if params.MinFreeSpace != nil {
+		policy.MinFreeSpace = *params.MinFreeSpace
+	}
+	if params.MinTorrentsPerTracker != nil {
+		policy.MinTorrentsPerTracker = *params.MinTorrentsPerTracker
+	}
+	if params.TrackerMinimums != nil {
+		policy.TrackerMinimums = params.TrackerMinimums
+	}
+	if params.Strategy != "" {
+		policy.Strategy = params.Strategy
+	}
+	if params.Predictive != nil {
+		policy.Predictive = *params.Predictive
+	}
+	policy = policy.clone()
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if params.IncomingSize < 0 {
+		return nil, fmt.Errorf("%w: incoming size must not be negative", ErrInvalidPolicy)
+	}
+
+	freeSpace, err := c.client.GetFreeSpace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get free space: %w", err)
+	}
+
+	result := &SimulationResult{
+		Policy:       policy,
+		FreeSpace:    freeSpace,
+		IncomingSize: params.IncomingSize,
+		Selected:     []models.Torrent{},
+		Skipped:      []SkippedTorrent{},
+		Sufficient:   true,
+	}
+
+	torrents, err := c.client.GetTorrents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+
+	// The incoming download, and the active ones with a predictive policy,
+	// consume free space before it is counted
+	available := freeSpace - params.IncomingSize
+	if policy.Predictive {
+		result.PendingDownloads = PendingDownloads(torrents)
+		available -= result.PendingDownloads
+	}
+	result.ProjectedFreeSpace = available
+	if available >= policy.MinFreeSpace {
+		return result, nil
+	}
+
+	result.NeedCleanup = true
+	result.SpaceNeeded = policy.MinFreeSpace - available
+
+	sel := c.selectTorrentsToRemove(c.logger, policy, torrents, result.SpaceNeeded)
+	if sel.Selected != nil {
+		result.Selected = sel.Selected
+	}
+	if sel.Skipped != nil {
+		result.Skipped = sel.Skipped
+	}
+	result.Considered = sel.Considered
+	for _, t := range result.Selected {
+		result.SelectedSize += t.TotalSize
+	}
+	result.ProjectedFreeSpace = available + result.SelectedSize
+	result.Sufficient = result.SelectedSize >= result.SpaceNeeded
+	result.Blocked = blockedBy(policy, torrents, sel, result.SpaceNeeded)
+
+	return result, nil
+}
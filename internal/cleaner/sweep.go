@@ -0,0 +1,87 @@
+package cleaner
+
+import (
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/Celedhrim/btcleaner/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Sweep is a cleanup policy that does not depend on free space, such as the
+// removal of stalled or unregistered torrents. Its torrents are removed
+// whatever the free space, with the deletion windows and dry-run mode of the
+// run. In daemon mode, a sweep runs with every check or on its own schedule
+// (daemon.policy_schedules).
+type Sweep interface {
+	Name() string
+	Select(torrents []models.Torrent, now time.Time) []models.Torrent // Torrents to remove
+}
+
+// sweeps are the available sweeps. None is implemented yet: stalled and
+// unregistered torrents are not detected.
+var sweeps []Sweep
+
+// SweepNames returns the names of the available sweeps, which may run on
+// their own schedule
+func SweepNames() []string {
+	names := make([]string, 0, len(sweeps))
+	for _, s := range sweeps {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// dueSweeps returns the sweeps to run at now. due reports whether a sweep
+// last run at the given time is due, nil to run them all.
+func (c *Cleaner) dueSweeps(due func(name string, last time.Time) bool, now time.Time) []Sweep {
+	var run []Sweep
+	for _, s := range sweeps {
+		if due == nil || due(s.Name(), c.sweptAt[s.Name()]) {
+			run = append(run, s)
+		}
+	}
+	return run
+}
+
+// sweep runs the sweeps at now and returns the torrents they select, each
+// torrent once. Runs being serialized, it is called with runMutex held.
+func (c *Cleaner) sweep(log *logrus.Entry, run []Sweep, torrents []models.Torrent, now time.Time) []models.Torrent {
+	if c.sweptAt == nil {
+		c.sweptAt = make(map[string]time.Time)
+	}
+	var selected []models.Torrent
+	seen := make(map[int]bool)
+	for _, s := range run {
+		c.sweptAt[s.Name()] = now
+		picked := s.Select(torrents, now)
+		log.Infof("Policy %s selected %d torrents", s.Name(), len(picked))
+		for _, t := range picked {
+			if seen[t.ID] {
+				continue
+			}
+			seen[t.ID] = true
+			log.WithFields(logger.TorrentFields(t)).Debugf("  Policy %s: [%s] %s", s.Name(), t.NormalizedTracker, t.Name)
+			selected = append(selected, t)
+		}
+	}
+	return selected
+}
+
+// withoutTorrents returns the torrents that are not in removed
+func withoutTorrents(torrents, removed []models.Torrent) []models.Torrent {
+	if len(removed) == 0 {
+		return torrents
+	}
+	ids := make(map[int]bool, len(removed))
+	for _, t := range removed {
+		ids[t.ID] = true
+	}
+	kept := make([]models.Torrent, 0, len(torrents))
+	for _, t := range torrents {
+		if !ids[t.ID] {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
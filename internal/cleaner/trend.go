@@ -0,0 +1,255 @@
+package cleaner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxSpaceSamples is the number of free space samples kept, a day of
+	// checks every minute
+	MaxSpaceSamples = 1440
+	// TrendWindow is the period over which the fill rate is computed
+	TrendWindow = 6 * time.Hour
+	// maxForecast bounds the time to threshold, further estimates are meaningless
+	maxForecast = 365 * 24 * time.Hour
+)
+
+// Files of the free space samples in the data directory
+const (
+	spaceFileName       = "space.jsonl" // One JSON sample per line, appended at each check
+	legacySpaceFileName = "space.json"  // JSON array written by older versions
+)
+
+// SpaceSample is the free space measured by a cleanup check
+type SpaceSample struct {
+	Time         time.Time `json:"time"`
+	FreeSpace    int64     `json:"free_space"`     // Bytes
+	MinFreeSpace int64     `json:"min_free_space"` // Configured minimum at the time (bytes)
+}
+
+// SpaceTrend estimates how fast the disk fills up and when free space drops
+// below the minimum
+type SpaceTrend struct {
+	Samples                int        `json:"samples"`                             // Samples within the trend window
+	WindowSeconds          int64      `json:"window_seconds"`                      // Time between the first and last of these samples
+	FillRateBytesPerHour   int64      `json:"fill_rate_bytes_per_hour"`            // Average decrease of free space, increases (cleanups, deletions) ignored
+	TimeToThresholdSeconds *int64     `json:"time_to_threshold_seconds,omitempty"` // Until free space drops below the minimum, 0 if it already is, absent if it does not decrease
+	ThresholdAt            *time.Time `json:"threshold_at,omitempty"`
+}
+
+// spaceStore keeps the most recent free space samples, oldest first
+type spaceStore struct {
+	samples   []SpaceSample
+	fileLines int // Samples in the file, compacted beyond twice MaxSpaceSamples
+	mu        sync.RWMutex
+}
+
+// recordSpace adds a free space sample, and appends it to the samples file of
+// the data directory if any
+func (c *Cleaner) recordSpace(freeSpace int64) {
+	sample := SpaceSample{
+		Time:         time.Now(),
+		FreeSpace:    freeSpace,
+		MinFreeSpace: c.Policy().MinFreeSpace,
+	}
+
+	c.runs.mu.RLock()
+	dir := c.dataDir
+	c.runs.mu.RUnlock()
+
+	c.space.mu.Lock()
+	defer c.space.mu.Unlock()
+	c.space.samples = append(c.space.samples, sample)
+	if len(c.space.samples) > MaxSpaceSamples {
+		c.space.samples = append([]SpaceSample(nil), c.space.samples[len(c.space.samples)-MaxSpaceSamples:]...)
+	}
+	if dir == "" {
+		return
+	}
+
+	// Rewriting the whole file at each check would wear out flash storage,
+	// samples are appended and the file is compacted once in a while
+	path := filepath.Join(dir, spaceFileName)
+	if c.space.fileLines >= 2*MaxSpaceSamples {
+		if err := writeSpaceFile(path, c.space.samples); err != nil {
+			c.logger.Warnf("Failed to save free space samples: %v", err)
+			return
+		}
+		c.space.fileLines = len(c.space.samples)
+		return
+	}
+	if err := appendSpaceSample(path, sample); err != nil {
+		c.logger.Warnf("Failed to save free space sample: %v", err)
+		return
+	}
+	c.space.fileLines++
+}
+
+// loadSpaceSamples reads the most recent MaxSpaceSamples samples saved in dir,
+// and the number of lines of the samples file. The file of older versions is
+// converted.
+func loadSpaceSamples(dir string) ([]SpaceSample, int, error) {
+	path := filepath.Join(dir, spaceFileName)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return convertLegacySpaceFile(dir)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var samples []SpaceSample
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+		var s SpaceSample
+		// Skip a line cut short by a crash
+		if json.Unmarshal(scanner.Bytes(), &s) == nil {
+			samples = append(samples, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(samples) > MaxSpaceSamples {
+		samples = samples[len(samples)-MaxSpaceSamples:]
+	}
+	return samples, lines, nil
+}
+
+// convertLegacySpaceFile converts the samples file of older versions, if any,
+// and returns its samples
+func convertLegacySpaceFile(dir string) ([]SpaceSample, int, error) {
+	legacyPath := filepath.Join(dir, legacySpaceFileName)
+	var samples []SpaceSample
+	if err := readJSONFile(legacyPath, &samples); err != nil || samples == nil {
+		return nil, 0, err
+	}
+
+	path := filepath.Join(dir, spaceFileName)
+	if err := writeSpaceFile(path, samples); err != nil {
+		return nil, 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Remove(legacyPath); err != nil {
+		return nil, 0, fmt.Errorf("failed to remove %s: %w", legacyPath, err)
+	}
+	return samples, len(samples), nil
+}
+
+// writeSpaceFile atomically replaces the samples file with samples
+func writeSpaceFile(path string, samples []SpaceSample) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// appendSpaceSample appends a sample to the samples file
+func appendSpaceSample(path string, s SpaceSample) error {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SpaceHistory returns the free space samples taken since the given time,
+// oldest first
+func (c *Cleaner) SpaceHistory(since time.Time) []SpaceSample {
+	c.space.mu.RLock()
+	defer c.space.mu.RUnlock()
+
+	samples := make([]SpaceSample, 0, len(c.space.samples))
+	for _, s := range c.space.samples {
+		if !s.Time.Before(since) {
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// SpaceTrend computes the fill rate over the last TrendWindow of samples and
+// the time until free space drops below the current minimum
+func (c *Cleaner) SpaceTrend() SpaceTrend {
+	c.space.mu.RLock()
+	samples := c.space.samples
+	var recent []SpaceSample
+	if len(samples) > 0 {
+		start := samples[len(samples)-1].Time.Add(-TrendWindow)
+		for i, s := range samples {
+			if !s.Time.Before(start) {
+				recent = append([]SpaceSample(nil), samples[i:]...)
+				break
+			}
+		}
+	}
+	c.space.mu.RUnlock()
+
+	return computeTrend(recent, c.Policy().MinFreeSpace, time.Now())
+}
+
+// computeTrend estimates the trend of samples, oldest first, against the
+// minimum free space at now
+func computeTrend(samples []SpaceSample, minFreeSpace int64, now time.Time) SpaceTrend {
+	trend := SpaceTrend{Samples: len(samples)}
+	if len(samples) < 2 {
+		return trend
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+	window := last.Time.Sub(first.Time)
+	trend.WindowSeconds = int64(window.Seconds())
+	if window < time.Minute {
+		return trend
+	}
+
+	// Only the decreases: cleanups would otherwise hide the downloads
+	var consumed int64
+	for i := 1; i < len(samples); i++ {
+		if d := samples[i-1].FreeSpace - samples[i].FreeSpace; d > 0 {
+			consumed += d
+		}
+	}
+	trend.FillRateBytesPerHour = int64(float64(consumed) / window.Hours())
+
+	var remaining time.Duration
+	switch {
+	case last.FreeSpace < minFreeSpace:
+		// Already below
+	case trend.FillRateBytesPerHour > 0:
+		hours := float64(last.FreeSpace-minFreeSpace) / float64(trend.FillRateBytesPerHour)
+		if hours > maxForecast.Hours() {
+			return trend
+		}
+		remaining = time.Duration(hours * float64(time.Hour))
+	default:
+		return trend
+	}
+	at := last.Time.Add(remaining)
+	seconds := int64(max(at.Sub(now), 0).Seconds())
+	trend.TimeToThresholdSeconds = &seconds
+	trend.ThresholdAt = &at
+	return trend
+}
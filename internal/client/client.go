@@ -0,0 +1,213 @@
+// Package client calls the REST API of a running btcleaner instance
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/server"
+)
+
+const (
+	// runPollInterval is how often the record of a requested run is polled
+	runPollInterval = 500 * time.Millisecond
+	// runTimeout is how long to wait for a requested run to finish
+	runTimeout = 30 * time.Minute
+)
+
+// ErrNotFound is returned when the API responds with HTTP 404
+var ErrNotFound = errors.New("not found")
+
+// Client is a btcleaner API v1 client
+type Client struct {
+	baseURL string // Instance URL including its webroot, without trailing slash
+	token   string
+	client  *http.Client
+}
+
+// NewClient creates a client for the instance at baseURL (e.g.
+// "http://host:8888/btcleaner"). If baseURL has no path, webRoot is used
+// instead, so the server.webroot setting of the config file applies.
+func NewClient(baseURL, webRoot, token string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q: must be an http(s) URL", baseURL)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = webRoot
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Path != "" && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	u.RawQuery, u.Fragment = "", ""
+
+	return &Client{
+		baseURL: u.String(),
+		token:   token,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// do sends an API request and decodes the JSON response into out
+func (c *Client) do(method, endpoint string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+"/api/v1"+endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr server.ErrorResponse
+		msg := http.StatusText(resp.StatusCode)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			msg = apiErr.Error.Message
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrNotFound, msg)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
+	}
+
+	if out != nil {
+		// Numbers stay exact in untyped values, such as the configuration
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response from %s: %w", c.baseURL, err)
+		}
+	}
+	return nil
+}
+
+// Stats returns the statistics of the instance
+func (c *Client) Stats() (*cleaner.Stats, error) {
+	var stats cleaner.Stats
+	if err := c.do(http.MethodGet, "/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Candidates returns the torrents a cleanup would remove now
+func (c *Client) Candidates() (*server.CandidatesResponse, error) {
+	var resp server.CandidatesResponse
+	if err := c.do(http.MethodGet, "/candidates", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// History returns the deleted torrents
+func (c *Client) History() ([]cleaner.DeletedTorrent, error) {
+	var resp server.HistoryResponse
+	if err := c.do(http.MethodGet, "/history", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.History, nil
+}
+
+// Config returns the configuration of the instance, secrets redacted
+func (c *Client) Config() (*server.ConfigResponse, error) {
+	var resp server.ConfigResponse
+	if err := c.do(http.MethodGet, "/config", nil, &resp); err != nil {
+		return nil, err
+	}
+	resp.Config, _ = numbers(resp.Config).(map[string]interface{})
+	return &resp, nil
+}
+
+// numbers converts the JSON numbers of a decoded value to int64, or float64
+// if they are not integers
+func numbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = numbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = numbers(item)
+		}
+	}
+	return v
+}
+
+// DeleteTorrents deletes torrents and their data by ID or hash
+func (c *Client) DeleteTorrents(ids []int, hashes []string) ([]cleaner.DeleteResult, error) {
+	deleteData := true
+	req := server.BulkDeleteRequest{IDs: ids, Hashes: hashes, DeleteData: &deleteData}
+	var resp server.BulkDeleteResponse
+	if err := c.do(http.MethodPost, "/torrents/delete", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// RunCleanup requests a cleanup run and waits for its record. A run that
+// failed is returned with an error, as a local run would.
+func (c *Client) RunCleanup(req server.CleanupRunRequest) (*cleaner.RunRecord, error) {
+	var started server.CleanupRunResponse
+	if err := c.do(http.MethodPost, "/cleanup/run", req, &started); err != nil {
+		return nil, err
+	}
+
+	// The record exists once the run is finished
+	deadline := time.Now().Add(runTimeout)
+	for {
+		var record cleaner.RunRecord
+		err := c.do(http.MethodGet, "/runs/"+url.PathEscape(started.RunID), nil, &record)
+		if err == nil {
+			if record.Status == cleaner.RunStatusFailed && len(record.Errors) > 0 {
+				return &record, errors.New(record.Errors[len(record.Errors)-1])
+			}
+			return &record, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("run %s did not finish within %s", started.RunID, runTimeout)
+		}
+		time.Sleep(runPollInterval)
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles granted to authenticated users
+const (
+	RoleViewer   = "viewer"   // Read-only access to the dashboard and API
+	RoleOperator = "operator" // Can also delete torrents and change settings
+)
+
+// AuthConfig holds web UI and API authentication settings
+type AuthConfig struct {
+	Enabled       bool                `mapstructure:"enabled"`
+	SessionTTL    time.Duration       `mapstructure:"session_ttl"`
+	Users         []UserConfig        `mapstructure:"users"`
+	Tokens        []TokenConfig       `mapstructure:"tokens"`
+	TrustedHeader TrustedHeaderConfig `mapstructure:"trusted_header"`
+}
+
+// UserConfig is a local user allowed to log in to the web UI
+type UserConfig struct {
+	Username     string `mapstructure:"username"`
+	PasswordHash Secret `mapstructure:"password_hash"` // bcrypt hash
+	Role         string `mapstructure:"role"`
+}
+
+// TokenConfig is an API token for scripts (sent as "Authorization: Bearer <token>")
+type TokenConfig struct {
+	Name        string `mapstructure:"name"`
+	TokenSHA256 Secret `mapstructure:"token_sha256"` // hex-encoded SHA-256 of the token
+	Role        string `mapstructure:"role"`
+}
+
+// TrustedHeaderConfig enables authentication by an SSO reverse proxy (Authelia, Authentik...)
+// which passes the authenticated user in request headers
+type TrustedHeaderConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	UserHeader     string   `mapstructure:"user_header"`
+	GroupsHeader   string   `mapstructure:"groups_header"`
+	OperatorGroups []string `mapstructure:"operator_groups"`
+	DefaultRole    string   `mapstructure:"default_role"`
+}
+
+// ValidRole reports whether role is a known role name
+func ValidRole(role string) bool {
+	return role == RoleViewer || role == RoleOperator
+}
+
+// ParseTrustedProxies parses a list of IP addresses or CIDR ranges
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address: %s", p)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range: %s", p)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// validateAuth checks the authentication settings of the web server
+func validateAuth(srv *ServerConfig, v *validator) {
+	if _, err := ParseTrustedProxies(srv.TrustedProxies); err != nil {
+		v.add("server.trusted_proxies", "%v", err)
+	}
+
+	auth := &srv.Auth
+	if !auth.Enabled {
+		return
+	}
+
+	if len(auth.Users) == 0 && len(auth.Tokens) == 0 && !auth.TrustedHeader.Enabled {
+		v.add("server.auth.enabled", "auth is enabled but no users, tokens or trusted header are configured")
+	}
+	if auth.SessionTTL <= 0 {
+		v.add("server.auth.session_ttl", "must be positive")
+	}
+
+	seen := make(map[string]bool)
+	for i, u := range auth.Users {
+		key := fmt.Sprintf("server.auth.users[%d]", i)
+		if u.Username == "" {
+			v.add(key+".username", "is required")
+		} else if seen[u.Username] {
+			v.add(key+".username", "user %q is defined twice", u.Username)
+		}
+		seen[u.Username] = true
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash.Value())); err != nil {
+			v.add(key+".password_hash", "is not a valid bcrypt hash")
+		}
+		if !ValidRole(u.Role) {
+			v.add(key+".role", "invalid role %q (expected %s or %s)", u.Role, RoleViewer, RoleOperator)
+		}
+	}
+
+	for i, t := range auth.Tokens {
+		key := fmt.Sprintf("server.auth.tokens[%d]", i)
+		hash, err := hex.DecodeString(t.TokenSHA256.Value())
+		if err != nil || len(hash) != 32 {
+			v.add(key+".token_sha256", "must be a hex-encoded SHA-256 hash")
+		}
+		if !ValidRole(t.Role) {
+			v.add(key+".role", "invalid role %q (expected %s or %s)", t.Role, RoleViewer, RoleOperator)
+		}
+	}
+
+	th := &auth.TrustedHeader
+	if th.Enabled {
+		if len(srv.TrustedProxies) == 0 {
+			v.add("server.trusted_proxies", "must be set to use auth trusted_header")
+		}
+		if th.UserHeader == "" {
+			v.add("server.auth.trusted_header.user_header", "is required")
+		}
+		if !ValidRole(th.DefaultRole) {
+			v.add("server.auth.trusted_header.default_role", "invalid role %q (expected %s or %s)", th.DefaultRole, RoleViewer, RoleOperator)
+		}
+	}
+}
@@ -14,19 +14,33 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Transmission TransmissionConfig `mapstructure:"transmission"`
-	Cleaner      CleanerConfig      `mapstructure:"cleaner"`
-	Server       ServerConfig       `mapstructure:"server"`
-	Daemon       DaemonConfig       `mapstructure:"daemon"`
-	DryRun       bool               `mapstructure:"dry_run"`
-	LogLevel     string             `mapstructure:"log_level"`
+	Transmission  TransmissionConfig   `mapstructure:"transmission"`
+	Cleaner       CleanerConfig        `mapstructure:"cleaner"`
+	Server        ServerConfig         `mapstructure:"server"`
+	Daemon        DaemonConfig         `mapstructure:"daemon"`
+	DryRun        bool                 `mapstructure:"dry_run"`
+	LogLevel      string               `mapstructure:"log_level"`
+	LogFormat     string               `mapstructure:"log_format"`      // "text" or "json"
+	LogFile       string               `mapstructure:"log_file"`        // Also write the logs to this file, empty for stderr only
+	LogMaxSizeRaw string               `mapstructure:"log_max_size"`    // Rotate the log file beyond this size (e.g. "100MB"), 0 to disable
+	LogMaxSize    int64                `mapstructure:"-"`               // Parsed value in bytes
+	LogMaxAge     time.Duration        `mapstructure:"log_max_age"`     // Rotate the log file once it has been written to for this long, 0 to disable
+	LogMaxBackups int                  `mapstructure:"log_max_backups"` // Rotated files kept, 0 to keep them all
+	LogBufferSize int                  `mapstructure:"log_buffer_size"` // Log entries kept in memory for the web UI and API
+	DataDir       string               `mapstructure:"data_dir"`        // Persists run records and history, empty to keep them in memory
+	Notifications []NotificationConfig `mapstructure:"notifications"`
+	File          string               `mapstructure:"-"` // Config file read, empty if none
+	Overrides     map[string]string    `mapstructure:"-"` // Keys set by environment variables and flags, with their source
 }
 
 // TransmissionConfig holds Transmission connection settings
 type TransmissionConfig struct {
-	URL      string `mapstructure:"url"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
+	URL             string        `mapstructure:"url"`
+	Username        string        `mapstructure:"username"`
+	Password        Secret        `mapstructure:"password"`
+	PasswordFile    string        `mapstructure:"password_file"`    // Read password from a file (e.g. /run/secrets/...)
+	PasswordCommand Secret        `mapstructure:"password_command"` // Read password from the output of a command, which may embed credentials
+	StartupTimeout  time.Duration `mapstructure:"startup_timeout"`  // How long to wait for Transmission at startup, 0 to wait forever
 }
 
 // CleanerConfig holds cleaner behavior settings
@@ -34,25 +48,78 @@ type CleanerConfig struct {
 	MinFreeSpaceRaw string `mapstructure:"min_free_space"` // Can be bytes or with unit (e.g., "100GB")
 	MinFreeSpace    int64  `mapstructure:"-"` // Parsed value in bytes
 	MinTorrentsPerTracker int `mapstructure:"min_torrents_per_tracker"`
+	TrackerMinimums []TrackerMinimum `mapstructure:"tracker_minimums"` // Per-tracker overrides of min_torrents_per_tracker
+	Strategy        string           `mapstructure:"strategy"`         // Removal order: "oldest" or "largest"
+	Predictive      bool             `mapstructure:"predictive"`       // Subtract the bytes left to download by the active torrents from the free space
+}
+
+// TrackerMinimum overrides the minimum number of torrents kept for one tracker
+type TrackerMinimum struct {
+	Tracker     string `mapstructure:"tracker"` // Normalized tracker domain, as shown in the web UI
+	MinTorrents int    `mapstructure:"min_torrents"`
+}
+
+// TrackerMinimumsMap returns the per-tracker minimums indexed by tracker
+func (c *CleanerConfig) TrackerMinimumsMap() map[string]int {
+	minimums := make(map[string]int, len(c.TrackerMinimums))
+	for _, tm := range c.TrackerMinimums {
+		minimums[strings.ToLower(tm.Tracker)] = tm.MinTorrents
+	}
+	return minimums
 }
 
 // ServerConfig holds web UI server settings
 type ServerConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Port    int    `mapstructure:"port"`
-	WebRoot string `mapstructure:"webroot"`
+	Enabled        bool            `mapstructure:"enabled"`
+	Port           int             `mapstructure:"port"`
+	WebRoot        string          `mapstructure:"webroot"`
+	UI             bool            `mapstructure:"ui"`              // Serve the HTML dashboard, disable to only expose the API and metrics
+	ReadyMaxAge    time.Duration   `mapstructure:"ready_max_age"`   // Max age of the last successful check for /readyz, 0 for 3 check intervals
+	Metrics        bool            `mapstructure:"metrics"`         // Expose Prometheus metrics on <webroot>/metrics
+	TrustedProxies []string        `mapstructure:"trusted_proxies"` // Reverse proxies allowed to set X-Forwarded-* and auth headers
+	AllowedOrigins []string        `mapstructure:"allowed_origins"` // Extra origins allowed for API calls and WebSockets
+	Auth           AuthConfig      `mapstructure:"auth"`
+	WebSocket      WebSocketConfig `mapstructure:"websocket"`
+}
+
+// Policies for WebSocket clients that do not keep up with the messages
+const (
+	SlowClientDrop       = "drop"       // Drop the messages that do not fit in the send queue
+	SlowClientDisconnect = "disconnect" // Close the connection when the send queue is full
+)
+
+// WebSocketConfig holds WebSocket delivery settings
+type WebSocketConfig struct {
+	SendQueueSize    int    `mapstructure:"send_queue_size"`    // Messages buffered per client
+	SlowClientPolicy string `mapstructure:"slow_client_policy"` // SlowClientDrop or SlowClientDisconnect
 }
 
 // DaemonConfig holds daemon mode settings
 type DaemonConfig struct {
-	Enabled       bool          `mapstructure:"enabled"`
-	CheckInterval time.Duration `mapstructure:"check_interval"`
+	Enabled               bool              `mapstructure:"enabled"`
+	CheckInterval         time.Duration     `mapstructure:"check_interval"`
+	Schedule              string            `mapstructure:"schedule"`             // Cron expression replacing check_interval (e.g. "*/10 * * * *")
+	DeleteWindows         []string          `mapstructure:"delete_windows"`       // Deletions only happen within these windows ("02:00-06:00"), any time if empty
+	BlockedWindows        []string          `mapstructure:"blocked_windows"`      // No deletion within these windows
+	EmergencyFreeSpaceRaw string            `mapstructure:"emergency_free_space"` // Windows are ignored below this free space (e.g. "20GB")
+	EmergencyFreeSpace    int64             `mapstructure:"-"`                    // Parsed value in bytes, 0 for none
+	PolicySchedules       map[string]string `mapstructure:"policy_schedules"`     // Cron expressions of the policies that do not depend on free space, by policy
+	Watch                 WatchConfig       `mapstructure:"watch"`
+}
+
+// WatchConfig holds the settings of the event-driven cleanup checks
+type WatchConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	DownloadDir  string        `mapstructure:"download_dir"`  // Directory watched for new files, Transmission's download-dir if empty
+	PollInterval time.Duration `mapstructure:"poll_interval"` // How often Transmission is asked for recently added torrents (at most 1m)
+	Debounce     time.Duration `mapstructure:"debounce"`      // Delay between activity and the free space forecast
+	MaxInterval  time.Duration `mapstructure:"max_interval"`  // Idle checks back off from check_interval up to this interval, 0 to disable
 }
 
-// parseSize parses a size string that can be either a plain number (bytes)
+// ParseSize parses a size string that can be either a plain number (bytes)
 // or a number with a unit suffix (KB, MB, GB, TB)
 // Examples: "100", "100GB", "500MB", "1.5TB"
-func parseSize(s string) (int64, error) {
+func ParseSize(s string) (int64, error) {
 	s = strings.TrimSpace(s)
 	
 	// Try to parse as plain number (bytes)
@@ -97,22 +164,30 @@ func parseSize(s string) (int64, error) {
 // Load loads configuration from file, environment variables, and CLI flags
 // Priority order (highest to lowest): CLI flags > Environment variables > Config file > Defaults
 func Load(version string) (*Config, error) {
-	// Set defaults
-	viper.SetDefault("transmission.url", "http://localhost:9091/transmission/rpc")
-	viper.SetDefault("cleaner.min_free_space", 100*1024*1024*1024) // 100 GB
-	viper.SetDefault("cleaner.min_torrents_per_tracker", 2)
-	viper.SetDefault("server.enabled", false)
-	viper.SetDefault("server.port", 8888)
-	viper.SetDefault("server.webroot", "/")
-	viper.SetDefault("daemon.enabled", false)
-	viper.SetDefault("daemon.check_interval", "1m")
-	viper.SetDefault("dry_run", false)
-	viper.SetDefault("log_level", "info")
+	ParseFlags(version)
+	return load(pflag.Lookup("config").Value.String(), true)
+}
+
+// LoadUnresolved loads the configuration like Load, without reading the
+// Transmission password from password_file or password_command. It is meant
+// for the commands that do not talk to Transmission, e.g. the health check.
+func LoadUnresolved(version string) (*Config, error) {
+	ParseFlags(version)
+	return load(pflag.Lookup("config").Value.String(), false)
+}
+
+// ParseFlags defines and parses the CLI flags, and handles --version. It does
+// nothing if the flags are already parsed.
+func ParseFlags(version string) {
+	if pflag.Parsed() {
+		return
+	}
 
 	// Setup CLI flags
 	pflag.StringP("transmission-url", "u", "", "Transmission RPC URL")
 	pflag.StringP("transmission-user", "U", "", "Transmission username")
-	pflag.StringP("transmission-pass", "P", "", "Transmission password")
+	pflag.StringP("transmission-pass", "P", "", "Transmission password (visible in ps, prefer --transmission-pass-file)")
+	pflag.String("transmission-pass-file", "", "Read Transmission password from file")
 	pflag.Int64P("min-free-space", "s", 0, "Minimum free space in GB (default: 100)")
 	pflag.IntP("min-torrents", "m", 0, "Minimum torrents per tracker (default: 2)")
 	pflag.BoolP("daemon", "d", false, "Run in daemon mode")
@@ -123,7 +198,12 @@ func Load(version string) (*Config, error) {
 	pflag.StringP("web-root", "r", "", "Web UI root path for reverse proxy (default: /)")
 	pflag.StringP("config", "c", "", "Config file path")
 	pflag.StringP("log-level", "l", "", "Log level (debug, info, warn, error)")
+	pflag.String("data-dir", "", "Directory where run records and history are persisted")
 	pflag.BoolP("version", "v", false, "Show version and exit")
+	pflag.Bool("healthcheck", false, "Check the readiness of the running instance and exit (for container health checks)")
+	pflag.Bool("json", false, "Print the output of commands as JSON")
+	pflag.String("server", "", "URL of a running btcleaner (e.g. http://host:8888/btcleaner) for commands to call its API instead of Transmission")
+	pflag.String("token", "", "API token for --server (visible in ps, prefer BTCLEANER_TOKEN)")
 	pflag.Parse()
 	
 	// Handle version flag
@@ -131,13 +211,73 @@ func Load(version string) (*Config, error) {
 		fmt.Printf("btcleaner version %s\n", version)
 		os.Exit(0)
 	}
+}
+
+// Reload reads the configuration again from the same sources as Load, with
+// the config file of the current configuration. CLI flags keep their priority.
+// password_command is only run again if it changed, as it may be slow or
+// prompt the user (e.g. a password manager).
+func Reload(current *Config) (*Config, error) {
+	cfg, err := load(current.File, false)
+	if err != nil {
+		return nil, err
+	}
+	if cmd := cfg.Transmission.PasswordCommand; cmd != "" && cmd == current.Transmission.PasswordCommand {
+		cfg.Transmission.Password = current.Transmission.Password
+		return cfg, nil
+	}
+	if err := cfg.resolvePassword(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// setDefaults sets the default configuration values
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("transmission.url", "http://localhost:9091/transmission/rpc")
+	v.SetDefault("transmission.startup_timeout", "2m")
+	v.SetDefault("cleaner.min_free_space", 100*1024*1024*1024) // 100 GB
+	v.SetDefault("cleaner.min_torrents_per_tracker", 2)
+	v.SetDefault("cleaner.strategy", "oldest")
+	v.SetDefault("cleaner.predictive", false)
+	v.SetDefault("server.enabled", false)
+	v.SetDefault("server.port", 8888)
+	v.SetDefault("server.webroot", "/")
+	v.SetDefault("server.ui", true)
+	v.SetDefault("server.metrics", true)
+	v.SetDefault("server.websocket.send_queue_size", 256)
+	v.SetDefault("server.websocket.slow_client_policy", SlowClientDrop)
+	v.SetDefault("server.auth.enabled", false)
+	v.SetDefault("server.auth.session_ttl", "24h")
+	v.SetDefault("server.auth.trusted_header.user_header", "Remote-User")
+	v.SetDefault("server.auth.trusted_header.groups_header", "Remote-Groups")
+	v.SetDefault("server.auth.trusted_header.default_role", RoleViewer)
+	v.SetDefault("daemon.enabled", false)
+	v.SetDefault("daemon.check_interval", "1m")
+	v.SetDefault("daemon.watch.enabled", false)
+	v.SetDefault("daemon.watch.poll_interval", "30s")
+	v.SetDefault("daemon.watch.debounce", "10s")
+	v.SetDefault("daemon.watch.max_interval", "30m")
+	v.SetDefault("dry_run", false)
+	v.SetDefault("log_level", "info")
+	v.SetDefault("log_format", "text")
+	v.SetDefault("log_max_size", "100MB")
+	v.SetDefault("log_max_age", "0")
+	v.SetDefault("log_max_backups", 5)
+	v.SetDefault("log_buffer_size", 1000)
+}
+
+// load reads and validates the configuration from the config file (searched
+// in the default locations if empty), environment variables and CLI flags
+func load(configFile string, resolve bool) (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
 
 	// STEP 1: Load config file first (lowest priority)
-	configFile := pflag.Lookup("config").Value.String()
 	if configFile != "" {
 		// User specified a config file
-		viper.SetConfigFile(configFile)
-		if err := viper.ReadInConfig(); err != nil {
+		v.SetConfigFile(configFile)
+		if err := v.ReadInConfig(); err != nil {
 			return nil, fmt.Errorf("error reading config file %s: %w", configFile, err)
 		}
 	} else {
@@ -151,8 +291,8 @@ func Load(version string) (*Config, error) {
 		
 		for _, path := range configPaths {
 			if _, err := os.Stat(path); err == nil {
-				viper.SetConfigFile(path)
-				if err := viper.ReadInConfig(); err != nil {
+				v.SetConfigFile(path)
+				if err := v.ReadInConfig(); err != nil {
 					return nil, fmt.Errorf("error reading config file %s: %w", path, err)
 				}
 				break
@@ -161,110 +301,149 @@ func Load(version string) (*Config, error) {
 		// Config file is optional, so don't error if not found
 	}
 
+	src := &sources{v: v, file: v.ConfigFileUsed(), set: make(map[string]string)}
+	val := &validator{src: src}
+	validateKeys(v.AllSettings(), val)
+
 	// STEP 2: Apply environment variables (medium priority) - they override config file values
-	viper.SetEnvPrefix("BTCLEANER")
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetEnvPrefix("BTCLEANER")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	
 	// Manually bind environment variables for proper override
 	envVars := map[string]string{
 		"BTCLEANER_TRANSMISSION_URL":                "transmission.url",
 		"BTCLEANER_TRANSMISSION_USERNAME":           "transmission.username",
 		"BTCLEANER_TRANSMISSION_PASSWORD":           "transmission.password",
+		"BTCLEANER_TRANSMISSION_PASSWORD_FILE":      "transmission.password_file",
+		"BTCLEANER_TRANSMISSION_PASSWORD_COMMAND":   "transmission.password_command",
+		"BTCLEANER_TRANSMISSION_STARTUP_TIMEOUT":    "transmission.startup_timeout",
 		"BTCLEANER_CLEANER_MIN_FREE_SPACE":          "cleaner.min_free_space",
 		"BTCLEANER_CLEANER_MIN_TORRENTS_PER_TRACKER": "cleaner.min_torrents_per_tracker",
+		"BTCLEANER_CLEANER_STRATEGY":                "cleaner.strategy",
+		"BTCLEANER_CLEANER_PREDICTIVE":              "cleaner.predictive",
 		"BTCLEANER_SERVER_ENABLED":                  "server.enabled",
 		"BTCLEANER_SERVER_PORT":                     "server.port",
 		"BTCLEANER_SERVER_WEBROOT":                  "server.webroot",
+		"BTCLEANER_SERVER_UI":                       "server.ui",
+		"BTCLEANER_SERVER_METRICS":                  "server.metrics",
+		"BTCLEANER_SERVER_AUTH_ENABLED":             "server.auth.enabled",
 		"BTCLEANER_DAEMON_ENABLED":                  "daemon.enabled",
 		"BTCLEANER_DAEMON_CHECK_INTERVAL":           "daemon.check_interval",
+		"BTCLEANER_DAEMON_SCHEDULE":                 "daemon.schedule",
+		"BTCLEANER_DAEMON_EMERGENCY_FREE_SPACE":     "daemon.emergency_free_space",
+		"BTCLEANER_DAEMON_WATCH_ENABLED":            "daemon.watch.enabled",
+		"BTCLEANER_DAEMON_WATCH_DOWNLOAD_DIR":       "daemon.watch.download_dir",
 		"BTCLEANER_DRY_RUN":                         "dry_run",
 		"BTCLEANER_LOG_LEVEL":                       "log_level",
+		"BTCLEANER_LOG_FORMAT":                      "log_format",
+		"BTCLEANER_LOG_FILE":                        "log_file",
+		"BTCLEANER_LOG_BUFFER_SIZE":                 "log_buffer_size",
+		"BTCLEANER_DATA_DIR":                        "data_dir",
 	}
 	
 	for envVar, configKey := range envVars {
 		if val := os.Getenv(envVar); val != "" {
-			viper.Set(configKey, val)
+			v.Set(configKey, val)
+			src.set[configKey] = "env " + envVar
 		}
 	}
 
 	// STEP 3: Apply CLI flags (highest priority) - they override everything
-	if pflag.Lookup("transmission-url").Changed {
-		viper.Set("transmission.url", pflag.Lookup("transmission-url").Value.String())
-	}
-	if pflag.Lookup("transmission-user").Changed {
-		viper.Set("transmission.username", pflag.Lookup("transmission-user").Value.String())
-	}
-	if pflag.Lookup("transmission-pass").Changed {
-		viper.Set("transmission.password", pflag.Lookup("transmission-pass").Value.String())
-	}
-	if pflag.Lookup("daemon").Changed {
-		viper.Set("daemon.enabled", pflag.Lookup("daemon").Value.String())
+	flags := []struct{ flag, key string }{
+		{"transmission-url", "transmission.url"},
+		{"transmission-user", "transmission.username"},
+		{"transmission-pass", "transmission.password"},
+		{"transmission-pass-file", "transmission.password_file"},
+		{"min-torrents", "cleaner.min_torrents_per_tracker"},
+		{"daemon", "daemon.enabled"},
+		{"check-interval", "daemon.check_interval"},
+		{"dry-run", "dry_run"},
+		{"web-ui", "server.enabled"},
+		{"web-port", "server.port"},
+		{"web-root", "server.webroot"},
+		{"log-level", "log_level"},
+		{"data-dir", "data_dir"},
 	}
-	if pflag.Lookup("check-interval").Changed {
-		viper.Set("daemon.check_interval", pflag.Lookup("check-interval").Value.String())
-	}
-	if pflag.Lookup("dry-run").Changed {
-		viper.Set("dry_run", pflag.Lookup("dry-run").Value.String())
-	}
-	if pflag.Lookup("web-ui").Changed {
-		viper.Set("server.enabled", pflag.Lookup("web-ui").Value.String())
-	}
-	if pflag.Lookup("web-port").Changed {
-		viper.Set("server.port", pflag.Lookup("web-port").Value.String())
-	}
-	if pflag.Lookup("web-root").Changed {
-		viper.Set("server.webroot", pflag.Lookup("web-root").Value.String())
-	}
-	if pflag.Lookup("log-level").Changed {
-		viper.Set("log_level", pflag.Lookup("log-level").Value.String())
+	for _, f := range flags {
+		if pflag.Lookup(f.flag).Changed {
+			v.Set(f.key, pflag.Lookup(f.flag).Value.String())
+			src.set[f.key] = "flag --" + f.flag
+		}
 	}
-	
+
 	// Handle min-free-space (convert GB to bytes)
 	if pflag.Lookup("min-free-space").Changed {
-		gb := pflag.Lookup("min-free-space").Value.String()
-		if gb != "" && gb != "0" {
-			var gbInt int64
-			fmt.Sscanf(gb, "%d", &gbInt)
-			viper.Set("cleaner.min_free_space", gbInt*1024*1024*1024)
-		}
-	}
-	
-	// Handle min-torrents
-	if pflag.Lookup("min-torrents").Changed {
-		mt := pflag.Lookup("min-torrents").Value.String()
-		if mt != "" && mt != "0" {
-			var mtInt int
-			fmt.Sscanf(mt, "%d", &mtInt)
-			viper.Set("cleaner.min_torrents_per_tracker", mtInt)
+		gb, err := pflag.CommandLine.GetInt64("min-free-space")
+		if err != nil {
+			return nil, fmt.Errorf("invalid --min-free-space: %w", err)
 		}
+		v.Set("cleaner.min_free_space", gb*1024*1024*1024)
+		src.set["cleaner.min_free_space"] = "flag --min-free-space"
 	}
 
 	// Unmarshal config
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("unable to decode config: %w", err)
 	}
+	cfg.File = v.ConfigFileUsed()
+	cfg.Overrides = src.set
 
 	// Parse min_free_space from config file (can be with units like "100GB")
 	if cfg.Cleaner.MinFreeSpaceRaw != "" {
-		parsed, err := parseSize(cfg.Cleaner.MinFreeSpaceRaw)
+		parsed, err := ParseSize(cfg.Cleaner.MinFreeSpaceRaw)
 		if err != nil {
-			return nil, fmt.Errorf("invalid min_free_space value: %w", err)
+			val.add("cleaner.min_free_space", "%v", err)
+		} else {
+			// As written by WriteSettings, so that reloads compare equal
+			cfg.Cleaner.MinFreeSpaceRaw = FormatSize(parsed)
 		}
 		cfg.Cleaner.MinFreeSpace = parsed
 	} else {
 		// Use default if not specified
 		cfg.Cleaner.MinFreeSpace = 100 * 1024 * 1024 * 1024 // 100 GB
 	}
+	if cfg.Daemon.EmergencyFreeSpaceRaw != "" {
+		parsed, err := ParseSize(cfg.Daemon.EmergencyFreeSpaceRaw)
+		if err != nil {
+			val.add("daemon.emergency_free_space", "%v", err)
+		}
+		cfg.Daemon.EmergencyFreeSpace = parsed
+	}
+	if cfg.LogMaxSizeRaw != "" {
+		parsed, err := ParseSize(cfg.LogMaxSizeRaw)
+		if err != nil {
+			val.add("log_max_size", "%v", err)
+		}
+		cfg.LogMaxSize = parsed
+	}
 
-	// Validate required fields
-	if cfg.Transmission.URL == "" {
-		return nil, fmt.Errorf("transmission URL is required")
+	// Report all invalid settings at once
+	validate(&cfg, val)
+	if err := val.err(); err != nil {
+		return nil, err
 	}
 
+	if !resolve {
+		return &cfg, nil
+	}
+	if err := cfg.resolvePassword(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
+// resolvePassword reads the Transmission password from its file or command,
+// so that it is validated at startup
+func (c *Config) resolvePassword() error {
+	password, err := c.Transmission.ResolvePassword()
+	if err != nil {
+		return fmt.Errorf("failed to resolve transmission password: %w", err)
+	}
+	c.Transmission.Password = Secret(password)
+	return nil
+}
+
 // GenerateExampleConfig generates an example configuration file
 func GenerateExampleConfig(path string) error {
 	example := `# BTCleaner Configuration File
@@ -274,6 +453,12 @@ transmission:
   url: "http://localhost:9091/transmission/rpc"
   username: ""
   password: ""
+  # Read the password from a file instead (e.g. Docker secret)
+  # password_file: "/run/secrets/transmission_password"
+  # Or from the first line of a command output
+  # password_command: "pass show transmission"
+  # How long to wait for Transmission at startup before giving up ("0" waits forever)
+  startup_timeout: "2m"
 
 # Cleaner settings
 cleaner:
@@ -282,6 +467,16 @@ cleaner:
   min_free_space: "100GB"
   # Minimum torrents to keep per tracker
   min_torrents_per_tracker: 2
+  # Per-tracker overrides of min_torrents_per_tracker
+  # (tracker domain as shown in the web UI)
+  tracker_minimums: []
+  #  - tracker: "tracker.example.org"
+  #    min_torrents: 10
+  # Removal order: "oldest" (oldest added first) or "largest" (largest first)
+  strategy: "oldest"
+  # Predictive cleanup: count the bytes the active downloads still have to
+  # write as used, so space is freed before the disk fills up
+  predictive: false
 
 # Web UI settings
 server:
@@ -289,18 +484,140 @@ server:
   port: 8888
   # Webroot for reverse proxy (default: "/", example for reverse proxy: "/btcleaner")
   webroot: "/"
+  # Serve the HTML dashboard (false: only the API and metrics)
+  ui: true
+  # Expose Prometheus metrics on <webroot>/metrics
+  metrics: true
+  # /readyz fails when the last successful check is older than this
+  # (default: 3 check intervals in daemon mode)
+  # ready_max_age: "5m"
+  # Reverse proxies allowed to pass X-Forwarded-* and trusted auth headers
+  # trusted_proxies: ["127.0.0.1", "172.16.0.0/12"]
+  # Origins allowed to call the API and open WebSockets, in addition to the
+  # server itself (needed when the proxy does not forward the Host header)
+  # allowed_origins: ["https://seedbox.example.com"]
+  # Live logs and events WebSockets
+  websocket:
+    # Messages buffered per client
+    send_queue_size: 256
+    # What to do when a client does not keep up: drop messages or disconnect it
+    slow_client_policy: "drop"
+  auth:
+    # Require authentication for the web UI and API
+    enabled: false
+    session_ttl: "24h"
+    # Local users (role: viewer or operator)
+    # Generate a bcrypt hash with: htpasswd -bnBC 10 "" 'password' | tr -d ':\n'
+    users: []
+    #  - username: "admin"
+    #    password_hash: "$2y$10$..."
+    #    role: "operator"
+    # API tokens for scripts, sent as "Authorization: Bearer <token>"
+    # Generate with: openssl rand -hex 32, then store: echo -n '<token>' | sha256sum
+    tokens: []
+    #  - name: "backup-script"
+    #    token_sha256: "..."
+    #    role: "viewer"
+    # Trust the user passed by an SSO proxy (Authelia, Authentik...)
+    trusted_header:
+      enabled: false
+      user_header: "Remote-User"
+      groups_header: "Remote-Groups"
+      # Members of these groups get the operator role, others get default_role
+      operator_groups: []
+      default_role: "viewer"
 
 # Daemon mode settings
 daemon:
   enabled: false
   # Check interval (e.g., "1m", "5m", "1h")
   check_interval: "1m"
+  # Cron expression replacing check_interval (minute hour day month weekday)
+  # schedule: "*/10 * * * *"
+  # Only delete within these windows ("[days] HH:MM-HH:MM", any time if empty)
+  # delete_windows: ["02:00-06:00", "sat,sun 10:00-18:00"]
+  # Never delete within these windows
+  # blocked_windows: ["mon-fri 18:00-23:00"]
+  # Below this free space, cleanups ignore the windows
+  # emergency_free_space: "20GB"
+  # Cron expressions of the cleanup policies that do not depend on free space
+  # (e.g. stalled or unregistered torrents), which otherwise run with every
+  # check. No such policy is available yet.
+  # policy_schedules: {}
+  # Event-driven checks: watch the download directory and the torrents added
+  # to Transmission, and check as soon as the pending downloads would bring
+  # free space below the minimum
+  watch:
+    enabled: false
+    # Directory watched for new files (default: Transmission's download-dir,
+    # when btcleaner can access it)
+    # download_dir: "/downloads"
+    # How often Transmission is asked for recently added torrents (at most 1m)
+    poll_interval: "30s"
+    # Delay between activity and the free space forecast
+    debounce: "10s"
+    # While nothing happens, periodic checks back off from check_interval up
+    # to this interval ("0" to disable)
+    max_interval: "30m"
 
 # Dry run mode (simulate only, don't delete)
 dry_run: false
 
 # Log level (debug, info, warn, error)
 log_level: "info"
+
+# Log format: "text" (human readable) or "json" (one object per line, with
+# fields such as run_id, torrent_id, hash, tracker and bytes)
+log_format: "text"
+
+# Also write the logs to this file (empty: standard error only). The file is
+# rotated beyond log_max_size ("0" to disable) and once it has been written to
+# for log_max_age ("0" to disable); log_max_backups rotated files are kept
+# ("0" keeps them all). Changes of these settings require a restart.
+log_file: ""
+log_max_size: "100MB"
+log_max_age: "0"
+log_max_backups: 5
+
+# Number of log entries kept in memory, shown in the web UI and returned by
+# /api/v1/logs
+log_buffer_size: 1000
+
+# Directory where cleanup run records and deletion history are persisted
+# across restarts (empty: keep them in memory only)
+data_dir: ""
+
+# Notifications sent on cleanup events. Each sink receives the events listed
+# in "events": run_summary (cleanup needed or failed), deletion (each removed
+# torrent), insufficient_space (cleanup could not free enough space) and
+# transmission_unreachable (Transmission down, or back up). Default: all but deletion.
+notifications: []
+#  - name: "phone"
+#    type: "ntfy"
+#    url: "https://ntfy.sh"
+#    topic: "btcleaner"
+#    token: ""          # Access token, for protected topics
+#    priority: 3        # 1-5
+#  - type: "gotify"
+#    url: "https://gotify.example.org"
+#    token: "<application token>"
+#    priority: 5        # 0-10
+#  - type: "discord"    # or "slack": incoming webhook URL
+#    url: "https://discord.com/api/webhooks/..."
+#    events: ["run_summary", "insufficient_space"]
+#  - type: "webhook"    # Posts the notification as JSON, or the rendered template
+#    url: "https://hooks.example.org/btcleaner"
+#    headers:
+#      X-Api-Key: "..."
+#    # Go template with .Event, .Title, .Message, .Time and .Data (event payload)
+#    template: '{"text": {{json .Title}}, "body": {{json .Message}}}'
+#  - type: "email"      # SMTP, with STARTTLS when offered
+#    smtp_host: "smtp.example.org"
+#    smtp_port: 587
+#    username: "btcleaner@example.org"
+#    password: ""
+#    from: "btcleaner@example.org"
+#    to: ["me@example.org"]
 `
 	return os.WriteFile(path, []byte(example), 0644)
 }
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Diff lists the settings that differ between two configurations, as
+// "key: old -> new" with the keys of the config file. Secrets are redacted
+// and lists holding secrets (users, notifications...) are only reported as changed.
+func Diff(old, new *Config) []string {
+	var changes []string
+	diffStruct("", reflect.ValueOf(*old), reflect.ValueOf(*new), &changes)
+	return changes
+}
+
+// diffStruct appends the differences between two structs of the same type
+func diffStruct(prefix string, a, b reflect.Value, changes *[]string) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		av, bv := a.Field(i), b.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(key+".", av, bv, changes)
+			continue
+		}
+		if reflect.DeepEqual(av.Interface(), bv.Interface()) {
+			continue
+		}
+		// A missing list and an empty one are the same setting
+		if (field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Map) && av.Len() == 0 && bv.Len() == 0 {
+			continue
+		}
+		// Secrets would print as "******** -> ********"
+		if field.Type == reflect.TypeOf(Secret("")) || (field.Type.Kind() == reflect.Slice && hasSecret(field.Type.Elem())) {
+			*changes = append(*changes, key+": changed (redacted)")
+			continue
+		}
+		*changes = append(*changes, fmt.Sprintf("%s: %v -> %v", key, av.Interface(), bv.Interface()))
+	}
+}
+
+// hasSecret reports whether t is a struct with a Secret field
+func hasSecret(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type == reflect.TypeOf(Secret("")) {
+			return true
+		}
+	}
+	return false
+}
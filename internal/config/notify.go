@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+
+	"github.com/Celedhrim/btcleaner/internal/tmplfuncs"
+)
+
+// Notification sink types
+const (
+	NotifyWebhook = "webhook" // Generic webhook with a templated JSON body
+	NotifyNtfy    = "ntfy"
+	NotifyGotify  = "gotify"
+	NotifyDiscord = "discord" // Discord incoming webhook
+	NotifySlack   = "slack"   // Slack incoming webhook
+	NotifyEmail   = "email"   // SMTP
+)
+
+// Notification events a sink can subscribe to
+const (
+	NotifyRunSummary         = "run_summary"              // A cleanup run needed to free space or failed
+	NotifyDeletion           = "deletion"                 // Each deleted torrent
+	NotifyInsufficientSpace  = "insufficient_space"       // A cleanup could not free enough space
+	NotifyTransmissionStatus = "transmission_unreachable" // Transmission became unreachable, or reachable again
+)
+
+// NotifyEvents lists all notification events
+var NotifyEvents = []string{NotifyRunSummary, NotifyDeletion, NotifyInsufficientSpace, NotifyTransmissionStatus}
+
+// DefaultNotifyEvents are the events of a sink without events filter
+var DefaultNotifyEvents = []string{NotifyRunSummary, NotifyInsufficientSpace, NotifyTransmissionStatus}
+
+// NotificationConfig is a notification sink
+type NotificationConfig struct {
+	Name   string   `mapstructure:"name"`
+	Type   string   `mapstructure:"type"`
+	Events []string `mapstructure:"events"` // Events sent to this sink, DefaultNotifyEvents if empty
+
+	// HTTP sinks (webhook, ntfy, gotify, discord, slack)
+	URL      string            `mapstructure:"url"`
+	Headers  map[string]string `mapstructure:"headers"`  // webhook: extra request headers
+	Template string            `mapstructure:"template"` // webhook: Go template of the JSON body
+	Topic    string            `mapstructure:"topic"`    // ntfy
+	Token    Secret            `mapstructure:"token"`    // ntfy access token, gotify application token
+	Priority int               `mapstructure:"priority"` // ntfy (1-5), gotify (0-10)
+
+	// SMTP (email)
+	SMTPHost string   `mapstructure:"smtp_host"`
+	SMTPPort int      `mapstructure:"smtp_port"`
+	Username string   `mapstructure:"username"`
+	Password Secret   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// DisplayName returns the sink name, or its type and position if unnamed
+func (n NotificationConfig) DisplayName(index int) string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return fmt.Sprintf("%s #%d", n.Type, index+1)
+}
+
+// validateNotifications checks the notification sinks
+func validateNotifications(sinks []NotificationConfig, v *validator) {
+	for i, n := range sinks {
+		key := fmt.Sprintf("notifications[%d]", i)
+
+		for _, e := range n.Events {
+			if !validNotifyEvent(e) {
+				v.add(key+".events", "unknown event %q", e)
+			}
+		}
+
+		switch n.Type {
+		case NotifyWebhook, NotifyNtfy, NotifyGotify, NotifyDiscord, NotifySlack:
+			u, err := url.Parse(n.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.add(key+".url", "must be an http(s) URL")
+			}
+		case NotifyEmail:
+			if n.SMTPHost == "" || n.From == "" || len(n.To) == 0 {
+				v.add(key, "smtp_host, from and to are required for email")
+			}
+		case "":
+			v.add(key+".type", "is required")
+		default:
+			v.add(key+".type", "unknown type %q", n.Type)
+		}
+
+		switch n.Type {
+		case NotifyWebhook:
+			if n.Template != "" {
+				if _, err := template.New(n.DisplayName(i)).Funcs(tmplfuncs.Funcs()).Parse(n.Template); err != nil {
+					v.add(key+".template", "invalid template: %v", err)
+				}
+			}
+		case NotifyNtfy:
+			if n.Topic == "" {
+				v.add(key+".topic", "is required for ntfy")
+			}
+		case NotifyGotify:
+			if n.Token == "" {
+				v.add(key+".token", "is required for gotify")
+			}
+		}
+	}
+}
+
+// validNotifyEvent reports whether e is a known notification event
+func validNotifyEvent(e string) bool {
+	for _, known := range NotifyEvents {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces secrets when they are printed or serialized
+const redactedValue = "********"
+
+// passwordCommandTimeout bounds the execution time of password_command
+const passwordCommandTimeout = 10 * time.Second
+
+// Secret is a string holding sensitive data (passwords, tokens...).
+// It is redacted when formatted with fmt or marshalled to JSON/YAML,
+// so a Config can be safely logged or exposed through the API.
+type Secret string
+
+// Value returns the plain text secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// GoString implements fmt.GoStringer (used by %#v)
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// ResolvePassword returns the Transmission password from the configured source.
+// password_command takes precedence over password_file, which takes precedence
+// over the inline password.
+func (t *TransmissionConfig) ResolvePassword() (string, error) {
+	switch {
+	case t.PasswordCommand != "":
+		return runPasswordCommand(t.PasswordCommand.Value())
+	case t.PasswordFile != "":
+		return readSecretFile(t.PasswordFile)
+	default:
+		return t.Password.Value(), nil
+	}
+}
+
+// readSecretFile reads a secret from a file (e.g. a Docker secret in /run/secrets).
+// A single trailing newline is stripped.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// runPasswordCommand runs a local command and returns the first line of its output
+func runPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return "", fmt.Errorf("password command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+
+	line, _, _ := strings.Cut(string(out), "\n")
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return "", fmt.Errorf("password command returned an empty password")
+	}
+	return line, nil
+}
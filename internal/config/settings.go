@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ErrInvalidSettings is returned when runtime settings are invalid
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// Settings are the settings that can be changed while btcleaner runs
+type Settings struct {
+	MinFreeSpace          int64 // Bytes
+	MinTorrentsPerTracker int
+	TrackerMinimums       []TrackerMinimum
+	Strategy              string
+	CheckInterval         time.Duration
+	DryRun                bool
+	LogLevel              string
+}
+
+// Settings returns the runtime settings of the configuration
+func (c *Config) Settings() Settings {
+	return Settings{
+		MinFreeSpace:          c.Cleaner.MinFreeSpace,
+		MinTorrentsPerTracker: c.Cleaner.MinTorrentsPerTracker,
+		TrackerMinimums:       append([]TrackerMinimum{}, c.Cleaner.TrackerMinimums...),
+		Strategy:              c.Cleaner.Strategy,
+		CheckInterval:         c.Daemon.CheckInterval,
+		DryRun:                c.DryRun,
+		LogLevel:              c.LogLevel,
+	}
+}
+
+// ApplySettings replaces the runtime settings of the configuration
+func (c *Config) ApplySettings(s Settings) {
+	// Unchanged values keep their representation, so that Diff ignores them
+	if s.MinFreeSpace != c.Cleaner.MinFreeSpace {
+		c.Cleaner.MinFreeSpaceRaw = FormatSize(s.MinFreeSpace)
+	}
+	c.Cleaner.MinFreeSpace = s.MinFreeSpace
+	c.Cleaner.MinTorrentsPerTracker = s.MinTorrentsPerTracker
+	if len(s.TrackerMinimums) > 0 || len(c.Cleaner.TrackerMinimums) > 0 {
+		c.Cleaner.TrackerMinimums = append([]TrackerMinimum{}, s.TrackerMinimums...)
+	}
+	c.Cleaner.Strategy = s.Strategy
+	c.Daemon.CheckInterval = s.CheckInterval
+	c.DryRun = s.DryRun
+	c.LogLevel = s.LogLevel
+}
+
+// settingKeys returns the config keys of the runtime settings that differ
+// between a and b
+func settingKeys(a, b Settings) []string {
+	var keys []string
+	if a.MinFreeSpace != b.MinFreeSpace {
+		keys = append(keys, "cleaner.min_free_space")
+	}
+	if a.MinTorrentsPerTracker != b.MinTorrentsPerTracker {
+		keys = append(keys, "cleaner.min_torrents_per_tracker")
+	}
+	if !reflect.DeepEqual(a.TrackerMinimums, b.TrackerMinimums) && (len(a.TrackerMinimums) > 0 || len(b.TrackerMinimums) > 0) {
+		keys = append(keys, "cleaner.tracker_minimums")
+	}
+	if a.Strategy != b.Strategy {
+		keys = append(keys, "cleaner.strategy")
+	}
+	if a.CheckInterval != b.CheckInterval {
+		keys = append(keys, "daemon.check_interval")
+	}
+	if a.DryRun != b.DryRun {
+		keys = append(keys, "dry_run")
+	}
+	if a.LogLevel != b.LogLevel {
+		keys = append(keys, "log_level")
+	}
+	return keys
+}
+
+// OverriddenSettings returns the runtime settings changed by s that are set
+// by an environment variable or a flag, as "key (source)". Saved to the config
+// file, they would be replaced by the override at the next reload.
+func (c *Config) OverriddenSettings(s Settings) []string {
+	var overridden []string
+	for _, key := range settingKeys(c.Settings(), s) {
+		if source, ok := c.Overrides[key]; ok {
+			overridden = append(overridden, fmt.Sprintf("%s (%s)", key, source))
+		}
+	}
+	return overridden
+}
+
+// Validate checks the settings with the rules of Load
+func (s Settings) Validate() error {
+	cleaner := CleanerConfig{
+		MinFreeSpace:          s.MinFreeSpace,
+		MinTorrentsPerTracker: s.MinTorrentsPerTracker,
+		TrackerMinimums:       s.TrackerMinimums,
+		Strategy:              s.Strategy,
+	}
+	v := &validator{}
+	validateCleaner(&cleaner, v)
+	validateCheckInterval(s.CheckInterval, v)
+	validateLogLevel(s.LogLevel, v)
+	if len(v.errs) > 0 {
+		msgs := make([]string, len(v.errs))
+		for i, fe := range v.errs {
+			msgs[i] = fe.Error()
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidSettings, strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// FormatSize formats a size in bytes with the largest exact unit, as
+// accepted by ParseSize (e.g. "100GB")
+func FormatSize(bytes int64) string {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TB", 1024 * 1024 * 1024 * 1024},
+		{"GB", 1024 * 1024 * 1024},
+		{"MB", 1024 * 1024},
+		{"KB", 1024},
+	}
+	for _, u := range units {
+		if bytes != 0 && bytes%u.size == 0 {
+			return strconv.FormatInt(bytes/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(bytes, 10)
+}
+
+// WriteSettings writes the runtime settings to a YAML config file. The other
+// settings and the comments of the file are kept.
+func WriteSettings(path string, s Settings) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		// Empty file
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file is not a YAML mapping")
+	}
+
+	minimums := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, tm := range s.TrackerMinimums {
+		minimums.Content = append(minimums.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			scalarNode("tracker", "!!str"), scalarNode(tm.Tracker, "!!str"),
+			scalarNode("min_torrents", "!!str"), scalarNode(strconv.Itoa(tm.MinTorrents), "!!int"),
+		}})
+	}
+	if len(minimums.Content) == 0 {
+		minimums.Style = yaml.FlowStyle // []
+	}
+
+	cleaner := mappingValue(root, "cleaner")
+	setValue(cleaner, "min_free_space", scalarNode(FormatSize(s.MinFreeSpace), "!!str"))
+	setValue(cleaner, "min_torrents_per_tracker", scalarNode(strconv.Itoa(s.MinTorrentsPerTracker), "!!int"))
+	setValue(cleaner, "tracker_minimums", minimums)
+	setValue(cleaner, "strategy", scalarNode(s.Strategy, "!!str"))
+	setValue(mappingValue(root, "daemon"), "check_interval", scalarNode(s.CheckInterval.String(), "!!str"))
+	setValue(root, "dry_run", scalarNode(strconv.FormatBool(s.DryRun), "!!bool"))
+	setValue(root, "log_level", scalarNode(s.LogLevel, "!!str"))
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	enc.Close()
+
+	// Replace the file a symlink points to, not the symlink
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := replaceFile(target, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// replaceFile atomically replaces the file at path through a temporary file
+// in the same directory, so that a failed write never leaves it truncated.
+// Bind-mounted files, as in containers, cannot be replaced and are written in
+// place instead.
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EXDEV) {
+		return os.WriteFile(path, data, perm)
+	}
+	return err
+}
+
+// scalarNode returns a YAML scalar node
+func scalarNode(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// mappingValue returns the mapping under key, created if missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key && mapping.Content[i+1].Kind == yaml.MappingNode {
+			return mapping.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setValue(mapping, key, value)
+	return value
+}
+
+// setValue sets the value of key in a mapping, keeping the comments of the
+// previous value
+func setValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, scalarNode(key, "!!str"), value)
+}
+
+// Redacted returns the configuration as a map with the keys of the config
+// file, for display. Secrets are redacted, as well as the URLs and headers of
+// notification sinks, which often embed tokens (Discord, Slack webhooks...).
+func (c *Config) Redacted() map[string]interface{} {
+	cfg := *c
+	cfg.Notifications = make([]NotificationConfig, len(c.Notifications))
+	for i, n := range c.Notifications {
+		cfg.Notifications[i] = n.redacted()
+	}
+	return redactStruct(reflect.ValueOf(cfg))
+}
+
+// redacted returns the sink with its URL reduced to scheme and host, and its
+// header values redacted
+func (n NotificationConfig) redacted() NotificationConfig {
+	if u, err := url.Parse(n.URL); err == nil && u.Host != "" {
+		n.URL = u.Scheme + "://" + u.Host + "/" + redactedValue
+	} else if n.URL != "" {
+		n.URL = redactedValue
+	}
+	if len(n.Headers) > 0 {
+		headers := make(map[string]string, len(n.Headers))
+		for k := range n.Headers {
+			headers[k] = redactedValue
+		}
+		n.Headers = headers
+	}
+	return n
+}
+
+// redactStruct converts a struct to a map indexed by mapstructure tags
+func redactStruct(v reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		m[tag] = redactValue(v.Field(i))
+	}
+	return m
+}
+
+// redactValue converts a config value for display
+func redactValue(v reflect.Value) interface{} {
+	switch val := v.Interface().(type) {
+	case Secret:
+		return val.String()
+	case time.Duration:
+		return val.String()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactValue(v.Index(i))
+		}
+		return items
+	}
+	return v.Interface()
+}
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/schedule"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// FieldError is an invalid setting
+type FieldError struct {
+	Key     string `json:"key"`    // Config file key (e.g. "cleaner.min_torrents_per_tracker")
+	Source  string `json:"source"` // Where the value comes from (e.g. "file /etc/btcleaner.yaml", "env BTCLEANER_LOG_LEVEL", "flag --log-level", "default")
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (e FieldError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%s: %s", e.Key, e.Message)
+	}
+	return fmt.Sprintf("%s: %s (from %s)", e.Key, e.Message, e.Source)
+}
+
+// ValidationError lists all the invalid settings of a configuration
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid configuration: " + e.Errors[0].Error()
+	}
+	lines := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		lines[i] = "  - " + fe.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d errors):\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+// sources tracks where the settings of a configuration come from
+type sources struct {
+	v    *viper.Viper
+	file string            // Config file read, empty if none
+	set  map[string]string // Keys set by environment variables and flags
+}
+
+// of returns the source of a key. Keys of list items ("users[1].role") take
+// the source of the list.
+func (s *sources) of(key string) string {
+	if i := strings.Index(key, "["); i >= 0 {
+		key = key[:i]
+	}
+	if src, ok := s.set[key]; ok {
+		return src
+	}
+	if s.file != "" && s.v.InConfig(key) {
+		return "file " + s.file
+	}
+	return "default"
+}
+
+// validator collects the invalid settings of a configuration
+type validator struct {
+	src  *sources // nil when the sources are unknown
+	errs []FieldError
+}
+
+// add records an invalid setting
+func (v *validator) add(key, format string, args ...interface{}) {
+	fe := FieldError{Key: key, Message: fmt.Sprintf(format, args...)}
+	if v.src != nil {
+		fe.Source = v.src.of(key)
+	}
+	v.errs = append(v.errs, fe)
+}
+
+// err returns the invalid settings as a *ValidationError, nil if none
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+// validate checks a decoded configuration
+func validate(cfg *Config, v *validator) {
+	t := &cfg.Transmission
+	if t.URL == "" {
+		v.add("transmission.url", "is required")
+	} else if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("transmission.url", "must be an http(s) URL")
+	}
+	if t.PasswordFile != "" && t.PasswordCommand != "" {
+		v.add("transmission.password_command", "password_file and password_command are mutually exclusive")
+	}
+	if t.StartupTimeout < 0 {
+		v.add("transmission.startup_timeout", "must not be negative")
+	}
+
+	validateCleaner(&cfg.Cleaner, v)
+	validateCheckInterval(cfg.Daemon.CheckInterval, v)
+	validateSchedule(&cfg.Daemon, v)
+	validateLogLevel(cfg.LogLevel, v)
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		v.add("log_format", "invalid format %q (expected text or json)", cfg.LogFormat)
+	}
+	if cfg.LogMaxSize < 0 {
+		v.add("log_max_size", "must not be negative")
+	}
+	if cfg.LogMaxAge < 0 {
+		v.add("log_max_age", "must not be negative")
+	}
+	if cfg.LogMaxBackups < 0 {
+		v.add("log_max_backups", "must not be negative")
+	}
+	if cfg.LogBufferSize < 1 {
+		v.add("log_buffer_size", "must be positive")
+	}
+
+	srv := &cfg.Server
+	if srv.Port < 1 || srv.Port > 65535 {
+		v.add("server.port", "must be between 1 and 65535")
+	}
+	if srv.ReadyMaxAge < 0 {
+		v.add("server.ready_max_age", "must not be negative")
+	}
+	if srv.WebSocket.SendQueueSize <= 0 {
+		v.add("server.websocket.send_queue_size", "must be positive")
+	}
+	if p := srv.WebSocket.SlowClientPolicy; p != SlowClientDrop && p != SlowClientDisconnect {
+		v.add("server.websocket.slow_client_policy", "invalid policy %q (expected %s or %s)", p, SlowClientDrop, SlowClientDisconnect)
+	}
+	validateAuth(srv, v)
+	validateNotifications(cfg.Notifications, v)
+}
+
+// validateCleaner checks the cleaner settings
+func validateCleaner(c *CleanerConfig, v *validator) {
+	if c.MinFreeSpace <= 0 {
+		v.add("cleaner.min_free_space", "must be positive")
+	}
+	if c.MinTorrentsPerTracker < 0 {
+		v.add("cleaner.min_torrents_per_tracker", "must not be negative")
+	}
+	if c.Strategy != "oldest" && c.Strategy != "largest" {
+		v.add("cleaner.strategy", "invalid strategy %q (expected oldest or largest)", c.Strategy)
+	}
+	seen := make(map[string]bool)
+	for i, tm := range c.TrackerMinimums {
+		key := fmt.Sprintf("cleaner.tracker_minimums[%d]", i)
+		tracker := strings.ToLower(tm.Tracker)
+		switch {
+		case tm.Tracker == "":
+			v.add(key+".tracker", "is required")
+		case seen[tracker]:
+			v.add(key+".tracker", "%s is defined twice", tm.Tracker)
+		}
+		seen[tracker] = true
+		if tm.MinTorrents < 0 {
+			v.add(key+".min_torrents", "must not be negative")
+		}
+	}
+}
+
+// validateCheckInterval checks the daemon check interval
+func validateCheckInterval(d time.Duration, v *validator) {
+	if d <= 0 {
+		v.add("daemon.check_interval", "must be positive")
+	}
+}
+
+// validatePolicySchedules checks the schedules of the policies that do not
+// depend on free space, which must name an available policy
+func validatePolicySchedules(policies map[string]string, v *validator) {
+	available := cleaner.SweepNames()
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := "daemon.policy_schedules." + name
+		if !slices.Contains(available, name) {
+			if len(available) == 0 {
+				v.add(key, "unknown policy %q (no policy runs on its own schedule yet)", name)
+			} else {
+				v.add(key, "unknown policy %q (expected one of %s)", name, strings.Join(available, ", "))
+			}
+			continue
+		}
+		if cron, err := schedule.ParseCron(policies[name]); err != nil {
+			v.add(key, "%v", err)
+		} else if cron.Next(time.Now()).IsZero() {
+			v.add(key, "%q never matches", policies[name])
+		}
+	}
+}
+
+// validateSchedule checks the cron schedule, deletion windows and event-driven
+// checks of the daemon
+func validateSchedule(d *DaemonConfig, v *validator) {
+	if d.Schedule != "" {
+		if cron, err := schedule.ParseCron(d.Schedule); err != nil {
+			v.add("daemon.schedule", "%v", err)
+		} else if cron.Next(time.Now()).IsZero() {
+			v.add("daemon.schedule", "%q never matches", d.Schedule)
+		}
+	}
+	for i, w := range d.DeleteWindows {
+		if _, err := schedule.ParseWindow(w); err != nil {
+			v.add(fmt.Sprintf("daemon.delete_windows[%d]", i), "%v", err)
+		}
+	}
+	for i, w := range d.BlockedWindows {
+		if _, err := schedule.ParseWindow(w); err != nil {
+			v.add(fmt.Sprintf("daemon.blocked_windows[%d]", i), "%v", err)
+		}
+	}
+	validatePolicySchedules(d.PolicySchedules, v)
+
+	w := &d.Watch
+	if !w.Enabled {
+		return
+	}
+	// Transmission reports the torrents active during the last minute
+	if w.PollInterval <= 0 || w.PollInterval > time.Minute {
+		v.add("daemon.watch.poll_interval", "must be positive and at most 1m")
+	}
+	if w.Debounce <= 0 {
+		v.add("daemon.watch.debounce", "must be positive")
+	}
+	if w.MaxInterval < 0 {
+		v.add("daemon.watch.max_interval", "must not be negative")
+	}
+}
+
+// validateLogLevel checks the log level
+func validateLogLevel(level string, v *validator) {
+	if _, err := logrus.ParseLevel(level); err != nil {
+		v.add("log_level", "invalid level %q (expected debug, info, warn or error)", level)
+	}
+}
+
+// validateKeys reports the settings of the config file that btcleaner does
+// not know, usually typos that would otherwise be silently ignored
+func validateKeys(settings map[string]interface{}, v *validator) {
+	checkKeys("", settings, reflect.TypeOf(Config{}), v)
+}
+
+// checkKeys compares the keys of a decoded YAML mapping with the fields of a struct
+func checkKeys(prefix string, m map[string]interface{}, t reflect.Type, v *validator) {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("mapstructure"); tag != "" && tag != "-" {
+			fields[tag] = t.Field(i).Type
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ft, ok := fields[strings.ToLower(k)]
+		if !ok {
+			v.add(prefix+k, "unknown setting")
+			continue
+		}
+		switch {
+		case ft.Kind() == reflect.Struct:
+			if sub, ok := m[k].(map[string]interface{}); ok {
+				checkKeys(prefix+k+".", sub, ft, v)
+			}
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			items, _ := m[k].([]interface{})
+			for i, item := range items {
+				if sub, ok := item.(map[string]interface{}); ok {
+					checkKeys(fmt.Sprintf("%s%s[%d].", prefix, k, i), sub, ft.Elem(), v)
+				}
+			}
+		}
+	}
+}
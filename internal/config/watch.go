@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce groups bursts of filesystem events into a single notification
+const watchDebounce = 500 * time.Millisecond
+
+// WatchFile calls onChange whenever the file at path is written, replaced or
+// recreated. The parent directory is watched rather than the file itself so
+// atomic replacements (editors, Kubernetes/Docker secret symlink swaps) are
+// detected as well. The returned function stops the watcher.
+func WatchFile(path string, onChange func()) (func(), error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	dir := filepath.Dir(absPath)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var timer *time.Timer
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Secret mounts swap a "..data" symlink instead of touching the file
+				if event.Name != absPath && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDebounce, onChange)
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			watcher.Close()
+		})
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,255 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event topics
+const (
+	TopicTorrentDeleted    = "torrent.deleted"
+	TopicCleanupStarted    = "cleanup.started"
+	TopicCleanupFinished   = "cleanup.finished"
+	TopicCleanupBlocked    = "cleanup.blocked"
+	TopicSpaceChanged      = "space.changed"
+	TopicCandidatesChanged = "candidates.changed"
+	TopicConfigReloaded    = "config.reloaded"
+)
+
+// Topics lists all event topics
+var Topics = []string{
+	TopicTorrentDeleted,
+	TopicCleanupStarted,
+	TopicCleanupFinished,
+	TopicCleanupBlocked,
+	TopicSpaceChanged,
+	TopicCandidatesChanged,
+	TopicConfigReloaded,
+}
+
+// ReplaySize is the number of recent events kept for clients that reconnect
+const ReplaySize = 100
+
+// Event is a typed notification published on the bus
+type Event struct {
+	ID    uint64      `json:"id"`
+	Topic string      `json:"topic"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// TorrentDeleted is the data of torrent.deleted events
+type TorrentDeleted struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Hash      string `json:"hash,omitempty"`
+	Tracker   string `json:"tracker"`
+	SizeBytes int64  `json:"size_bytes"`
+	Reason    string `json:"reason"` // "auto" or "manual"
+	RunID     string `json:"run_id,omitempty"`
+}
+
+// CleanupStarted is the data of cleanup.started events
+type CleanupStarted struct {
+	RunID        string `json:"run_id"`
+	Trigger      string `json:"trigger"`
+	DryRun       bool   `json:"dry_run"`
+	MinFreeSpace int64  `json:"min_free_space"`
+}
+
+// CleanupFinished is the data of cleanup.finished events
+type CleanupFinished struct {
+	RunID            string `json:"run_id"`
+	Trigger          string `json:"trigger"`
+	Status           string `json:"status"`
+	DryRun           bool   `json:"dry_run"`
+	Deferred         bool   `json:"deferred,omitempty"` // Outside the deletion windows, nothing removed
+	NeedCleanup      bool   `json:"need_cleanup"`
+	InitialFreeSpace int64  `json:"initial_free_space"`
+	FinalFreeSpace   int64  `json:"final_free_space"`
+	RemovedCount     int    `json:"removed_count"`
+	RemovedSize      int64  `json:"removed_size"`
+	DurationMs       int64  `json:"duration_ms"`
+	SpaceShortfall   int64  `json:"space_shortfall,omitempty"` // Bytes still missing to reach the minimum free space
+	Error            string `json:"error,omitempty"`
+}
+
+// CleanupBlocked is the data of cleanup.blocked events, published after
+// cleanup.finished when a run could not reach the minimum free space
+type CleanupBlocked struct {
+	RunID          string   `json:"run_id"`
+	DryRun         bool     `json:"dry_run"`
+	ShortfallBytes int64    `json:"shortfall_bytes"`
+	Trackers       []string `json:"trackers"`   // Trackers whose minimums kept torrents
+	Resolvable     bool     `json:"resolvable"` // Whether lowering tracker minimums frees enough space
+	Message        string   `json:"message"`    // Blocking constraints and suggested minimums
+}
+
+// SpaceChanged is the data of space.changed events
+type SpaceChanged struct {
+	FreeSpace         int64 `json:"free_space"`
+	PreviousFreeSpace int64 `json:"previous_free_space"`
+	MinFreeSpace      int64 `json:"min_free_space"`
+	NeedsCleanup      bool  `json:"needs_cleanup"`
+}
+
+// CandidatesChanged is the data of candidates.changed events
+type CandidatesChanged struct {
+	IDs            []int `json:"ids"`
+	Count          int   `json:"count"`
+	TotalSizeBytes int64 `json:"total_size_bytes"`
+}
+
+// ConfigReloaded is the data of config.reloaded events
+type ConfigReloaded struct {
+	Source  string   `json:"source"`  // What triggered the reload (file, signal, api)
+	Changes []string `json:"changes"` // Changed settings, secrets redacted
+}
+
+// Bus dispatches events to subscribers. Publishing never blocks: events are
+// dropped for subscribers whose buffer is full.
+type Bus struct {
+	subs   map[*Subscription]struct{}
+	replay []Event
+	nextID uint64
+	mu     sync.RWMutex
+}
+
+// NewBus creates an event bus
+func NewBus() *Bus {
+	return &Bus{
+		subs:   make(map[*Subscription]struct{}),
+		replay: make([]Event, 0, ReplaySize),
+	}
+}
+
+// Publish sends an event to all subscribers of its topic. A nil bus discards the event.
+func (b *Bus) Publish(topic string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	// Delivered within the same critical section as the ID assignment, so
+	// subscribers receive the events in ID order. Sends do not block.
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	event := Event{ID: b.nextID, Topic: topic, Time: time.Now(), Data: data}
+	b.replay = append(b.replay, event)
+	if len(b.replay) > ReplaySize {
+		b.replay = b.replay[len(b.replay)-ReplaySize:]
+	}
+
+	for sub := range b.subs {
+		if !sub.matches(topic) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// HasSubscribers reports whether anyone listens to the topic
+func (b *Bus) HasSubscribers(topic string) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.matches(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe returns a subscription to the given topics, all topics if empty.
+// A topic ending with ".*" matches every topic with that prefix (e.g. "cleanup.*").
+func (b *Bus) Subscribe(topics []string, buffer int) *Subscription {
+	sub := &Subscription{
+		ch:     make(chan Event, buffer),
+		topics: make(map[string]bool, len(topics)),
+		bus:    b,
+	}
+	for _, t := range topics {
+		if t = strings.TrimSpace(t); t != "" {
+			sub.topics[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Since returns the recent events after the given event ID that match the topics
+func (b *Bus) Since(id uint64, topics []string) []Event {
+	filter := &Subscription{topics: make(map[string]bool, len(topics))}
+	for _, t := range topics {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.topics[t] = true
+		}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var events []Event
+	for _, e := range b.replay {
+		if e.ID > id && filter.matches(e.Topic) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Subscription receives the events of a set of topics
+type Subscription struct {
+	ch      chan Event
+	topics  map[string]bool
+	bus     *Bus
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// C returns the channel delivering the events. It is closed by Close.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the event channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// matches reports whether the subscription wants events of the topic
+func (s *Subscription) matches(topic string) bool {
+	if len(s.topics) == 0 || s.topics[topic] {
+		return true
+	}
+	for t := range s.topics {
+		if strings.HasSuffix(t, ".*") && strings.HasPrefix(topic, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
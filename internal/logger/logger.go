@@ -2,14 +2,28 @@ package logger
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Celedhrim/btcleaner/pkg/models"
 	"github.com/sirupsen/logrus"
 )
 
+// MaxLogLines is the default number of log entries kept in memory
 const MaxLogLines = 1000
 
+// Structured fields attached to log entries
+const (
+	FieldRunID     = "run_id"     // Cleanup run
+	FieldTorrentID = "torrent_id" // Transmission torrent ID
+	FieldHash      = "hash"       // Torrent info hash
+	FieldTracker   = "tracker"    // Normalized tracker domain
+	FieldBytes     = "bytes"      // Size or amount of space involved
+)
+
 // LogCallback is called when a new log entry is created
 type LogCallback func(LogEntry)
 
@@ -20,13 +34,56 @@ type Logger struct {
 	bufferMu  sync.RWMutex
 	maxLines  int
 	callback  LogCallback
+	file      *rotatingFile
 }
 
 // LogEntry represents a single log entry
 type LogEntry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Level     string    `json:"level"`
-	Message   string    `json:"message"`
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"` // Structured fields, e.g. run_id or torrent_id
+}
+
+// LogFilter selects log entries, zero values selecting all of them
+type LogFilter struct {
+	Levels []logrus.Level // Levels of the entries
+	Since  time.Time      // Entries logged at or after this time
+	Query  string         // Case-insensitive text in the message or in a field as "key=value"
+	Limit  int            // Number of most recent entries
+}
+
+// Match reports whether the filter selects the entry, regardless of the limit
+func (f LogFilter) Match(e LogEntry) bool {
+	if len(f.Levels) > 0 {
+		found := false
+		for _, level := range f.Levels {
+			if level.String() == e.Level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if f.Query == "" {
+		return true
+	}
+
+	query := strings.ToLower(f.Query)
+	if strings.Contains(strings.ToLower(e.Message), query) {
+		return true
+	}
+	for k, v := range e.Fields {
+		if strings.Contains(strings.ToLower(fmt.Sprintf("%s=%v", k, v)), query) {
+			return true
+		}
+	}
+	return false
 }
 
 // New creates a new logger
@@ -54,6 +111,65 @@ func New(level string) (*Logger, error) {
 	return logger, nil
 }
 
+// NewFormatter returns the formatter of an output format, "text" or "json"
+func NewFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "text":
+		return &logrus.TextFormatter{
+			FullTimestamp: true,
+		}, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+}
+
+// SetFormat sets the output format, "text" or "json"
+func (l *Logger) SetFormat(format string) error {
+	formatter, err := NewFormatter(format)
+	if err != nil {
+		return err
+	}
+	l.SetFormatter(formatter)
+	return nil
+}
+
+// SetFile writes the logs to the file at path as well as to stderr. The file
+// is rotated beyond maxSize bytes and after maxAge, keeping maxBackups
+// rotated files; 0 disables each limit.
+func (l *Logger) SetFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) error {
+	file, err := openRotatingFile(path, maxSize, maxAge, maxBackups)
+	if err != nil {
+		return err
+	}
+	l.SetOutput(io.MultiWriter(os.Stderr, file))
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file = file
+	return nil
+}
+
+// Close closes the log file, if any
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	l.SetOutput(os.Stderr)
+	return l.file.Close()
+}
+
+// TorrentFields returns the fields identifying a torrent in log entries
+func TorrentFields(t models.Torrent) logrus.Fields {
+	return logrus.Fields{
+		FieldTorrentID: t.ID,
+		FieldHash:      t.Hash,
+		FieldTracker:   t.NormalizedTracker,
+		FieldBytes:     t.TotalSize,
+	}
+}
+
 // Levels implements logrus.Hook interface
 func (l *Logger) Levels() []logrus.Level {
 	return logrus.AllLevels
@@ -66,6 +182,16 @@ func (l *Logger) Fire(entry *logrus.Entry) error {
 		Level:     entry.Level.String(),
 		Message:   entry.Message,
 	}
+	if len(entry.Data) > 0 {
+		logEntry.Fields = make(map[string]interface{}, len(entry.Data))
+		for k, v := range entry.Data {
+			// Errors would encode as empty JSON objects
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			logEntry.Fields[k] = v
+		}
+	}
 
 	l.bufferMu.Lock()
 	l.buffer = append(l.buffer, logEntry)
@@ -98,6 +224,45 @@ func (l *Logger) GetLogs() []LogEntry {
 	return logs
 }
 
+// Query returns the logs in memory selected by the filter, oldest first
+func (l *Logger) Query(f LogFilter) []LogEntry {
+	l.bufferMu.RLock()
+	defer l.bufferMu.RUnlock()
+
+	logs := make([]LogEntry, 0, len(l.buffer))
+	for _, e := range l.buffer {
+		if f.Match(e) {
+			logs = append(logs, e)
+		}
+	}
+	if f.Limit > 0 && len(logs) > f.Limit {
+		logs = logs[len(logs)-f.Limit:]
+	}
+	return logs
+}
+
+// SetBufferSize sets the number of log entries kept in memory, dropping the
+// oldest ones beyond it
+func (l *Logger) SetBufferSize(size int) {
+	if size < 1 {
+		size = MaxLogLines
+	}
+
+	l.bufferMu.Lock()
+	defer l.bufferMu.Unlock()
+	l.maxLines = size
+	if len(l.buffer) > size {
+		l.buffer = append([]LogEntry(nil), l.buffer[len(l.buffer)-size:]...)
+	}
+}
+
+// BufferSize returns the number of log entries kept in memory
+func (l *Logger) BufferSize() int {
+	l.bufferMu.RLock()
+	defer l.bufferMu.RUnlock()
+	return l.maxLines
+}
+
 // SetCallback sets a callback to be called when a new log entry is created
 func (l *Logger) SetCallback(callback LogCallback) {
 	l.bufferMu.Lock()
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat suffixes the name of the rotated log files
+const rotatedTimeFormat = "20060102-150405"
+
+// rotatingFile is a log file renamed with a timestamp suffix, and replaced by
+// a new one, when it grows beyond maxSize or has been written to for maxAge
+type rotatingFile struct {
+	path       string
+	maxSize    int64         // 0 to disable
+	maxAge     time.Duration // 0 to disable
+	maxBackups int           // Rotated files kept, 0 to keep them all
+
+	mu       sync.Mutex
+	file     *os.File // nil after a failed rotation, until reopened
+	size     int64
+	openedAt time.Time // Start of the current file, for maxAge
+	closed   bool
+}
+
+// openRotatingFile opens the log file at path, appending to it if it exists
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file and records its size and start
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	if f.size > 0 {
+		f.openedAt = f.startOf(info)
+	}
+	return nil
+}
+
+// startOf returns when the existing file was started: at the last rotation,
+// or at its last write if it was never rotated
+func (f *rotatingFile) startOf(info os.FileInfo) time.Time {
+	rotated, _ := f.rotatedFiles()
+	if len(rotated) > 0 {
+		t, _ := rotatedAt(filepath.Base(f.path), filepath.Base(rotated[len(rotated)-1]))
+		return t
+	}
+	return info.ModTime()
+}
+
+// rotatedFiles returns the files renamed by rotate, oldest first. Other files
+// starting with the name of the log file are ignored.
+func (f *rotatingFile) rotatedFiles() ([]string, error) {
+	dir, base := filepath.Split(f.path)
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+	var rotated []string
+	for _, e := range entries {
+		if _, ok := rotatedAt(base, e.Name()); ok && e.Type().IsRegular() {
+			rotated = append(rotated, dir+e.Name())
+		}
+	}
+	// The timestamp suffix sorts chronologically
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// rotatedAt returns the rotation time of name, the base name of a file renamed
+// by rotate from the log file base: base, the timestamp and for files rotated
+// within the same second, the time in nanoseconds. It returns false for other
+// names.
+func rotatedAt(base, name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, base+".")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, nanos, found := strings.Cut(suffix, ".")
+	if found && (nanos == "" || strings.Trim(nanos, "0123456789") != "") {
+		return time.Time{}, false
+	}
+	if len(stamp) != len(rotatedTimeFormat) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(rotatedTimeFormat, stamp, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Write implements io.Writer, rotating the file first if needed
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, fmt.Errorf("log file is closed")
+	}
+	if f.file != nil && f.size > 0 && ((f.maxSize > 0 && f.size+int64(len(p)) > f.maxSize) ||
+		(f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge)) {
+		if err := f.rotate(); err != nil {
+			// Keep logging to the current file rather than losing entries
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file: %v\n", err)
+		}
+	}
+	if f.file == nil {
+		// The new file could not be opened by a rotation, e.g. on a full
+		// disk: retried at each write
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file and opens a new one
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	rotated := f.path + "." + time.Now().Format(rotatedTimeFormat)
+	if _, err := os.Stat(rotated); err == nil {
+		// Several rotations within a second
+		rotated = fmt.Sprintf("%s.%d", rotated, time.Now().UnixNano())
+	}
+	renameErr := os.Rename(f.path, rotated)
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	f.prune()
+	return nil
+}
+
+// prune removes the oldest rotated files beyond maxBackups
+func (f *rotatingFile) prune() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	rotated, err := f.rotatedFiles()
+	if err != nil || len(rotated) <= f.maxBackups {
+		return
+	}
+	for _, name := range rotated[:len(rotated)-f.maxBackups] {
+		os.Remove(name)
+	}
+}
+
+// Close closes the file
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/events"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "btcleaner"
+
+// eventBufferSize is large enough to hold the deletions of a whole cleanup
+// run, so that no counter update is dropped
+const eventBufferSize = 1024
+
+// Metrics holds the Prometheus metrics of btcleaner
+type Metrics struct {
+	registry     *prometheus.Registry
+	removed      *prometheus.CounterVec
+	reclaimed    prometheus.Counter
+	runDuration  prometheus.Histogram
+	lastSuccess  prometheus.Gauge
+	blockedRuns  prometheus.Counter
+	rpcDuration  *prometheus.HistogramVec
+	rpcErrors    *prometheus.CounterVec
+	subscription *events.Subscription
+	logger       *logger.Logger
+}
+
+// New creates the metrics. Disk and torrent gauges are read from the cleaner
+// on each scrape.
+func New(clean *cleaner.Cleaner, log *logger.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		removed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "torrents_removed_total",
+			Help:      "Torrents removed, by reason (auto or manual).",
+		}, []string{"reason"}),
+		reclaimed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reclaimed_bytes_total",
+			Help:      "Bytes reclaimed by removing torrents.",
+		}),
+		runDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cleanup_run_duration_seconds",
+			Help:      "Duration of cleanup runs.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cleanup_last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful cleanup run.",
+		}),
+		blockedRuns: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cleanup_blocked_runs_total",
+			Help:      "Cleanup runs that could not reach the minimum free space.",
+		}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "transmission",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Transmission RPC requests, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		rpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "transmission",
+			Name:      "request_errors_total",
+			Help:      "Failed Transmission RPC requests, by method.",
+		}, []string{"method"}),
+		logger: log,
+	}
+
+	// Initialize the labels so that the series exist before the first removal
+	m.removed.WithLabelValues("auto")
+	m.removed.WithLabelValues("manual")
+
+	m.registry.MustRegister(
+		m.removed,
+		m.reclaimed,
+		m.runDuration,
+		m.lastSuccess,
+		m.blockedRuns,
+		m.rpcDuration,
+		m.rpcErrors,
+		&statsCollector{cleaner: clean, logger: log},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// ObserveRequest records a Transmission RPC request. It is a
+// transmission.RequestObserver.
+func (m *Metrics) ObserveRequest(method string, duration time.Duration, err error) {
+	m.rpcDuration.WithLabelValues(method).Observe(duration.Seconds())
+	m.rpcErrors.WithLabelValues(method) // Expose a zero error count
+	if err != nil {
+		m.rpcErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// Watch updates the removal and run metrics from the events of the bus
+func (m *Metrics) Watch(bus *events.Bus) {
+	m.subscription = bus.Subscribe([]string{events.TopicTorrentDeleted, events.TopicCleanupFinished, events.TopicCleanupBlocked}, eventBufferSize)
+
+	go func() {
+		for event := range m.subscription.C() {
+			switch data := event.Data.(type) {
+			case events.TorrentDeleted:
+				m.removed.WithLabelValues(data.Reason).Inc()
+				m.reclaimed.Add(float64(data.SizeBytes))
+			case events.CleanupFinished:
+				m.runDuration.Observe(float64(data.DurationMs) / 1000)
+				if data.Status == cleaner.RunStatusSuccess {
+					m.lastSuccess.Set(float64(event.Time.Unix()))
+				}
+			case events.CleanupBlocked:
+				m.blockedRuns.Inc()
+			}
+		}
+	}()
+}
+
+// Close stops watching events
+func (m *Metrics) Close() {
+	if m.subscription != nil {
+		m.subscription.Close()
+	}
+}
+
+// Handler returns the HTTP handler exposing the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// statsCollector reads the disk and torrent gauges from the cleaner stats
+type statsCollector struct {
+	cleaner *cleaner.Cleaner
+	logger  *logger.Logger
+}
+
+var (
+	freeSpaceDesc = prometheus.NewDesc(namespace+"_free_space_bytes",
+		"Free space in the Transmission download directory.", nil, nil)
+	minFreeSpaceDesc = prometheus.NewDesc(namespace+"_min_free_space_bytes",
+		"Free space threshold below which torrents are removed.", nil, nil)
+	candidatesDesc = prometheus.NewDesc(namespace+"_cleanup_candidates",
+		"Torrents a cleanup would remove now.", nil, nil)
+	trackerTorrentsDesc = prometheus.NewDesc(namespace+"_tracker_torrents",
+		"Torrents in Transmission, by tracker.", []string{"tracker"}, nil)
+	trackerBytesDesc = prometheus.NewDesc(namespace+"_tracker_size_bytes",
+		"Size of the torrents in Transmission, by tracker.", []string{"tracker"}, nil)
+	blockedDesc = prometheus.NewDesc(namespace+"_cleanup_blocked",
+		"Whether a cleanup now could not reach the minimum free space.", nil, nil)
+	shortfallDesc = prometheus.NewDesc(namespace+"_cleanup_shortfall_bytes",
+		"Space a cleanup now could not free to reach the minimum free space.", nil, nil)
+	statsUpDesc = prometheus.NewDesc(namespace+"_stats_up",
+		"Whether the stats could be read from Transmission during the scrape.", nil, nil)
+)
+
+// Describe implements prometheus.Collector
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- freeSpaceDesc
+	ch <- minFreeSpaceDesc
+	ch <- candidatesDesc
+	ch <- trackerTorrentsDesc
+	ch <- trackerBytesDesc
+	ch <- blockedDesc
+	ch <- shortfallDesc
+	ch <- statsUpDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.cleaner.GetStats()
+	if err != nil {
+		c.logger.Debugf("Failed to get stats for metrics: %v", err)
+		ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 0)
+		ch <- prometheus.MustNewConstMetric(minFreeSpaceDesc, prometheus.GaugeValue, float64(c.cleaner.Policy().MinFreeSpace))
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(freeSpaceDesc, prometheus.GaugeValue, float64(stats.FreeSpaceBytes))
+	ch <- prometheus.MustNewConstMetric(minFreeSpaceDesc, prometheus.GaugeValue, float64(stats.MinFreeSpaceBytes))
+	ch <- prometheus.MustNewConstMetric(candidatesDesc, prometheus.GaugeValue, float64(stats.CandidatesCount))
+	blocked, shortfall := 0.0, 0.0
+	if stats.Blocked != nil {
+		blocked, shortfall = 1, float64(stats.Blocked.ShortfallBytes)
+	}
+	ch <- prometheus.MustNewConstMetric(blockedDesc, prometheus.GaugeValue, blocked)
+	ch <- prometheus.MustNewConstMetric(shortfallDesc, prometheus.GaugeValue, shortfall)
+	for _, t := range stats.TrackerStats {
+		ch <- prometheus.MustNewConstMetric(trackerTorrentsDesc, prometheus.GaugeValue, float64(t.Count), t.Name)
+		ch <- prometheus.MustNewConstMetric(trackerBytesDesc, prometheus.GaugeValue, float64(t.SizeBytes), t.Name)
+	}
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/config"
+)
+
+// defaultSMTPPort is the submission port, with STARTTLS
+const defaultSMTPPort = 587
+
+// email sends notifications over SMTP
+type email struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// newEmail creates an SMTP sink
+func newEmail(cfg config.NotificationConfig) *email {
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	return &email{
+		host:     cfg.SMTPHost,
+		port:     port,
+		username: cfg.Username,
+		password: cfg.Password.Value(),
+		from:     cfg.From,
+		to:       cfg.To,
+	}
+}
+
+// Notify implements Notifier. STARTTLS is used when the server offers it;
+// authentication requires it, except on localhost.
+func (e *email) Notify(ctx context.Context, n Notification) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(e.host, strconv.Itoa(e.port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if e.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(e.message(n)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.Quit()
+}
+
+// message builds the email of a notification
+func (e *email) message(n Notification) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + e.from + "\r\n")
+	b.WriteString("To: " + strings.Join(e.to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", "[BTCleaner] "+n.Title) + "\r\n")
+	b.WriteString("Date: " + n.Time.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(n.Message, "\n", "\r\n") + "\r\n")
+	return []byte(b.String())
+}
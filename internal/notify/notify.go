@@ -0,0 +1,269 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/events"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+)
+
+const (
+	// sendTimeout bounds the delivery of one notification to one sink
+	sendTimeout = 15 * time.Second
+	// queueSize is the number of notifications waiting for a sink before new ones are dropped
+	queueSize = 100
+	// eventBufferSize is large enough to hold the deletions of a whole cleanup run
+	eventBufferSize = 1024
+	// closeTimeout bounds the delivery of pending notifications on Close
+	closeTimeout = 10 * time.Second
+)
+
+// Notification is a message sent to the notification sinks
+type Notification struct {
+	Event   string      `json:"event"` // One of the config.Notify* events
+	Title   string      `json:"title"`
+	Message string      `json:"message"`
+	Time    time.Time   `json:"time"`
+	Data    interface{} `json:"data,omitempty"` // Payload of the bus event
+}
+
+// Notifier delivers notifications to one sink
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// sink is a notifier with its event filter and delivery queue
+type sink struct {
+	name     string
+	notifier Notifier
+	events   map[string]bool
+	queue    chan Notification
+}
+
+// Dispatcher turns bus events into notifications and sends them to the
+// sinks subscribed to them
+type Dispatcher struct {
+	sinks        []*sink
+	logger       *logger.Logger
+	subscription *events.Subscription
+	ctx          context.Context
+	cancel       context.CancelFunc
+	loop         sync.WaitGroup
+	workers      sync.WaitGroup
+
+	transmissionDown bool // Only accessed by the event loop
+}
+
+// New creates a dispatcher for the configured sinks
+func New(cfgs []config.NotificationConfig, log *logger.Logger) (*Dispatcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{logger: log, ctx: ctx, cancel: cancel}
+
+	for i, cfg := range cfgs {
+		notifier, err := newNotifier(cfg)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("notification %s: %w", cfg.DisplayName(i), err)
+		}
+
+		filter := cfg.Events
+		if len(filter) == 0 {
+			filter = config.DefaultNotifyEvents
+		}
+		s := &sink{
+			name:     cfg.DisplayName(i),
+			notifier: notifier,
+			events:   make(map[string]bool, len(filter)),
+			queue:    make(chan Notification, queueSize),
+		}
+		for _, e := range filter {
+			s.events[e] = true
+		}
+		d.sinks = append(d.sinks, s)
+	}
+
+	for _, s := range d.sinks {
+		d.workers.Add(1)
+		go d.deliver(s)
+	}
+	return d, nil
+}
+
+// newNotifier creates the notifier of a sink
+func newNotifier(cfg config.NotificationConfig) (Notifier, error) {
+	switch cfg.Type {
+	case config.NotifyWebhook:
+		return newWebhook(cfg)
+	case config.NotifyNtfy:
+		return &ntfy{url: cfg.URL, topic: cfg.Topic, token: cfg.Token.Value(), priority: cfg.Priority}, nil
+	case config.NotifyGotify:
+		return &gotify{url: cfg.URL, token: cfg.Token.Value(), priority: cfg.Priority}, nil
+	case config.NotifyDiscord:
+		return &discord{url: cfg.URL}, nil
+	case config.NotifySlack:
+		return &slack{url: cfg.URL}, nil
+	case config.NotifyEmail:
+		return newEmail(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", cfg.Type)
+	}
+}
+
+// Watch sends notifications for the cleanup runs and deletions published on the bus
+func (d *Dispatcher) Watch(bus *events.Bus) {
+	d.subscription = bus.Subscribe([]string{events.TopicTorrentDeleted, events.TopicCleanupFinished, events.TopicCleanupBlocked}, eventBufferSize)
+
+	d.loop.Add(1)
+	go func() {
+		defer d.loop.Done()
+		for event := range d.subscription.C() {
+			switch data := event.Data.(type) {
+			case events.TorrentDeleted:
+				d.send(deletionNotification(event.Time, data))
+			case events.CleanupFinished:
+				d.cleanupFinished(event.Time, data)
+			case events.CleanupBlocked:
+				d.send(Notification{
+					Event:   config.NotifyInsufficientSpace,
+					Title:   "Cannot free enough space",
+					Message: data.Message,
+					Time:    event.Time,
+					Data:    data,
+				})
+			}
+		}
+	}()
+}
+
+// Close stops watching events and waits for the pending notifications to be
+// sent, at most closeTimeout
+func (d *Dispatcher) Close() {
+	if d.subscription != nil {
+		d.subscription.Close()
+		d.loop.Wait()
+	}
+	for _, s := range d.sinks {
+		close(s.queue)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+		d.logger.Warn("Timed out sending pending notifications")
+	}
+	d.cancel()
+}
+
+// cleanupFinished sends the notifications of a finished cleanup run
+func (d *Dispatcher) cleanupFinished(at time.Time, run events.CleanupFinished) {
+	failed := run.Status == cleaner.RunStatusFailed
+	wasDown := d.transmissionDown
+
+	// A run only fails when Transmission cannot be queried
+	if failed && !wasDown {
+		d.transmissionDown = true
+		d.send(Notification{
+			Event:   config.NotifyTransmissionStatus,
+			Title:   "Transmission unreachable",
+			Message: fmt.Sprintf("Cleanup check failed: %s", run.Error),
+			Time:    at,
+			Data:    run,
+		})
+	} else if !failed && wasDown {
+		d.transmissionDown = false
+		d.send(Notification{
+			Event:   config.NotifyTransmissionStatus,
+			Title:   "Transmission reachable again",
+			Message: fmt.Sprintf("Cleanup check succeeded, free space: %s", formatSize(run.FinalFreeSpace)),
+			Time:    at,
+			Data:    run,
+		})
+	}
+
+	// Failures are only reported once per outage, not at each check, and
+	// cleanups deferred to the next deletion window when they happen
+	if (failed && !wasDown) || (run.NeedCleanup && !run.Deferred) {
+		d.send(runSummaryNotification(at, run))
+	}
+}
+
+// runSummaryNotification describes a cleanup run
+func runSummaryNotification(at time.Time, run events.CleanupFinished) Notification {
+	n := Notification{Event: config.NotifyRunSummary, Time: at, Data: run}
+	switch {
+	case run.Status == cleaner.RunStatusFailed:
+		n.Title = "Cleanup failed"
+		n.Message = run.Error
+	case run.DryRun:
+		n.Title = "Cleanup simulated (dry run)"
+		n.Message = fmt.Sprintf("Would remove %d torrents (%s), free space: %s",
+			run.RemovedCount, formatSize(run.RemovedSize), formatSize(run.InitialFreeSpace))
+	default:
+		n.Title = "Cleanup completed"
+		n.Message = fmt.Sprintf("Removed %d torrents (%s freed), free space: %s -> %s",
+			run.RemovedCount, formatSize(run.RemovedSize), formatSize(run.InitialFreeSpace), formatSize(run.FinalFreeSpace))
+		if run.Status == cleaner.RunStatusPartial {
+			n.Title = "Cleanup completed with errors"
+		}
+	}
+	return n
+}
+
+// deletionNotification describes a deleted torrent
+func deletionNotification(at time.Time, t events.TorrentDeleted) Notification {
+	title := "Torrent removed"
+	if t.Reason == "manual" {
+		title = "Torrent removed manually"
+	}
+	return Notification{
+		Event:   config.NotifyDeletion,
+		Title:   title,
+		Message: fmt.Sprintf("%s (%s, tracker %s)", t.Name, formatSize(t.SizeBytes), t.Tracker),
+		Time:    at,
+		Data:    t,
+	}
+}
+
+// send queues a notification for the sinks subscribed to its event, without blocking
+func (d *Dispatcher) send(n Notification) {
+	for _, s := range d.sinks {
+		if !s.events[n.Event] {
+			continue
+		}
+		select {
+		case s.queue <- n:
+		default:
+			d.logger.Warnf("Notification queue of %s is full, dropping %s notification", s.name, n.Event)
+		}
+	}
+}
+
+// deliver sends the queued notifications of a sink, in order
+func (d *Dispatcher) deliver(s *sink) {
+	defer d.workers.Done()
+	for n := range s.queue {
+		ctx, cancel := context.WithTimeout(d.ctx, sendTimeout)
+		err := s.notifier.Notify(ctx, n)
+		cancel()
+		if err != nil {
+			d.logger.Warnf("Failed to send %s notification to %s: %v", n.Event, s.name, err)
+		} else {
+			d.logger.Debugf("Sent %s notification to %s", n.Event, s.name)
+		}
+	}
+}
+
+// formatSize formats a size in bytes as GB, like the logs
+func formatSize(bytes int64) string {
+	return fmt.Sprintf("%.2f GB", float64(bytes)/(1024*1024*1024))
+}
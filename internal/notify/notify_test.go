@@ -0,0 +1,406 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+)
+
+// request is an HTTP request received by a test server
+type request struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// recorder is an HTTP server recording the requests it receives
+type recorder struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+}
+
+func newRecorder(t *testing.T) *recorder {
+	t.Helper()
+	rec := &recorder{}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, request{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		rec.mu.Unlock()
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+// received returns the recorded requests
+func (rec *recorder) received() []request {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]request(nil), rec.requests...)
+}
+
+// only returns the single recorded request
+func (rec *recorder) only(t *testing.T) request {
+	t.Helper()
+	reqs := rec.received()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	return reqs[0]
+}
+
+var testNotification = Notification{
+	Event:   config.NotifyRunSummary,
+	Title:   "Cleanup completed",
+	Message: "Removed 2 torrents",
+	Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+// notify sends testNotification through the sink of cfg
+func notify(t *testing.T, cfg config.NotificationConfig) {
+	t.Helper()
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		t.Fatalf("newNotifier: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+}
+
+// decode decodes a JSON object body
+func decode(t *testing.T, body []byte) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", body, err)
+	}
+	return m
+}
+
+func TestWebhookDefaultBody(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{
+		Type:    config.NotifyWebhook,
+		URL:     rec.URL + "/hook",
+		Headers: map[string]string{"X-Test": "yes"},
+	})
+
+	req := rec.only(t)
+	if req.path != "/hook" {
+		t.Errorf("path = %q, want /hook", req.path)
+	}
+	if got := req.header.Get("X-Test"); got != "yes" {
+		t.Errorf("X-Test header = %q, want yes", got)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var got Notification
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", req.body, err)
+	}
+	if got.Event != testNotification.Event || got.Title != testNotification.Title ||
+		got.Message != testNotification.Message || !got.Time.Equal(testNotification.Time) {
+		t.Errorf("body = %+v, want %+v", got, testNotification)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{
+		Type:     config.NotifyWebhook,
+		URL:      rec.URL,
+		Template: `{"text": {{json (printf "%s: %s" .Title .Message)}}, "event": "{{.Event}}"}`,
+	})
+
+	body := decode(t, rec.only(t).body)
+	if body["text"] != "Cleanup completed: Removed 2 torrents" {
+		t.Errorf("text = %v", body["text"])
+	}
+	if body["event"] != config.NotifyRunSummary {
+		t.Errorf("event = %v", body["event"])
+	}
+}
+
+func TestWebhookHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	notifier, err := newNotifier(config.NotificationConfig{Type: config.NotifyWebhook, URL: srv.URL})
+	if err != nil {
+		t.Fatalf("newNotifier: %v", err)
+	}
+	err = notifier.Notify(context.Background(), testNotification)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("Notify error = %v, want HTTP 400", err)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{
+		Type:     config.NotifyNtfy,
+		URL:      rec.URL + "/",
+		Topic:    "btcleaner",
+		Token:    "tk_secret",
+		Priority: 4,
+	})
+
+	req := rec.only(t)
+	if req.path != "/" {
+		t.Errorf("path = %q, want /", req.path)
+	}
+	if got := req.header.Get("Authorization"); got != "Bearer tk_secret" {
+		t.Errorf("Authorization = %q, want Bearer tk_secret", got)
+	}
+	body := decode(t, req.body)
+	want := map[string]interface{}{
+		"topic":    "btcleaner",
+		"title":    testNotification.Title,
+		"message":  testNotification.Message,
+		"priority": float64(4),
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %v, want %v", k, body[k], v)
+		}
+	}
+}
+
+func TestNtfyWithoutToken(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{Type: config.NotifyNtfy, URL: rec.URL, Topic: "btcleaner"})
+
+	req := rec.only(t)
+	if got := req.header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+	if _, ok := decode(t, req.body)["priority"]; ok {
+		t.Error("priority sent although not configured")
+	}
+}
+
+func TestGotify(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{
+		Type:     config.NotifyGotify,
+		URL:      rec.URL + "/gotify/",
+		Token:    "app_token",
+		Priority: 7,
+	})
+
+	req := rec.only(t)
+	if req.path != "/gotify/message" {
+		t.Errorf("path = %q, want /gotify/message", req.path)
+	}
+	if got := req.header.Get("X-Gotify-Key"); got != "app_token" {
+		t.Errorf("X-Gotify-Key = %q, want app_token", got)
+	}
+	body := decode(t, req.body)
+	if body["title"] != testNotification.Title || body["message"] != testNotification.Message || body["priority"] != float64(7) {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{Type: config.NotifyDiscord, URL: rec.URL})
+
+	body := decode(t, rec.only(t).body)
+	if len(body) != 1 || body["content"] != "**Cleanup completed**\nRemoved 2 torrents" {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestDiscordTruncatesLongMessages(t *testing.T) {
+	rec := newRecorder(t)
+	notifier := &discord{url: rec.URL}
+	n := testNotification
+	n.Message = strings.Repeat("é", 3000)
+	if err := notifier.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	content, _ := decode(t, rec.only(t).body)["content"].(string)
+	if got := len([]rune(content)); got != discordMaxContent {
+		t.Errorf("content length = %d runes, want %d", got, discordMaxContent)
+	}
+	if !strings.HasSuffix(content, "…") {
+		t.Error("truncated content does not end with an ellipsis")
+	}
+}
+
+func TestSlack(t *testing.T) {
+	rec := newRecorder(t)
+	notify(t, config.NotificationConfig{Type: config.NotifySlack, URL: rec.URL})
+
+	body := decode(t, rec.only(t).body)
+	if len(body) != 1 || body["text"] != "*Cleanup completed*\nRemoved 2 torrents" {
+		t.Errorf("body = %v", body)
+	}
+}
+
+// smtpMessage is a message received by the SMTP stub
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP accepts one connection on l and answers it as a minimal SMTP
+// server without STARTTLS nor authentication
+func serveSMTP(t *testing.T, l net.Listener) <-chan smtpMessage {
+	t.Helper()
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		defer close(messages)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var msg smtpMessage
+
+		reply("220 localhost ESMTP test")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msg.data = data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				messages <- msg
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return messages
+}
+
+func TestEmail(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	messages := serveSMTP(t, l)
+
+	notify(t, config.NotificationConfig{
+		Type:     config.NotifyEmail,
+		SMTPHost: "127.0.0.1",
+		SMTPPort: l.Addr().(*net.TCPAddr).Port,
+		From:     "btcleaner@example.com",
+		To:       []string{"admin@example.com", "ops@example.com"},
+	})
+
+	select {
+	case msg := <-messages:
+		if msg.from != "btcleaner@example.com" {
+			t.Errorf("MAIL FROM = %q", msg.from)
+		}
+		if strings.Join(msg.to, ",") != "admin@example.com,ops@example.com" {
+			t.Errorf("RCPT TO = %v", msg.to)
+		}
+		for _, want := range []string{
+			"To: admin@example.com, ops@example.com\r\n",
+			"Subject: [BTCleaner] Cleanup completed\r\n",
+			"Content-Type: text/plain; charset=utf-8\r\n",
+			"\r\n\r\nRemoved 2 torrents\r\n",
+		} {
+			if !strings.Contains(msg.data, want) {
+				t.Errorf("message does not contain %q:\n%s", want, msg.data)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestDispatcherEventFilter(t *testing.T) {
+	deletions := newRecorder(t)
+	defaults := newRecorder(t)
+
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+	d, err := New([]config.NotificationConfig{
+		{Type: config.NotifyWebhook, URL: deletions.URL, Events: []string{config.NotifyDeletion}},
+		{Type: config.NotifyWebhook, URL: defaults.URL},
+	}, log)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for _, event := range []string{config.NotifyDeletion, config.NotifyRunSummary, config.NotifyDeletion, config.NotifyInsufficientSpace} {
+		n := testNotification
+		n.Event = event
+		d.send(n)
+	}
+	// Close waits for the queued notifications to be delivered
+	d.Close()
+
+	events := func(rec *recorder) []string {
+		var got []string
+		for _, req := range rec.received() {
+			var n Notification
+			if err := json.Unmarshal(req.body, &n); err != nil {
+				t.Fatalf("invalid JSON body %q: %v", req.body, err)
+			}
+			got = append(got, n.Event)
+		}
+		return got
+	}
+	if got, want := strings.Join(events(deletions), ","), "deletion,deletion"; got != want {
+		t.Errorf("deletion sink received %s, want %s", got, want)
+	}
+	if got, want := strings.Join(events(defaults), ","), "run_summary,insufficient_space"; got != want {
+		t.Errorf("default sink received %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"text/template"
+
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/tmplfuncs"
+)
+
+// discordMaxContent is the maximum length of a Discord message
+const discordMaxContent = 2000
+
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// postJSON posts a JSON body and fails on non-2xx responses
+func postJSON(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// The URL of webhooks holds their token, keep it out of the logs
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// webhook posts the notification as JSON, or the rendered template
+type webhook struct {
+	url      string
+	headers  map[string]string
+	template *template.Template
+}
+
+// newWebhook creates a generic webhook sink
+func newWebhook(cfg config.NotificationConfig) (*webhook, error) {
+	w := &webhook{url: cfg.URL, headers: cfg.Headers}
+	if cfg.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(tmplfuncs.Funcs()).Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		w.template = tmpl
+	}
+	return w, nil
+}
+
+// Notify implements Notifier
+func (w *webhook) Notify(ctx context.Context, n Notification) error {
+	if w.template == nil {
+		body, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, w.url, body, w.headers)
+	}
+
+	var body bytes.Buffer
+	if err := w.template.Execute(&body, n); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return postJSON(ctx, w.url, body.Bytes(), w.headers)
+}
+
+// ntfy publishes to an ntfy topic
+type ntfy struct {
+	url      string
+	topic    string
+	token    string
+	priority int
+}
+
+// Notify implements Notifier
+func (s *ntfy) Notify(ctx context.Context, n Notification) error {
+	// JSON publishing keeps non-ASCII titles intact, unlike headers
+	body, err := json.Marshal(struct {
+		Topic    string `json:"topic"`
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority,omitempty"`
+	}{s.topic, n.Title, n.Message, s.priority})
+	if err != nil {
+		return err
+	}
+
+	var headers map[string]string
+	if s.token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + s.token}
+	}
+	return postJSON(ctx, strings.TrimSuffix(s.url, "/"), body, headers)
+}
+
+// gotify sends a Gotify application message
+type gotify struct {
+	url      string
+	token    string
+	priority int
+}
+
+// Notify implements Notifier
+func (s *gotify) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}{n.Title, n.Message, s.priority})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, strings.TrimSuffix(s.url, "/")+"/message", body, map[string]string{"X-Gotify-Key": s.token})
+}
+
+// discord posts to a Discord incoming webhook
+type discord struct {
+	url string
+}
+
+// Notify implements Notifier
+func (s *discord) Notify(ctx context.Context, n Notification) error {
+	content := fmt.Sprintf("**%s**\n%s", n.Title, n.Message)
+	if r := []rune(content); len(r) > discordMaxContent {
+		content = string(r[:discordMaxContent-1]) + "…"
+	}
+	body, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.url, body, nil)
+}
+
+// slack posts to a Slack incoming webhook
+type slack struct {
+	url string
+}
+
+// Notify implements Notifier
+func (s *slack) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", n.Title, n.Message)})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.url, body, nil)
+}
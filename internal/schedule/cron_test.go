@@ -0,0 +1,154 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// bits returns the bit set of the given values
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+// span returns the bit set of the values from lo to hi, every step
+func span(lo, hi, step int) uint64 {
+	var b uint64
+	for v := lo; v <= hi; v += step {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+// date returns the time of a test in UTC
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParseCron(t *testing.T) {
+	anyMinute, anyHour, anyDay, anyMonth, anyWeek := span(0, 59, 1), span(0, 23, 1), span(1, 31, 1), span(1, 12, 1), span(0, 7, 1)
+	tests := []struct {
+		expr                          string
+		minute, hour, dom, month, dow uint64
+		anyDay, anyWeek               bool
+	}{
+		{"* * * * *", anyMinute, anyHour, anyDay, anyMonth, anyWeek, true, true},
+		{"*/10 * * * *", bits(0, 10, 20, 30, 40, 50), anyHour, anyDay, anyMonth, anyWeek, true, true},
+		{"5/15 * * * *", bits(5, 20, 35, 50), anyHour, anyDay, anyMonth, anyWeek, true, true},
+		{"0-30/15 2-5 * * *", bits(0, 15, 30), span(2, 5, 1), anyDay, anyMonth, anyWeek, true, true},
+		{"0 2 1,15 * mon-fri", bits(0), bits(2), bits(1, 15), anyMonth, span(1, 5, 1), false, false},
+		{"0 0 * JAN,jul sun", bits(0), bits(0), anyDay, bits(1, 7), bits(0), true, false},
+		{"0 0 * * 7", bits(0), bits(0), anyDay, anyMonth, bits(0, 7), true, false},
+		{"0 0 * * fri-7", bits(0), bits(0), anyDay, anyMonth, bits(0, 5, 6, 7), true, false},
+		{"@daily", bits(0), bits(0), anyDay, anyMonth, anyWeek, true, true},
+		{"@weekly", bits(0), bits(0), anyDay, anyMonth, bits(0), true, false},
+		{"@monthly", bits(0), bits(0), bits(1), anyMonth, anyWeek, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error: %v", tt.expr, err)
+			}
+			if c.minute != tt.minute || c.hour != tt.hour || c.dom != tt.dom || c.month != tt.month || c.dow != tt.dow {
+				t.Errorf("fields = %b %b %b %b %b, want %b %b %b %b %b",
+					c.minute, c.hour, c.dom, c.month, c.dow, tt.minute, tt.hour, tt.dom, tt.month, tt.dow)
+			}
+			if c.anyDay != tt.anyDay || c.anyWeek != tt.anyWeek {
+				t.Errorf("anyDay, anyWeek = %v, %v, want %v, %v", c.anyDay, c.anyWeek, tt.anyDay, tt.anyWeek)
+			}
+			if c.String() != tt.expr {
+				t.Errorf("String() = %q, want %q", c.String(), tt.expr)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"* * * * fri-sun", // Sunday is 0 in ranges
+		"*/0 * * * *",
+		"*/x * * * *",
+		"* * * foo *",
+		"@yearly",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"step", "*/10 * * * *", time.Date(2026, 10, 18, 12, 34, 56, 0, time.UTC), date(2026, 10, 18, 12, 40)},
+		{"strictly after", "*/10 * * * *", date(2026, 10, 18, 12, 40), date(2026, 10, 18, 12, 50)},
+		{"next day", "0 2 * * *", date(2026, 10, 18, 3, 0), date(2026, 10, 19, 2, 0)},
+		{"next hour", "15 * * * *", date(2026, 10, 18, 23, 20), date(2026, 10, 19, 0, 15)},
+		{"weekday name", "30 1 * * mon", date(2026, 10, 18, 12, 0), date(2026, 10, 19, 1, 30)},
+		{"sunday as 7", "0 0 * * 7", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+		{"next month", "0 0 1 * *", date(2026, 10, 18, 0, 0), date(2026, 11, 1, 0, 0)},
+		{"next year", "0 0 1 jan *", date(2026, 10, 18, 0, 0), date(2027, 1, 1, 0, 0)},
+		{"leap day", "0 0 29 2 *", date(2026, 10, 18, 0, 0), date(2028, 2, 29, 0, 0)},
+		{"day of month or week, month day first", "0 0 13 * fri", date(2026, 10, 12, 12, 0), date(2026, 10, 13, 0, 0)},
+		{"day of month or week, weekday first", "0 0 13 * fri", date(2026, 10, 13, 12, 0), date(2026, 10, 16, 0, 0)},
+		{"never february 30", "0 0 30 2 *", date(2026, 10, 18, 0, 0), time.Time{}},
+		{"never april 31", "0 0 31 4 *", date(2026, 10, 18, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error: %v", tt.expr, err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronDayMatches(t *testing.T) {
+	// 2026-10-13 is a Tuesday, 2026-10-16 a Friday
+	tests := []struct {
+		expr string
+		day  time.Time
+		want bool
+	}{
+		{"* * * * *", date(2026, 10, 14, 0, 0), true},
+		{"* * 13 * *", date(2026, 10, 13, 0, 0), true},
+		{"* * 13 * *", date(2026, 10, 16, 0, 0), false},
+		{"* * * * fri", date(2026, 10, 16, 0, 0), true},
+		{"* * * * fri", date(2026, 10, 13, 0, 0), false},
+		{"* * 13 * fri", date(2026, 10, 13, 0, 0), true},
+		{"* * 13 * fri", date(2026, 10, 16, 0, 0), true},
+		{"* * 13 * fri", date(2026, 10, 14, 0, 0), false},
+		{"* * */2 * *", date(2026, 10, 13, 0, 0), true},
+		{"* * */2 * *", date(2026, 10, 14, 0, 0), false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) error: %v", tt.expr, err)
+		}
+		if got := c.dayMatches(tt.day); got != tt.want {
+			t.Errorf("%q: dayMatches(%s) = %v, want %v", tt.expr, tt.day.Format("Mon 2006-01-02"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ParsePolicySchedules parses the cron expressions of the cleanup policies
+// that run on their own schedule, by policy name
+func ParsePolicySchedules(specs map[string]string) (map[string]*Cron, error) {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	policies := make(map[string]*Cron, len(specs))
+	for _, name := range names {
+		cron, err := ParseCron(specs[name])
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", name, err)
+		}
+		policies[name] = cron
+	}
+	return policies, nil
+}
+
+// PolicyDue reports whether a policy last run at last is due at a check at
+// now. A policy without its own schedule runs with every check; one with a
+// schedule runs at the first check at or after each time of its schedule.
+func (s *Schedule) PolicyDue(policy string, last, now time.Time) bool {
+	cron, ok := s.policies[policy]
+	if !ok || last.IsZero() {
+		return true
+	}
+	next := cron.Next(last)
+	return !next.IsZero() && !next.After(now)
+}
@@ -0,0 +1,36 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyDue(t *testing.T) {
+	s, err := New(time.Minute, "", nil, nil, 0, map[string]string{"stalled": "0 */6 * * *"})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	tests := []struct {
+		name      string
+		policy    string
+		last, now time.Time
+		want      bool
+	}{
+		{"never run", "stalled", time.Time{}, date(2026, 10, 18, 1, 0), true},
+		{"before its time", "stalled", date(2026, 10, 18, 0, 0), date(2026, 10, 18, 5, 59), false},
+		{"at its time", "stalled", date(2026, 10, 18, 0, 0), date(2026, 10, 18, 6, 0), true},
+		{"first check after its time", "stalled", date(2026, 10, 18, 0, 0), date(2026, 10, 18, 7, 30), true},
+		{"without schedule", "unregistered", date(2026, 10, 18, 0, 0), date(2026, 10, 18, 0, 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.PolicyDue(tt.policy, tt.last, tt.now); got != tt.want {
+				t.Errorf("PolicyDue(%q, %v, %v) = %v, want %v", tt.policy, tt.last, tt.now, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(time.Minute, "", nil, nil, 0, map[string]string{"stalled": "* * *"}); err == nil {
+		t.Error("New succeeded with an invalid policy schedule")
+	}
+}
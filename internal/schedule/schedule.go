@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// windowSearchLimit bounds the search of the next deletion window
+const windowSearchLimit = 8 * 24 * time.Hour
+
+// Schedule decides when the daemon runs its checks, and when a check may
+// delete torrents
+type Schedule struct {
+	interval  time.Duration // Used without cron expression
+	cron      *Cron
+	allowed   []Window         // Deletions only happen within these windows, any time if empty
+	blocked   []Window         // No deletion within these windows
+	emergency int64            // Free space below which the windows are ignored, 0 for none
+	policies  map[string]*Cron // Own schedules of the policies that do not depend on free space
+}
+
+// New creates a schedule running every interval, or on a cron expression if
+// expr is set. Deletions are limited to the allowed windows, except within the
+// blocked windows, unless free space is below the emergency floor. Policies
+// maps the cleanup policies that run on their own schedule to a cron expression.
+func New(interval time.Duration, expr string, allowed, blocked []string, emergency int64, policies map[string]string) (*Schedule, error) {
+	s := &Schedule{interval: interval, emergency: emergency}
+	if expr != "" {
+		cron, err := ParseCron(expr)
+		if err != nil {
+			return nil, err
+		}
+		s.cron = cron
+	} else if interval <= 0 {
+		return nil, fmt.Errorf("check interval must be positive")
+	}
+
+	for _, spec := range allowed {
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.allowed = append(s.allowed, w)
+	}
+	for _, spec := range blocked {
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.blocked = append(s.blocked, w)
+	}
+	if len(policies) > 0 {
+		p, err := ParsePolicySchedules(policies)
+		if err != nil {
+			return nil, err
+		}
+		s.policies = p
+	}
+	return s, nil
+}
+
+// String describes when the checks run
+func (s *Schedule) String() string {
+	if s.cron != nil {
+		return "cron " + s.cron.String()
+	}
+	return "every " + s.interval.String()
+}
+
+// Interval returns the time between two checks, 0 for a cron schedule
+func (s *Schedule) Interval() time.Duration {
+	if s.cron != nil {
+		return 0
+	}
+	return s.interval
+}
+
+// Next returns the time of the check following t
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(t)
+	}
+	return t.Add(s.interval)
+}
+
+// LongestGap returns the longest time between two of the n checks following t
+func (s *Schedule) LongestGap(t time.Time, n int) time.Duration {
+	var longest time.Duration
+	prev := s.Next(t)
+	for i := 1; i < n && !prev.IsZero(); i++ {
+		next := s.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(prev); gap > longest {
+			longest = gap
+		}
+		prev = next
+	}
+	return longest
+}
+
+// Windowed reports whether deletions are limited by time windows
+func (s *Schedule) Windowed() bool {
+	return len(s.allowed) > 0 || len(s.blocked) > 0
+}
+
+// DeletionAllowed reports whether a check at t may delete torrents
+func (s *Schedule) DeletionAllowed(t time.Time) bool {
+	for _, w := range s.blocked {
+		if w.Contains(t) {
+			return false
+		}
+	}
+	if len(s.allowed) == 0 {
+		return true
+	}
+	for _, w := range s.allowed {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextDeletionWindow returns when deletions are next allowed after t, t
+// itself if they are allowed now. It returns false if they are never allowed.
+func (s *Schedule) NextDeletionWindow(t time.Time) (time.Time, bool) {
+	if s.DeletionAllowed(t) {
+		return t, true
+	}
+	// Windows are defined to the minute
+	limit := t.Add(windowSearchLimit)
+	for m := t.Truncate(time.Minute).Add(time.Minute); m.Before(limit); m = m.Add(time.Minute) {
+		if s.DeletionAllowed(m) {
+			return m, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// EmergencyFloor returns the free space below which the windows are ignored,
+// 0 if there is none
+func (s *Schedule) EmergencyFloor() int64 {
+	return s.emergency
+}
+
+// Emergency reports whether the free space is low enough to ignore the windows
+func (s *Schedule) Emergency(freeSpace int64) bool {
+	return s.emergency > 0 && freeSpace < s.emergency
+}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// allDays has the bit of every weekday set
+const allDays = 1<<7 - 1
+
+// Window is a daily time range, optionally restricted to some weekdays, such
+// as "02:00-06:00" or "sat,sun 10:00-18:00". A range ending before it starts
+// ends the next day ("22:00-02:00").
+type Window struct {
+	spec  string
+	days  uint8 // Bit i set when the window starts on time.Weekday i
+	start int   // Minutes since midnight
+	end   int   // Minutes since midnight, up to 24:00
+}
+
+// ParseWindow parses a time window: "[days ]HH:MM-HH:MM", where days is a
+// list of day names or ranges ("mon-fri", "sat,sun")
+func ParseWindow(spec string) (Window, error) {
+	w := Window{spec: spec, days: allDays}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+	case 2:
+		days, err := parseField(strings.ToLower(fields[0]), 0, 7, dayNames)
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid window %q: days: %w", spec, err)
+		}
+		if days&(1<<7) != 0 {
+			days |= 1
+		}
+		w.days = uint8(days & allDays)
+	default:
+		return Window{}, fmt.Errorf("invalid window %q: expected [days] HH:MM-HH:MM", spec)
+	}
+
+	times := strings.SplitN(fields[len(fields)-1], "-", 2)
+	if len(times) != 2 {
+		return Window{}, fmt.Errorf("invalid window %q: expected [days] HH:MM-HH:MM", spec)
+	}
+	var err error
+	if w.start, err = parseClock(times[0]); err != nil || w.start == 24*60 {
+		return Window{}, fmt.Errorf("invalid window %q: invalid start time %q", spec, times[0])
+	}
+	if w.end, err = parseClock(times[1]); err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: invalid end time %q", spec, times[1])
+	}
+	if w.start == w.end {
+		return Window{}, fmt.Errorf("invalid window %q: empty time range", spec)
+	}
+	return w, nil
+}
+
+// parseClock parses a time of day ("HH:MM") into minutes since midnight
+func parseClock(s string) (int, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) < 4 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// String returns the window as configured
+func (w Window) String() string {
+	return w.spec
+}
+
+// Contains reports whether t is within the window
+func (w Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := w.days&(1<<uint(t.Weekday())) != 0
+	if w.start < w.end {
+		return today && minute >= w.start && minute < w.end
+	}
+	// Wraps past midnight: the part after the start today, or the part
+	// before the end of a window started yesterday
+	yesterday := w.days&(1<<uint((t.Weekday()+6)%7)) != 0
+	return (today && minute >= w.start) || (yesterday && minute < w.end)
+}
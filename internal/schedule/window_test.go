@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"02:00",
+		"mon 02:00",
+		"02:00-02:00",
+		"24:00-01:00",
+		"25:00-26:00",
+		"02:60-03:00",
+		"2-3",
+		"foo 02:00-06:00",
+		"mon tue 02:00-06:00",
+	} {
+		if _, err := ParseWindow(spec); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 2026-10-16 is a Friday, 2026-10-18 a Sunday
+	tests := []struct {
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"02:00-06:00", date(2026, 10, 18, 1, 59), false},
+		{"02:00-06:00", date(2026, 10, 18, 2, 0), true},
+		{"02:00-06:00", date(2026, 10, 18, 5, 59), true},
+		{"02:00-06:00", date(2026, 10, 18, 6, 0), false},
+		{"sat,sun 10:00-18:00", date(2026, 10, 18, 12, 0), true},
+		{"sat,sun 10:00-18:00", date(2026, 10, 19, 12, 0), false},
+		{"mon-fri 18:00-24:00", date(2026, 10, 16, 23, 59), true},
+		{"mon-fri 18:00-24:00", date(2026, 10, 17, 0, 0), false},
+		{"7 10:00-11:00", date(2026, 10, 18, 10, 30), true},
+		{"22:00-02:00", date(2026, 10, 18, 21, 59), false},
+		{"22:00-02:00", date(2026, 10, 18, 23, 0), true},
+		{"22:00-02:00", date(2026, 10, 19, 1, 59), true},
+		{"22:00-02:00", date(2026, 10, 19, 2, 0), false},
+		// Wraps past midnight: Saturday morning belongs to the Friday window
+		{"fri 22:00-02:00", date(2026, 10, 16, 23, 0), true},
+		{"fri 22:00-02:00", date(2026, 10, 17, 1, 0), true},
+		{"fri 22:00-02:00", date(2026, 10, 17, 23, 0), false},
+		{"fri 22:00-02:00", date(2026, 10, 16, 1, 0), false},
+		// Sunday evening wraps to Monday morning
+		{"sun 23:00-01:00", date(2026, 10, 19, 0, 30), true},
+		{"sun 23:00-01:00", date(2026, 10, 20, 0, 30), false},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseWindow(%q) error: %v", tt.spec, err)
+		}
+		if got := w.Contains(tt.at); got != tt.want {
+			t.Errorf("%q: Contains(%s) = %v, want %v", tt.spec, tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,659 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/Celedhrim/btcleaner/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// apiV1Prefix is the path of the versioned REST API, relative to the webroot
+const apiV1Prefix = "/api/v1"
+
+// Error codes returned in the JSON error envelope
+const (
+	ErrCodeBadRequest       = "bad_request"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeInternal         = "internal_error"
+	ErrCodeConflict         = "conflict"
+)
+
+// errTorrentNotFound is returned when a torrent does not exist in Transmission
+var errTorrentNotFound = errors.New("torrent not found")
+
+// APIError describes an error returned by the API
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the JSON envelope of every API error
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// TorrentsResponse is returned by GET /api/v1/torrents
+type TorrentsResponse struct {
+	Torrents []models.Torrent `json:"torrents"`
+	Count    int              `json:"count"`
+}
+
+// CandidatesResponse is returned by GET /api/v1/candidates
+type CandidatesResponse struct {
+	Candidates     []*models.Torrent `json:"candidates"`
+	Count          int               `json:"count"`
+	TotalSizeBytes int64             `json:"total_size_bytes"`
+}
+
+// HistoryResponse is returned by GET /api/v1/history
+type HistoryResponse struct {
+	History []cleaner.DeletedTorrent `json:"history"`
+}
+
+// RunsResponse is returned by GET /api/v1/runs
+type RunsResponse struct {
+	Runs  []cleaner.RunSummary `json:"runs"`
+	Count int                  `json:"count"`
+}
+
+// SpaceHistoryResponse is returned by GET /api/v1/stats/history
+type SpaceHistoryResponse struct {
+	Samples []cleaner.SpaceSample `json:"samples"` // Oldest first
+	Trend   cleaner.SpaceTrend    `json:"trend"`
+}
+
+// LogsResponse is returned by GET /api/v1/logs
+type LogsResponse struct {
+	Logs       []logger.LogEntry `json:"logs"`        // Oldest first
+	BufferSize int               `json:"buffer_size"` // Entries kept in memory
+}
+
+// DeleteResponse is returned by DELETE /api/v1/torrents/{id}
+type DeleteResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+	Deleted   bool   `json:"deleted"`
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error envelope
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: APIError{Code: code, Message: message}})
+}
+
+// requireMethod writes a 405 error and returns false if the request method is not allowed
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return false
+	}
+	return true
+}
+
+// registerAPIv1 registers the versioned API handlers
+func (s *Server) registerAPIv1(mux *http.ServeMux, prefix string) {
+	base := prefix + apiV1Prefix
+	mux.HandleFunc(base+"/stats", s.protect(s.handleV1Stats))
+	mux.HandleFunc(base+"/stats/history", s.protect(s.handleV1StatsHistory))
+	mux.HandleFunc(base+"/torrents", s.protect(s.handleV1Torrents))
+	mux.HandleFunc(base+"/torrents/delete", s.protect(s.handleV1BulkDelete))
+	mux.HandleFunc(base+"/torrents/{id}", s.protect(s.handleV1Torrent))
+	mux.HandleFunc(base+"/cleanup/run", s.protect(s.handleV1CleanupRun))
+	mux.HandleFunc(base+"/simulate", s.requireRole(config.RoleViewer, s.handleV1Simulate))
+	mux.HandleFunc(base+"/runs", s.protect(s.handleV1Runs))
+	mux.HandleFunc(base+"/runs/{id}", s.protect(s.handleV1Run))
+	mux.HandleFunc(base+"/candidates", s.protect(s.handleV1Candidates))
+	mux.HandleFunc(base+"/history", s.protect(s.handleV1History))
+	mux.HandleFunc(base+"/logs", s.protect(s.handleV1Logs))
+	mux.HandleFunc(base+"/config", s.protect(s.handleV1Config))
+	mux.HandleFunc(base+"/events", s.protect(s.handleV1Events))
+	mux.HandleFunc(base+"/auth/login", s.csrfProtect(s.handleLogin))
+	mux.HandleFunc(base+"/auth/logout", s.csrfProtect(s.handleLogout))
+	mux.HandleFunc(base+"/auth/me", s.requireRole(config.RoleViewer, s.handleMe))
+	mux.HandleFunc(base+"/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc(base+"/", s.handleV1NotFound)
+}
+
+// deprecated marks a legacy endpoint as deprecated in favour of its /api/v1 successor
+func (s *Server) deprecated(successor string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", s.apiPath(successor)))
+		handler(w, r)
+	}
+}
+
+// apiPath returns the absolute path of an API v1 endpoint, including the webroot
+func (s *Server) apiPath(endpoint string) string {
+	return strings.TrimSuffix(s.webRoot, "/") + apiV1Prefix + endpoint
+}
+
+// handleV1Stats returns current statistics
+func (s *Server) handleV1Stats(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	stats, err := s.cleaner.GetStats()
+	if err != nil {
+		s.logger.Errorf("Failed to get stats: %v", err)
+		writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to get stats from Transmission")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleV1StatsHistory returns the free space samples of the checks and the
+// fill rate. The optional since parameter is a duration ("6h") restricting
+// the samples to the most recent ones.
+func (s *Server) handleV1StatsHistory(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var since time.Time
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		d, err := time.ParseDuration(sinceStr)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid since (expected a duration such as 6h)")
+			return
+		}
+		since = time.Now().Add(-d)
+	}
+
+	writeJSON(w, http.StatusOK, SpaceHistoryResponse{
+		Samples: s.cleaner.SpaceHistory(since),
+		Trend:   s.cleaner.SpaceTrend(),
+	})
+}
+
+// handleV1Torrents returns the list of torrents
+func (s *Server) handleV1Torrents(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	torrents, err := s.client.GetTorrents()
+	if err != nil {
+		s.logger.Errorf("Failed to get torrents: %v", err)
+		writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to get torrents from Transmission")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TorrentsResponse{Torrents: torrents, Count: len(torrents)})
+}
+
+// handleV1Torrent returns or deletes a single torrent
+func (s *Server) handleV1Torrent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid torrent id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		torrent, err := s.findTorrent(id)
+		if errors.Is(err, errTorrentNotFound) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Torrent %d not found", id))
+			return
+		}
+		if err != nil {
+			s.logger.WithField(logger.FieldTorrentID, id).Errorf("Failed to get torrent %d: %v", id, err)
+			writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to get torrent from Transmission")
+			return
+		}
+		writeJSON(w, http.StatusOK, torrent)
+
+	case http.MethodDelete:
+		torrent, err := s.deleteTorrent(id, true)
+		if errors.Is(err, errTorrentNotFound) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Torrent %d not found", id))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to delete torrent")
+			return
+		}
+		writeJSON(w, http.StatusOK, DeleteResponse{
+			ID:        torrent.ID,
+			Name:      torrent.Name,
+			SizeBytes: torrent.SizeBytes,
+			Deleted:   true,
+		})
+
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleV1Candidates returns the torrents that would be deleted by a cleanup
+func (s *Server) handleV1Candidates(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	candidates, err := s.cleaner.GetCandidates()
+	if err != nil {
+		s.logger.Errorf("Failed to get candidates: %v", err)
+		writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to get candidates")
+		return
+	}
+
+	resp := CandidatesResponse{Candidates: candidates, Count: len(candidates)}
+	for _, t := range candidates {
+		resp.TotalSizeBytes += t.TotalSize
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleV1History returns the deletion history
+func (s *Server) handleV1History(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, HistoryResponse{History: s.cleaner.GetHistory()})
+}
+
+// handleV1Logs returns the in-memory logs, filtered by the query parameters
+// of parseLogFilter
+func (s *Server) handleV1Logs(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LogsResponse{
+		Logs:       s.logger.Query(filter),
+		BufferSize: s.logger.BufferSize(),
+	})
+}
+
+// parseLogFilter reads a log filter from the query parameters: level, a
+// comma-separated list of levels; since, a duration ("15m") or an RFC 3339
+// time; q, a text searched in the messages and fields; and limit, the number
+// of most recent entries
+func parseLogFilter(r *http.Request) (logger.LogFilter, error) {
+	var filter logger.LogFilter
+	query := r.URL.Query()
+
+	if levels := query.Get("level"); levels != "" {
+		for _, name := range strings.Split(levels, ",") {
+			level, err := logrus.ParseLevel(strings.TrimSpace(name))
+			if err != nil {
+				return filter, fmt.Errorf("Invalid level %q (expected debug, info, warn or error)", name)
+			}
+			filter.Levels = append(filter.Levels, level)
+		}
+	}
+
+	if since := query.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else {
+			return filter, errors.New("Invalid since (expected a duration such as 15m or an RFC 3339 time)")
+		}
+	}
+
+	filter.Query = query.Get("q")
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return filter, errors.New("Invalid limit")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// handleV1NotFound returns a JSON error for unknown API endpoints
+func (s *Server) handleV1NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, ErrCodeNotFound, "Unknown API endpoint")
+}
+
+// findTorrent returns the torrent with the given ID
+func (s *Server) findTorrent(id int) (*models.Torrent, error) {
+	torrents, err := s.client.GetTorrentsByIDs([]int{id}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range torrents {
+		if torrents[i].ID == id {
+			return &torrents[i], nil
+		}
+	}
+
+	return nil, errTorrentNotFound
+}
+
+// deleteTorrent removes a torrent (and optionally its data) and records it in history
+func (s *Server) deleteTorrent(id int, deleteData bool) (*cleaner.DeleteResult, error) {
+	results, err := s.cleaner.DeleteTorrents([]int{id}, nil, deleteData)
+	if err != nil {
+		s.logger.WithField(logger.FieldTorrentID, id).Errorf("Failed to delete torrent %d: %v", id, err)
+		return nil, err
+	}
+
+	result := &results[0]
+	switch result.Status {
+	case cleaner.DeleteStatusNotFound:
+		return nil, errTorrentNotFound
+	case cleaner.DeleteStatusFailed:
+		return nil, errors.New(result.Error)
+	}
+
+	return result, nil
+}
+
+// BulkDeleteRequest is the body of POST /api/v1/torrents/delete
+type BulkDeleteRequest struct {
+	IDs        []int    `json:"ids"`
+	Hashes     []string `json:"hashes"`
+	DeleteData *bool    `json:"delete_data,omitempty"` // Defaults to true
+}
+
+// BulkDeleteResponse is returned by POST /api/v1/torrents/delete
+type BulkDeleteResponse struct {
+	Results    []cleaner.DeleteResult `json:"results"`
+	Deleted    int                    `json:"deleted"`
+	NotFound   int                    `json:"not_found"`
+	Failed     int                    `json:"failed"`
+	FreedBytes int64                  `json:"freed_bytes"`
+}
+
+// maxBulkDelete limits the number of torrents deleted by one request
+const maxBulkDelete = 1000
+
+// handleV1BulkDelete deletes several torrents with a single Transmission call
+func (s *Server) handleV1BulkDelete(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req BulkDeleteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
+		return
+	}
+
+	count := len(req.IDs) + len(req.Hashes)
+	if count == 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "No torrent ids or hashes given")
+		return
+	}
+	if count > maxBulkDelete {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Too many torrents (max %d)", maxBulkDelete))
+		return
+	}
+
+	deleteData := true
+	if req.DeleteData != nil {
+		deleteData = *req.DeleteData
+	}
+
+	results, err := s.cleaner.DeleteTorrents(req.IDs, req.Hashes, deleteData)
+	if err != nil {
+		s.logger.Errorf("Bulk delete failed: %v", err)
+		writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to get torrents from Transmission")
+		return
+	}
+
+	resp := BulkDeleteResponse{Results: results}
+	freed := make(map[int]bool)
+	for _, res := range results {
+		switch res.Status {
+		case cleaner.DeleteStatusDeleted:
+			resp.Deleted++
+			if !freed[res.ID] {
+				freed[res.ID] = true
+				resp.FreedBytes += res.SizeBytes
+			}
+		case cleaner.DeleteStatusNotFound:
+			resp.NotFound++
+		case cleaner.DeleteStatusFailed:
+			resp.Failed++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CleanupRunRequest is the body of POST /api/v1/cleanup/run. All fields are optional.
+type CleanupRunRequest struct {
+	DryRun       *bool       `json:"dry_run,omitempty"`
+	MinFreeSpace interface{} `json:"min_free_space,omitempty"` // Bytes or size with unit ("500GB")
+}
+
+// CleanupRunResponse is returned by POST /api/v1/cleanup/run
+type CleanupRunResponse struct {
+	RunID  string `json:"run_id"`
+	Status string `json:"status"` // "started", or "queued" behind a run in progress (a run already queued is shared)
+}
+
+// CleanupResultMessage is sent to WebSocket clients when a cleanup run finishes
+type CleanupResultMessage struct {
+	Type   string                 `json:"type"` // Always "cleanup_result"
+	RunID  string                 `json:"run_id"`
+	Result *cleaner.CleanupResult `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// parseSizeValue parses a JSON size given as a number of bytes or a string with unit
+func parseSizeValue(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case float64:
+		if val < 0 || val != float64(int64(val)) {
+			return 0, fmt.Errorf("invalid size: %v", val)
+		}
+		return int64(val), nil
+	case string:
+		return config.ParseSize(val)
+	default:
+		return 0, fmt.Errorf("invalid size: %v", val)
+	}
+}
+
+// handleV1CleanupRun starts a cleanup run in the background and returns its ID.
+// The result is sent to the WebSocket clients when the run finishes.
+func (s *Server) handleV1CleanupRun(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req CleanupRunRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
+		return
+	}
+
+	opts := cleaner.RunOptions{ID: cleaner.NewRunID(), Trigger: cleaner.TriggerAPI, DryRun: req.DryRun}
+	if req.MinFreeSpace != nil {
+		size, err := parseSizeValue(req.MinFreeSpace)
+		if err != nil || size <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid min_free_space")
+			return
+		}
+		opts.MinFreeSpace = &size
+	}
+
+	p := principalFromContext(r.Context())
+
+	// Requests made while a run waits share it, rather than piling up behind
+	// the run in progress
+	s.queueMutex.Lock()
+	if queued := s.queuedRun; queued != nil {
+		s.queueMutex.Unlock()
+		if !sameRunOverrides(*queued, opts) {
+			writeError(w, http.StatusConflict, ErrCodeConflict,
+				fmt.Sprintf("Cleanup run %s is already queued with other options", queued.ID))
+			return
+		}
+		s.logger.WithField(logger.FieldRunID, queued.ID).Infof("Cleanup run %s requested again by %s", queued.ID, p.Name)
+		writeJSON(w, http.StatusAccepted, CleanupRunResponse{RunID: queued.ID, Status: "queued"})
+		return
+	}
+	status := "started"
+	if s.cleaner.Running() {
+		status = "queued"
+	}
+	s.queuedRun = &opts
+	opts.Started = func() {
+		s.queueMutex.Lock()
+		s.queuedRun = nil
+		s.queueMutex.Unlock()
+	}
+	s.queueMutex.Unlock()
+
+	log := s.logger.WithField(logger.FieldRunID, opts.ID)
+	log.Infof("Cleanup run %s requested by %s", opts.ID, p.Name)
+
+	go func() {
+		msg := CleanupResultMessage{Type: "cleanup_result", RunID: opts.ID}
+		result, err := s.cleaner.RunWithOptions(opts)
+		if err != nil {
+			log.Errorf("Cleanup run %s failed: %v", opts.ID, err)
+			msg.Error = err.Error()
+		} else {
+			msg.Result = result
+		}
+		s.broadcast(msg)
+	}()
+
+	writeJSON(w, http.StatusAccepted, CleanupRunResponse{RunID: opts.ID, Status: status})
+}
+
+// sameRunOverrides reports whether two runs override the same settings
+func sameRunOverrides(a, b cleaner.RunOptions) bool {
+	sameDryRun := (a.DryRun == nil) == (b.DryRun == nil) && (a.DryRun == nil || *a.DryRun == *b.DryRun)
+	sameSize := (a.MinFreeSpace == nil) == (b.MinFreeSpace == nil) && (a.MinFreeSpace == nil || *a.MinFreeSpace == *b.MinFreeSpace)
+	return sameDryRun && sameSize
+}
+
+// handleV1Runs returns the recorded cleanup runs, newest first
+func (s *Server) handleV1Runs(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	runs := s.cleaner.GetRuns()
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid limit")
+			return
+		}
+		if limit < len(runs) {
+			runs = runs[:limit]
+		}
+	}
+
+	writeJSON(w, http.StatusOK, RunsResponse{Runs: runs, Count: len(runs)})
+}
+
+// handleV1Run returns the full record of a cleanup run
+func (s *Server) handleV1Run(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	id := r.PathValue("id")
+	record, ok := s.cleaner.GetRun(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Run %s not found", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// SimulateRequest is the body of POST /api/v1/simulate. Unset fields use the current policy.
+type SimulateRequest struct {
+	MinFreeSpace          interface{}    `json:"min_free_space,omitempty"` // Bytes or size with unit ("500GB")
+	MinTorrentsPerTracker *int           `json:"min_torrents_per_tracker,omitempty"`
+	TrackerMinimums       map[string]int `json:"tracker_minimums,omitempty"`
+	Strategy              string         `json:"strategy,omitempty"`
+	Predictive            *bool          `json:"predictive,omitempty"`
+	IncomingSize          interface{}    `json:"incoming_size,omitempty"` // Bytes or size with unit
+}
+
+// handleV1Simulate computes what a cleanup would remove with hypothetical settings.
+// It only reads from Transmission, so the viewer role is enough.
+func (s *Server) handleV1Simulate(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req SimulateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 65536)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
+		return
+	}
+
+	params := cleaner.SimulationParams{
+		MinTorrentsPerTracker: req.MinTorrentsPerTracker,
+		TrackerMinimums:       req.TrackerMinimums,
+		Strategy:              req.Strategy,
+		Predictive:            req.Predictive,
+	}
+	if req.MinFreeSpace != nil {
+		size, err := parseSizeValue(req.MinFreeSpace)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid min_free_space")
+			return
+		}
+		params.MinFreeSpace = &size
+	}
+	if req.IncomingSize != nil {
+		size, err := parseSizeValue(req.IncomingSize)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid incoming_size")
+			return
+		}
+		params.IncomingSize = size
+	}
+
+	result, err := s.cleaner.Simulate(params)
+	if errors.Is(err, cleaner.ErrInvalidPolicy) {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("Simulation failed: %v", err)
+		writeError(w, http.StatusBadGateway, ErrCodeInternal, "Failed to get torrents from Transmission")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,408 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionCookieName is the name of the web UI session cookie
+const sessionCookieName = "btcleaner_session"
+
+// Error codes returned by authentication
+const (
+	ErrCodeUnauthorized = "unauthorized"
+	ErrCodeForbidden    = "forbidden"
+)
+
+// Authentication methods reported in principals
+const (
+	authMethodNone    = "none"
+	authMethodSession = "session"
+	authMethodToken   = "token"
+	authMethodHeader  = "trusted_header"
+)
+
+// dummyHash is compared against when the username is unknown, so that the
+// response time does not reveal which usernames exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("btcleaner"), bcrypt.DefaultCost)
+
+// principal is the identity attached to an authenticated request
+type principal struct {
+	Name      string
+	Role      string
+	Method    string
+	SessionID string
+}
+
+// canOperate reports whether the principal may perform state-changing actions
+func (p *principal) canOperate() bool {
+	return p.Role == config.RoleOperator
+}
+
+// hasRole reports whether the principal has at least the given role
+func (p *principal) hasRole(role string) bool {
+	return role == config.RoleViewer || p.canOperate()
+}
+
+type principalKey struct{}
+
+// principalFromContext returns the principal stored in the request context
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+	return p
+}
+
+// session is a logged-in web UI session
+type session struct {
+	username string
+	role     string
+	expires  time.Time
+}
+
+// authenticator authenticates requests from sessions, API tokens and trusted headers
+type authenticator struct {
+	cfg         config.AuthConfig
+	trustedNets []*net.IPNet
+	sessions    map[string]*session
+	mu          sync.Mutex
+}
+
+// newAuthenticator creates an authenticator from the server configuration
+func newAuthenticator(cfg config.ServerConfig) (*authenticator, error) {
+	nets, err := config.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authenticator{
+		cfg:         cfg.Auth,
+		trustedNets: nets,
+		sessions:    make(map[string]*session),
+	}, nil
+}
+
+// fromTrustedProxy reports whether the request comes directly from a trusted reverse proxy
+func (a *authenticator) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.trustedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate returns the principal of the request, or nil if it is not authenticated
+func (a *authenticator) authenticate(r *http.Request) *principal {
+	if !a.cfg.Enabled {
+		return &principal{Name: "anonymous", Role: config.RoleOperator, Method: authMethodNone}
+	}
+
+	// API token
+	if authz := r.Header.Get("Authorization"); strings.HasPrefix(authz, "Bearer ") {
+		return a.checkToken(strings.TrimSpace(strings.TrimPrefix(authz, "Bearer ")))
+	}
+
+	// Session cookie
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if p := a.checkSession(cookie.Value); p != nil {
+			return p
+		}
+	}
+
+	// Trusted header from SSO proxy
+	th := a.cfg.TrustedHeader
+	if th.Enabled && a.fromTrustedProxy(r) {
+		if user := r.Header.Get(th.UserHeader); user != "" {
+			return &principal{Name: user, Role: a.headerRole(r), Method: authMethodHeader}
+		}
+	}
+
+	return nil
+}
+
+// headerRole maps the groups passed by the SSO proxy to a role
+func (a *authenticator) headerRole(r *http.Request) string {
+	th := a.cfg.TrustedHeader
+	if th.GroupsHeader != "" {
+		for _, group := range strings.Split(r.Header.Get(th.GroupsHeader), ",") {
+			group = strings.TrimSpace(group)
+			for _, og := range th.OperatorGroups {
+				if group != "" && group == og {
+					return config.RoleOperator
+				}
+			}
+		}
+	}
+	return th.DefaultRole
+}
+
+// checkToken validates an API token against the configured SHA-256 hashes
+func (a *authenticator) checkToken(token string) *principal {
+	if token == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(token))
+	for _, t := range a.cfg.Tokens {
+		expected, err := hex.DecodeString(t.TokenSHA256.Value())
+		if err != nil {
+			continue
+		}
+		if subtle.ConstantTimeCompare(sum[:], expected) == 1 {
+			name := t.Name
+			if name == "" {
+				name = "token"
+			}
+			return &principal{Name: name, Role: t.Role, Method: authMethodToken}
+		}
+	}
+	return nil
+}
+
+// checkSession returns the principal of a valid session
+func (a *authenticator) checkSession(id string) *principal {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess, ok := a.sessions[id]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(sess.expires) {
+		delete(a.sessions, id)
+		return nil
+	}
+	return &principal{Name: sess.username, Role: sess.role, Method: authMethodSession, SessionID: id}
+}
+
+// login checks a username and password and opens a new session
+func (a *authenticator) login(username, password string) (string, *session, bool) {
+	var user *config.UserConfig
+	for i := range a.cfg.Users {
+		if a.cfg.Users[i].Username == username {
+			user = &a.cfg.Users[i]
+			break
+		}
+	}
+
+	if user == nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.Value()), []byte(password)) != nil {
+		return "", nil, false
+	}
+
+	id, err := randomToken()
+	if err != nil {
+		return "", nil, false
+	}
+
+	sess := &session{
+		username: user.Username,
+		role:     user.Role,
+		expires:  time.Now().Add(a.cfg.SessionTTL),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.purgeExpiredLocked()
+	a.sessions[id] = sess
+
+	return id, sess, true
+}
+
+// logout closes a session
+func (a *authenticator) logout(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, id)
+}
+
+// purgeExpiredLocked removes expired sessions (caller must hold a.mu)
+func (a *authenticator) purgeExpiredLocked() {
+	now := time.Now()
+	for id, sess := range a.sessions {
+		if now.After(sess.expires) {
+			delete(a.sessions, id)
+		}
+	}
+}
+
+// randomToken returns a random URL-safe token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isSecureRequest reports whether the client reached us over HTTPS
+func (s *Server) isSecureRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return s.auth.fromTrustedProxy(r) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// protect requires authentication for a handler. Safe methods (GET, HEAD)
+// need the viewer role, other methods need the operator role.
+func (s *Server) protect(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := config.RoleOperator
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			role = config.RoleViewer
+		}
+		s.requireRole(role, handler)(w, r)
+	}
+}
+
+// requireRole requires an authenticated principal with at least the given role
+func (s *Server) requireRole(role string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := s.auth.authenticate(r)
+		if p == nil {
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication required")
+			return
+		}
+		if !p.hasRole(role) {
+			writeError(w, http.StatusForbidden, ErrCodeForbidden, "The "+role+" role is required")
+			return
+		}
+		if err := s.verifyCSRF(r, p); err != nil {
+			s.logger.Warnf("Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			writeError(w, http.StatusForbidden, ErrCodeCSRF, "CSRF validation failed")
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// LoginRequest is the body of POST /api/v1/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AuthInfo is returned by GET /api/v1/auth/me and POST /api/v1/auth/login
+type AuthInfo struct {
+	AuthEnabled bool   `json:"auth_enabled"`
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	Method      string `json:"method"`
+}
+
+// handleLogin authenticates a local user and sets the session cookie
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	if !s.auth.cfg.Enabled || len(s.auth.cfg.Users) == 0 {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Local login is not enabled")
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid login request")
+		return
+	}
+
+	id, sess, ok := s.auth.login(req.Username, req.Password)
+	if !ok {
+		s.logger.Warnf("Failed login attempt for user %q from %s", req.Username, r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Invalid username or password")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     s.htmlWebRoot(),
+		Expires:  sess.expires,
+		HttpOnly: true,
+		Secure:   s.isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	s.logger.Infof("User %s logged in", sess.username)
+
+	writeJSON(w, http.StatusOK, AuthInfo{
+		AuthEnabled: true,
+		Username:    sess.username,
+		Role:        sess.role,
+		Method:      authMethodSession,
+	})
+}
+
+// handleLogout closes the current session and clears the cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.auth.logout(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     s.htmlWebRoot(),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe returns the identity of the current user
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	p := principalFromContext(r.Context())
+	writeJSON(w, http.StatusOK, AuthInfo{
+		AuthEnabled: s.auth.cfg.Enabled,
+		Username:    p.Name,
+		Role:        p.Role,
+		Method:      p.Method,
+	})
+}
+
+// handleLoginPage serves the login form
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	if !s.auth.cfg.Enabled {
+		http.Redirect(w, r, s.webRoot, http.StatusFound)
+		return
+	}
+
+	csrfToken := s.ensureCSRFToken(w, r)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(s.generateLoginHTML(s.htmlWebRoot(), csrfToken)))
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CSRF token cookie and header names
+const (
+	csrfCookieName = "btcleaner_csrf"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// ErrCodeCSRF is returned when a state-changing request fails CSRF validation
+const ErrCodeCSRF = "csrf_failed"
+
+// isSafeMethod reports whether the HTTP method does not change server state
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// normalizeOrigin returns the scheme://host[:port] part of a URL, lower-cased
+func normalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid origin: %s", raw)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// parseAllowedOrigins normalizes the configured origin allowlist
+func parseAllowedOrigins(origins []string) (map[string]bool, error) {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		n, err := normalizeOrigin(o)
+		if err != nil {
+			return nil, err
+		}
+		allowed[n] = true
+	}
+	return allowed, nil
+}
+
+// ensureCSRFToken returns the CSRF token of the browser, setting a new cookie if needed.
+// The token is embedded in the HTML pages and sent back in the X-CSRF-Token header
+// (double-submit cookie): a foreign site can neither read the page nor the cookie.
+func (s *Server) ensureCSRFToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && len(cookie.Value) >= 32 {
+		return cookie.Value
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		s.logger.Errorf("Failed to generate CSRF token: %v", err)
+		return ""
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     s.htmlWebRoot(),
+		HttpOnly: true,
+		Secure:   s.isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return token
+}
+
+// expectedOrigins returns the origins under which the server is reached for this request
+func (s *Server) expectedOrigins(r *http.Request) []string {
+	scheme := "http"
+	if s.isSecureRequest(r) {
+		scheme = "https"
+	}
+
+	origins := []string{strings.ToLower(scheme + "://" + r.Host)}
+	if s.auth.fromTrustedProxy(r) {
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			host = strings.TrimSpace(strings.Split(host, ",")[0])
+			origins = append(origins, strings.ToLower(scheme+"://"+host))
+		}
+	}
+	return origins
+}
+
+// originAllowed reports whether a normalized origin is the server itself or in the allowlist
+func (s *Server) originAllowed(origin string, r *http.Request) bool {
+	if s.allowedOrigins[origin] {
+		return true
+	}
+	for _, expected := range s.expectedOrigins(r) {
+		if origin == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequestOrigin validates the Origin header, falling back to the Referer.
+// Requests with neither header do not come from a browser page and are accepted;
+// browsers always send Origin on cross-origin POST, PUT and DELETE requests.
+func (s *Server) checkRequestOrigin(r *http.Request) error {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		n, err := normalizeOrigin(origin)
+		if err != nil || !s.originAllowed(n, r) {
+			return fmt.Errorf("origin %s is not allowed", origin)
+		}
+		return nil
+	}
+
+	if referer := r.Header.Get("Referer"); referer != "" {
+		u, err := url.Parse(referer)
+		if err != nil {
+			return fmt.Errorf("invalid referer")
+		}
+		n, err := normalizeOrigin(referer)
+		if err != nil || !s.originAllowed(n, r) {
+			return fmt.Errorf("referer %s is not allowed", referer)
+		}
+		// Behind a reverse proxy the UI lives under the webroot
+		if !strings.HasPrefix(u.Path, s.htmlWebRoot()) {
+			return fmt.Errorf("referer %s is outside of the webroot", referer)
+		}
+	}
+
+	return nil
+}
+
+// verifyCSRF validates a state-changing request. API token requests are exempt
+// since browsers never attach bearer tokens on their own.
+func (s *Server) verifyCSRF(r *http.Request, p *principal) error {
+	if isSafeMethod(r.Method) {
+		return nil
+	}
+	if p != nil && p.Method == authMethodToken {
+		return nil
+	}
+
+	if err := s.checkRequestOrigin(r); err != nil {
+		return err
+	}
+
+	// Browsers holding a session or CSRF cookie must present the token
+	cookie, err := r.Cookie(csrfCookieName)
+	hasSession := false
+	if _, serr := r.Cookie(sessionCookieName); serr == nil {
+		hasSession = true
+	}
+	browser := err == nil || hasSession || r.Header.Get("Origin") != "" || r.Header.Get("Referer") != ""
+	if !browser {
+		return nil
+	}
+
+	if err != nil || cookie.Value == "" {
+		return fmt.Errorf("missing CSRF cookie")
+	}
+	token := r.Header.Get(csrfHeaderName)
+	if token == "" {
+		return fmt.Errorf("missing %s header", csrfHeaderName)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) != 1 {
+		return fmt.Errorf("invalid CSRF token")
+	}
+
+	return nil
+}
+
+// csrfProtect validates CSRF for handlers that are not behind requireRole (login, logout)
+func (s *Server) csrfProtect(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.verifyCSRF(r, nil); err != nil {
+			s.logger.Warnf("Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			writeError(w, http.StatusForbidden, ErrCodeCSRF, "CSRF validation failed")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// checkWebSocketOrigin validates the Origin of WebSocket handshakes.
+// Non-browser clients do not send an Origin header and are accepted.
+func (s *Server) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	n, err := normalizeOrigin(origin)
+	if err != nil || !s.originAllowed(n, r) {
+		s.logger.Warnf("Rejected WebSocket connection from origin %s", origin)
+		return false
+	}
+	return true
+}
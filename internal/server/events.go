@@ -0,0 +1,278 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/events"
+)
+
+const (
+	// eventBufferSize is the number of events queued per subscriber
+	eventBufferSize = 64
+	// eventPollInterval is how often free space and candidates are checked for changes
+	eventPollInterval = 15 * time.Second
+	// eventKeepAlive is the interval of SSE keep-alive comments
+	eventKeepAlive = 30 * time.Second
+)
+
+// SetEventBus sets the bus whose events are streamed to clients
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
+
+// parseTopics parses a comma separated list of event topics, empty for all topics
+func parseTopics(value string) ([]string, error) {
+	var topics []string
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !validTopic(t) {
+			return nil, fmt.Errorf("unknown event topic %q", t)
+		}
+		topics = append(topics, t)
+	}
+	return topics, nil
+}
+
+// validTopic reports whether t is a known topic or matches one as a "prefix.*" wildcard
+func validTopic(t string) bool {
+	for _, topic := range events.Topics {
+		if t == topic || (strings.HasSuffix(t, ".*") && strings.HasPrefix(topic, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleV1Events streams events as Server-Sent Events
+func (s *Server) handleV1Events(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	if s.events == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeInternal, "Events are not available")
+		return
+	}
+
+	topics, err := parseTopics(r.URL.Query().Get("topics"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Streaming not supported")
+		return
+	}
+
+	// Subscribe before replaying so that no event is missed in between
+	sub := s.events.Subscribe(topics, eventBufferSize)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+
+	// Replay the events missed by a reconnecting client
+	var lastID uint64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		lastID, _ = strconv.ParseUint(id, 10, 64)
+		for _, event := range s.events.Since(lastID, topics) {
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+			lastID = event.ID
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event := <-sub.C():
+			if event.ID <= lastID {
+				continue // Already replayed
+			}
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeSSE writes an event in the Server-Sent Events format
+func writeSSE(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Topic, data)
+	return err
+}
+
+// handleWebSocketEvents streams events over a WebSocket connection
+func (s *Server) handleWebSocketEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		http.Error(w, "Events are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	topics, err := parseTopics(r.URL.Query().Get("topics"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Errorf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	client := s.addWSClient(conn, false, nil)
+	sub := s.events.Subscribe(topics, eventBufferSize)
+
+	// Forward the events to the client queue until it is closed
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case event := <-sub.C():
+				if msg, err := json.Marshal(event); err == nil {
+					client.enqueue(msg)
+				}
+			case <-client.done:
+				return
+			}
+		}
+	}()
+
+	s.readPump(client)
+	s.removeWSClient(client)
+}
+
+// watchChanges publishes space.changed and candidates.changed events.
+// Free space and candidates are checked periodically and right after a cleanup
+// or deletion. Candidates, which need the full torrent list, are only checked
+// while someone listens.
+func (s *Server) watchChanges() {
+	if s.events == nil {
+		return
+	}
+
+	triggers := s.events.Subscribe([]string{events.TopicCleanupFinished, events.TopicTorrentDeleted}, eventBufferSize)
+	defer triggers.Close()
+
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	var lastFreeSpace int64 = -1
+	var lastCandidates []int
+	check := func() {
+		lastFreeSpace = s.checkSpaceChanged(lastFreeSpace)
+		if s.events.HasSubscribers(events.TopicCandidatesChanged) {
+			lastCandidates = s.checkCandidatesChanged(lastCandidates)
+		} else {
+			lastCandidates = nil
+		}
+	}
+	check()
+
+	for {
+		select {
+		case <-ticker.C:
+			check()
+		case <-triggers.C():
+			// Wait for the burst of deletions of a run to end
+			time.Sleep(time.Second)
+			for len(triggers.C()) > 0 {
+				<-triggers.C()
+			}
+			check()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// checkSpaceChanged publishes space.changed if the free space differs from last.
+// It returns the current free space.
+func (s *Server) checkSpaceChanged(last int64) int64 {
+	freeSpace, err := s.client.GetFreeSpace()
+	if err != nil {
+		s.logger.Debugf("Failed to check free space for events: %v", err)
+		return last
+	}
+	if freeSpace != last && last >= 0 {
+		minFreeSpace := s.cleaner.Policy().MinFreeSpace
+		s.events.Publish(events.TopicSpaceChanged, events.SpaceChanged{
+			FreeSpace:         freeSpace,
+			PreviousFreeSpace: last,
+			MinFreeSpace:      minFreeSpace,
+			NeedsCleanup:      freeSpace < minFreeSpace,
+		})
+	}
+	return freeSpace
+}
+
+// checkCandidatesChanged publishes candidates.changed if the cleanup candidates
+// differ from last. It returns the current candidate IDs.
+func (s *Server) checkCandidatesChanged(last []int) []int {
+	candidates, err := s.cleaner.GetCandidates()
+	if err != nil {
+		s.logger.Debugf("Failed to check candidates for events: %v", err)
+		return last
+	}
+
+	ids := make([]int, 0, len(candidates))
+	var totalSize int64
+	for _, t := range candidates {
+		ids = append(ids, t.ID)
+		totalSize += t.TotalSize
+	}
+	sort.Ints(ids)
+
+	if last != nil && !equalIDs(ids, last) {
+		s.events.Publish(events.TopicCandidatesChanged, events.CandidatesChanged{
+			IDs:            ids,
+			Count:          len(ids),
+			TotalSizeBytes: totalSize,
+		})
+	}
+	return ids
+}
+
+// equalIDs reports whether two sorted ID lists are equal
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// readyTransmissionTimeout bounds the Transmission check of /readyz
+	readyTransmissionTimeout = 5 * time.Second
+	// readyCacheTTL is how long a /readyz result is reused, the endpoint
+	// being unauthenticated
+	readyCacheTTL = 5 * time.Second
+)
+
+// Readiness statuses
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// HealthResponse is returned by /healthz
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+// ReadinessStatus is returned by /readyz to unauthenticated callers
+type ReadinessStatus struct {
+	Status string `json:"status"`
+}
+
+// ReadinessResponse is returned by /readyz to authenticated callers
+type ReadinessResponse struct {
+	Status       string            `json:"status"`
+	Transmission TransmissionCheck `json:"transmission"`
+	LastCheck    LastCheckStatus   `json:"last_check"`
+	Config       ConfigCheck       `json:"config"`
+}
+
+// TransmissionCheck reports whether Transmission answers
+type TransmissionCheck struct {
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// LastCheckStatus reports the age of the last successful cleanup check
+type LastCheckStatus struct {
+	OK            bool       `json:"ok"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	AgeSeconds    float64    `json:"age_seconds,omitempty"`
+	MaxAgeSeconds float64    `json:"max_age_seconds,omitempty"` // Unset when the age is not checked
+	Error         string     `json:"error,omitempty"`
+}
+
+// ConfigCheck reports whether the active configuration is valid
+type ConfigCheck struct {
+	OK          bool   `json:"ok"`
+	Error       string `json:"error,omitempty"`
+	ReloadError string `json:"reload_error,omitempty"` // Last rejected reload, the previous configuration staying active
+}
+
+// readyState caches the last readiness check
+type readyState struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	resp      ReadinessResponse
+	pending   chan error // Transmission check still running after a timeout
+	startedAt time.Time  // Start of the pending check
+}
+
+// SetReadyMaxAge sets the maximum age of the last successful cleanup check
+// for the server to be ready, 0 to only require one successful check
+func (s *Server) SetReadyMaxAge(d time.Duration) {
+	s.readyMaxAge = d
+}
+
+// SetConfigError records that a configuration reload was rejected. It is
+// reported by /readyz without making the server not ready, since the previous
+// configuration stays active. A nil error clears it.
+func (s *Server) SetConfigError(err error) {
+	s.healthMutex.Lock()
+	defer s.healthMutex.Unlock()
+	s.configErr = err
+}
+
+// handleHealthz reports that the process is alive
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok", Version: s.version})
+}
+
+// handleReadyz reports whether btcleaner can do its job: Transmission answers,
+// cleanup checks succeed and the configuration is valid. The details are only
+// returned to authenticated callers.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := s.readiness()
+
+	status := http.StatusOK
+	if resp.Status != StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	if s.auth.authenticate(r) == nil {
+		writeJSON(w, status, ReadinessStatus{Status: resp.Status})
+		return
+	}
+	writeJSON(w, status, resp)
+}
+
+// readiness runs the readiness checks, or returns the result of the last
+// ones if they are more recent than readyCacheTTL
+func (s *Server) readiness() ReadinessResponse {
+	s.ready.mu.Lock()
+	defer s.ready.mu.Unlock()
+
+	if !s.ready.checkedAt.IsZero() && time.Since(s.ready.checkedAt) < readyCacheTTL {
+		return s.ready.resp
+	}
+
+	resp := ReadinessResponse{
+		Transmission: s.checkTransmission(),
+		LastCheck:    s.checkLastRun(),
+		Config:       s.checkConfig(),
+	}
+	resp.Status = StatusReady
+	if !resp.Transmission.OK || !resp.LastCheck.OK || !resp.Config.OK {
+		resp.Status = StatusNotReady
+	}
+	s.ready.resp = resp
+	s.ready.checkedAt = time.Now()
+	return resp
+}
+
+// checkTransmission tests the connection to Transmission, within
+// readyTransmissionTimeout. A test that timed out is waited for by the next
+// check rather than started again. It is called with s.ready.mu held.
+func (s *Server) checkTransmission() TransmissionCheck {
+	if s.ready.pending == nil {
+		result := make(chan error, 1)
+		go func() {
+			result <- s.client.TestConnection()
+		}()
+		s.ready.pending = result
+		s.ready.startedAt = time.Now()
+	}
+
+	select {
+	case err := <-s.ready.pending:
+		s.ready.pending = nil
+		check := TransmissionCheck{OK: err == nil, LatencyMs: time.Since(s.ready.startedAt).Milliseconds()}
+		if err != nil {
+			check.Error = err.Error()
+		}
+		return check
+	case <-time.After(readyTransmissionTimeout):
+		return TransmissionCheck{LatencyMs: time.Since(s.ready.startedAt).Milliseconds(), Error: "timeout"}
+	}
+}
+
+// checkLastRun checks the age of the last successful cleanup check
+func (s *Server) checkLastRun() LastCheckStatus {
+	last, ok := s.cleaner.LastCompletedRun()
+	if !ok {
+		return LastCheckStatus{Error: "no successful cleanup check yet"}
+	}
+
+	age := time.Since(last)
+	check := LastCheckStatus{OK: true, LastSuccess: &last, AgeSeconds: age.Seconds()}
+	if s.readyMaxAge > 0 {
+		check.MaxAgeSeconds = s.readyMaxAge.Seconds()
+		if age > s.readyMaxAge {
+			check.OK = false
+			check.Error = "last successful cleanup check is too old"
+		}
+	}
+	return check
+}
+
+// checkConfig checks the active cleanup policy, and reports the last rejected
+// reload
+func (s *Server) checkConfig() ConfigCheck {
+	s.healthMutex.RLock()
+	reloadErr := s.configErr
+	s.healthMutex.RUnlock()
+
+	check := ConfigCheck{OK: true}
+	if reloadErr != nil {
+		check.ReloadError = reloadErr.Error()
+	}
+	if err := s.cleaner.Policy().Validate(); err != nil {
+		check.OK = false
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -1,9 +1,12 @@
 package server
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 // generateHTML generates the embedded HTML for the web interface
-func (s *Server) generateHTML(webRoot string) string {
+func (s *Server) generateHTML(webRoot, csrfToken string) string {
 	version := s.version
 	if version == "" {
 		version = "dev"
@@ -59,6 +62,26 @@ func (s *Server) generateHTML(webRoot string) string {
             font-weight: 400;
         }
 
+        header .header-right {
+            text-align: right;
+        }
+
+        header .user-info {
+            font-size: 13px;
+            margin-bottom: 4px;
+        }
+
+        header .user-info button {
+            margin-left: 8px;
+            background: transparent;
+            color: white;
+            border: 1px solid rgba(255,255,255,0.5);
+            border-radius: 4px;
+            padding: 2px 8px;
+            cursor: pointer;
+            font-size: 12px;
+        }
+
         header p {
             opacity: 0.9;
             margin-top: 5px;
@@ -109,6 +132,26 @@ func (s *Server) generateHTML(webRoot string) string {
             color: #27ae60;
         }
 
+        .blocked-banner {
+            background: #fdecea;
+            border-left: 4px solid #e74c3c;
+            border-radius: 8px;
+            padding: 15px 20px;
+            margin-bottom: 20px;
+            color: #2c3e50;
+        }
+
+        .blocked-banner h3 {
+            color: #c0392b;
+            font-size: 16px;
+            margin-bottom: 8px;
+        }
+
+        .blocked-banner ul {
+            margin: 8px 0 0 20px;
+            font-size: 14px;
+        }
+
         .card {
             background: white;
             border-radius: 8px;
@@ -289,6 +332,120 @@ func (s *Server) generateHTML(webRoot string) string {
             color: #856404;
         }
 
+        .history-badge.run-link {
+            background: #e8eaf6;
+            color: #3949ab;
+            cursor: pointer;
+        }
+
+        .tabs {
+            display: flex;
+            gap: 5px;
+            margin-bottom: -1px;
+        }
+
+        .tab {
+            padding: 10px 20px;
+            border: none;
+            border-radius: 8px 8px 0 0;
+            background: #ecf0f1;
+            color: #666;
+            font-size: 14px;
+            font-weight: 600;
+            cursor: pointer;
+        }
+
+        .tab.active {
+            background: white;
+            color: #2c3e50;
+        }
+
+        .tab-panel {
+            display: none;
+        }
+
+        .tab-panel.active {
+            display: block;
+        }
+
+        .tab-panel > .card {
+            border-top-left-radius: 0;
+        }
+
+        .sim-form {
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(200px, 1fr));
+            gap: 15px;
+            margin-bottom: 15px;
+        }
+
+        .sim-form label {
+            display: flex;
+            flex-direction: column;
+            gap: 5px;
+            font-size: 13px;
+            color: #666;
+        }
+
+        .sim-form label.wide {
+            grid-column: 1 / -1;
+        }
+
+        .sim-form .filter-input {
+            min-width: 0;
+            font-family: inherit;
+        }
+
+        .sim-summary {
+            padding: 10px 15px;
+            border-radius: 4px;
+            margin-bottom: 15px;
+            font-size: 14px;
+        }
+
+        .sim-summary.ok {
+            background: #d4edda;
+            color: #155724;
+        }
+
+        .sim-summary.insufficient {
+            background: #fdecea;
+            color: #721c24;
+        }
+
+        #runs-body tr {
+            cursor: pointer;
+        }
+
+        .run-status.success {
+            color: #27ae60;
+        }
+
+        .run-status.partial {
+            color: #f39c12;
+        }
+
+        .run-status.failed {
+            color: #e74c3c;
+        }
+
+        .run-details dl {
+            display: grid;
+            grid-template-columns: max-content 1fr;
+            gap: 4px 15px;
+            font-size: 13px;
+            margin: 10px 0;
+        }
+
+        .run-details dt {
+            color: #666;
+        }
+
+        .run-details h4 {
+            margin-top: 15px;
+            color: #2c3e50;
+        }
+
         .badge-warning {
             background: #fff3cd;
             color: #856404;
@@ -321,9 +478,28 @@ func (s *Server) generateHTML(webRoot string) string {
 
         .log-level.debug { color: #9cdcfe; }
         .log-level.info { color: #4ec9b0; }
-        .log-level.warn { color: #dcdcaa; }
+        .log-level.warn, .log-level.warning { color: #dcdcaa; }
         .log-level.error { color: #f48771; }
 
+        .log-field {
+            color: #c586c0;
+            margin-left: 8px;
+            font-size: 12px;
+        }
+
+        .log-levels, .log-actions {
+            display: flex;
+            align-items: center;
+            gap: 12px;
+            font-size: 14px;
+        }
+
+        .log-actions .filter-input {
+            min-width: 0;
+            margin-left: 6px;
+            padding: 6px 10px;
+        }
+
         .loading {
             text-align: center;
             padding: 40px;
@@ -341,6 +517,111 @@ func (s *Server) generateHTML(webRoot string) string {
             font-size: 12px;
         }
 
+        .table-toolbar {
+            display: flex;
+            justify-content: space-between;
+            align-items: center;
+            gap: 10px;
+            margin-bottom: 15px;
+            flex-wrap: wrap;
+        }
+
+        .filter-input {
+            padding: 8px 12px;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+            font-size: 14px;
+            min-width: 250px;
+        }
+
+        .bulk-bar {
+            display: none;
+            align-items: center;
+            gap: 10px;
+            padding: 8px 12px;
+            background: #fdecea;
+            border-radius: 4px;
+            font-size: 14px;
+        }
+
+        .bulk-bar.visible {
+            display: flex;
+        }
+
+        .btn-secondary {
+            background: #95a5a6;
+            color: white;
+        }
+
+        .btn-secondary:hover {
+            background: #7f8c8d;
+        }
+
+        .cleanup-controls {
+            display: flex;
+            flex-wrap: wrap;
+            align-items: center;
+            gap: 8px;
+            margin-top: 10px;
+            font-size: 13px;
+        }
+
+        .cleanup-controls .filter-input {
+            min-width: 0;
+            flex: 1;
+            padding: 6px 10px;
+            font-size: 13px;
+        }
+
+        .cleanup-result {
+            margin-top: 8px;
+            font-size: 13px;
+            color: #666;
+        }
+
+        .modal-overlay {
+            display: none;
+            position: fixed;
+            inset: 0;
+            background: rgba(0,0,0,0.5);
+            align-items: center;
+            justify-content: center;
+            z-index: 100;
+        }
+
+        .modal-overlay.visible {
+            display: flex;
+        }
+
+        .modal {
+            background: white;
+            border-radius: 8px;
+            padding: 20px;
+            max-width: 600px;
+            width: 90%;
+            max-height: 80vh;
+            overflow-y: auto;
+        }
+
+        .modal h3 {
+            margin-bottom: 10px;
+            color: #2c3e50;
+        }
+
+        .modal ul {
+            margin: 10px 0 10px 20px;
+            font-size: 13px;
+            max-height: 250px;
+            overflow-y: auto;
+        }
+
+        .modal-actions {
+            display: flex;
+            justify-content: flex-end;
+            gap: 10px;
+            margin-top: 15px;
+        }
+
         @media (max-width: 768px) {
             .stats-grid {
                 grid-template-columns: 1fr;
@@ -368,17 +649,31 @@ func (s *Server) generateHTML(webRoot string) string {
                     <h1>🧹 BTCleaner Dashboard</h1>
                     <p>Automatic Transmission Seedbox Cleanup</p>
                 </div>
-                <div class="version">` + version + `</div>
+                <div class="header-right">
+                    <div class="user-info" id="user-info" style="display: none;"></div>
+                    <div class="version">` + version + `</div>
+                </div>
             </div>
         </div>
     </header>
 
     <div class="container">
+        <!-- Shown while a cleanup cannot reach the minimum free space -->
+        <div class="blocked-banner" id="blocked-banner" style="display: none;"></div>
+
         <!-- Statistics -->
         <div class="stats-grid">
             <div class="stat-card" id="free-space-card">
                 <h3>Free Space</h3>
                 <div class="value" id="free-space">--</div>
+                <div style="font-size: 14px; color: #666; margin-top: 5px;" id="projected-space"></div>
+            </div>
+            <div class="stat-card wide">
+                <h3>Free Space Trend</h3>
+                <div id="space-chart">
+                    <div style="color: #999; text-align: center; padding: 20px;">Loading...</div>
+                </div>
+                <div style="font-size: 14px; color: #666; margin-top: 5px;" id="trend-info"></div>
             </div>
             <div class="stat-card">
                 <h3>Min Required</h3>
@@ -392,6 +687,13 @@ func (s *Server) generateHTML(webRoot string) string {
             <div class="stat-card" id="status-card">
                 <h3>Status</h3>
                 <div class="value" id="status">--</div>
+                <div style="font-size: 14px; color: #666; margin-top: 5px;" id="schedule-info"></div>
+                <div class="cleanup-controls" id="cleanup-controls" style="display: none;">
+                    <button class="btn btn-refresh" id="run-cleanup" onclick="runCleanup()">Run cleanup now</button>
+                    <label><input type="checkbox" id="run-dry-run"> Dry run</label>
+                    <input type="text" class="filter-input" id="run-target" placeholder="Target free space (e.g. 500GB)">
+                </div>
+                <div class="cleanup-result" id="cleanup-result"></div>
             </div>
             <div class="stat-card wide">
                 <h3>Trackers</h3>
@@ -407,7 +709,15 @@ func (s *Server) generateHTML(webRoot string) string {
             </div>
         </div>
 
+        <div class="tabs">
+            <button class="tab active" data-tab="torrents" onclick="showTab('torrents')">Torrents</button>
+            <button class="tab" data-tab="runs" onclick="showTab('runs')">Runs</button>
+            <button class="tab" data-tab="simulate" onclick="showTab('simulate')">Simulate</button>
+            <button class="tab" data-tab="settings" onclick="showTab('settings')">Settings</button>
+        </div>
+
         <!-- Torrents Table -->
+        <div class="tab-panel active" id="tab-torrents">
         <div class="card">
             <div class="card-header">
                 <h2>Torrents</h2>
@@ -417,10 +727,19 @@ func (s *Server) generateHTML(webRoot string) string {
                 </div>
             </div>
             <div class="card-body">
+                <div class="table-toolbar">
+                    <input type="text" class="filter-input" id="torrent-filter" placeholder="Filter by name or tracker..." oninput="renderTorrents()">
+                    <div class="bulk-bar" id="bulk-bar">
+                        <span id="bulk-summary"></span>
+                        <button class="btn btn-danger" onclick="confirmBulkDelete()">Delete selected</button>
+                        <button class="btn btn-secondary" onclick="clearSelection()">Clear</button>
+                    </div>
+                </div>
                 <div class="table-container">
                     <table id="torrents-table">
                         <thead>
                             <tr>
+                                <th class="select-col"><input type="checkbox" id="select-all" title="Select all filtered torrents" onchange="toggleSelectAll(this.checked)"></th>
                                 <th>Name</th>
                                 <th>Tracker</th>
                                 <th>Size</th>
@@ -430,13 +749,178 @@ func (s *Server) generateHTML(webRoot string) string {
                         </thead>
                         <tbody id="torrents-body">
                             <tr>
-                                <td colspan="5" class="loading">Loading...</td>
+                                <td colspan="6" class="loading">Loading...</td>
+                            </tr>
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+
+        </div>
+
+        <!-- Cleanup runs -->
+        <div class="tab-panel" id="tab-runs">
+        <div class="card">
+            <div class="card-header">
+                <h2>Cleanup Runs</h2>
+                <div>
+                    <span class="refresh-time" id="runs-update"></span>
+                    <button class="btn btn-refresh" onclick="loadRuns()">Refresh</button>
+                </div>
+            </div>
+            <div class="card-body">
+                <div class="table-container">
+                    <table>
+                        <thead>
+                            <tr>
+                                <th>Started</th>
+                                <th>Trigger</th>
+                                <th>Status</th>
+                                <th>Free Space</th>
+                                <th>Considered</th>
+                                <th>Removed</th>
+                                <th>Skipped</th>
+                                <th>Duration</th>
+                            </tr>
+                        </thead>
+                        <tbody id="runs-body">
+                            <tr>
+                                <td colspan="8" class="loading">Loading...</td>
                             </tr>
                         </tbody>
                     </table>
                 </div>
             </div>
         </div>
+        </div>
+
+        <!-- Simulation -->
+        <div class="tab-panel" id="tab-simulate">
+        <div class="card">
+            <div class="card-header">
+                <h2>What-if Simulation</h2>
+            </div>
+            <div class="card-body">
+                <p style="color: #666; font-size: 13px; margin-bottom: 15px;">
+                    Try other settings against the current torrents and free space. Nothing is deleted and the live configuration is not changed.
+                    Empty fields use the current settings.
+                </p>
+                <div class="sim-form">
+                    <label>Min free space
+                        <input type="text" class="filter-input" id="sim-min-free" placeholder="e.g. 500GB">
+                    </label>
+                    <label>Min torrents per tracker
+                        <input type="number" min="0" class="filter-input" id="sim-min-torrents">
+                    </label>
+                    <label>Strategy
+                        <select class="filter-input" id="sim-strategy">
+                            <option value="">(current)</option>
+                            <option value="oldest">Oldest first</option>
+                            <option value="largest">Largest first</option>
+                        </select>
+                    </label>
+                    <label>Incoming download size
+                        <input type="text" class="filter-input" id="sim-incoming" placeholder="e.g. 50GB">
+                    </label>
+                    <label>Active downloads
+                        <select class="filter-input" id="sim-predictive">
+                            <option value="">(current)</option>
+                            <option value="true">Must fit (predictive)</option>
+                            <option value="false">Ignored</option>
+                        </select>
+                    </label>
+                    <label class="wide">Per-tracker minimums (one "tracker=N" per line, replaces the current ones)
+                        <textarea class="filter-input" id="sim-trackers" rows="3" placeholder="tracker.example.org=10"></textarea>
+                    </label>
+                </div>
+                <button class="btn btn-refresh" onclick="runSimulation()">Simulate</button>
+                <div id="sim-result" style="margin-top: 20px;"></div>
+            </div>
+        </div>
+        </div>
+
+        <!-- Settings -->
+        <div class="tab-panel" id="tab-settings">
+        <div class="card">
+            <div class="card-header">
+                <h2>Settings</h2>
+            </div>
+            <div class="card-body">
+                <p style="color: #666; font-size: 13px; margin-bottom: 15px;">
+                    Changes apply immediately to the running cleaner. Without "Save to config file" they are lost on restart.
+                    <span id="settings-file"></span>
+                </p>
+                <div class="sim-form" oninput="settingsDirty = true">
+                    <label>Min free space
+                        <input type="text" class="filter-input" id="set-min-free" placeholder="e.g. 500GB">
+                    </label>
+                    <label>Min torrents per tracker
+                        <input type="number" min="0" class="filter-input" id="set-min-torrents">
+                    </label>
+                    <label>Strategy
+                        <select class="filter-input" id="set-strategy">
+                            <option value="oldest">Oldest first</option>
+                            <option value="largest">Largest first</option>
+                        </select>
+                    </label>
+                    <label>Check interval
+                        <input type="text" class="filter-input" id="set-interval" placeholder="e.g. 5m">
+                    </label>
+                    <label>Log level
+                        <select class="filter-input" id="set-log-level">
+                            <option value="debug">debug</option>
+                            <option value="info">info</option>
+                            <option value="warn">warn</option>
+                            <option value="error">error</option>
+                        </select>
+                    </label>
+                    <label>Dry run
+                        <select class="filter-input" id="set-dry-run">
+                            <option value="false">Off: delete torrents</option>
+                            <option value="true">On: only log deletions</option>
+                        </select>
+                    </label>
+                    <label class="wide">Per-tracker minimums (one "tracker=N" per line)
+                        <textarea class="filter-input" id="set-trackers" rows="3" placeholder="tracker.example.org=10"></textarea>
+                    </label>
+                </div>
+                <div id="settings-controls" style="display: none; align-items: center; gap: 15px;">
+                    <button class="btn btn-refresh" onclick="saveSettings()">Apply</button>
+                    <label style="font-size: 13px; color: #666;" id="settings-persist-label">
+                        <input type="checkbox" id="set-persist"> Save to config file
+                    </label>
+                    <button class="btn btn-secondary" onclick="loadSettings(true)">Reset</button>
+                </div>
+                <div id="settings-result" style="margin-top: 20px;"></div>
+            </div>
+        </div>
+        </div>
+
+        <!-- Run details -->
+        <div class="modal-overlay" id="run-modal" onclick="if (event.target === this) closeRunModal()">
+            <div class="modal run-details">
+                <h3 id="run-modal-title">Cleanup run</h3>
+                <div id="run-modal-body"></div>
+                <div class="modal-actions">
+                    <button class="btn btn-secondary" onclick="closeRunModal()">Close</button>
+                </div>
+            </div>
+        </div>
+
+        <!-- Bulk delete confirmation -->
+        <div class="modal-overlay" id="bulk-modal">
+            <div class="modal">
+                <h3>Delete selected torrents?</h3>
+                <p id="bulk-modal-summary"></p>
+                <ul id="bulk-modal-list"></ul>
+                <p style="color: #e74c3c; font-size: 13px;">Torrents and their data will be permanently removed.</p>
+                <div class="modal-actions">
+                    <button class="btn btn-secondary" onclick="closeBulkModal()">Cancel</button>
+                    <button class="btn btn-danger" id="bulk-confirm" onclick="bulkDelete()">Delete</button>
+                </div>
+            </div>
+        </div>
 
         <!-- Logs -->
         <div class="card">
@@ -448,6 +932,27 @@ func (s *Server) generateHTML(webRoot string) string {
                 </div>
             </div>
             <div class="card-body">
+                <div class="table-toolbar">
+                    <div class="log-levels" id="log-levels">
+                        <label><input type="checkbox" value="debug" checked onchange="renderLogs()"> Debug</label>
+                        <label><input type="checkbox" value="info" checked onchange="renderLogs()"> Info</label>
+                        <label><input type="checkbox" value="warning" checked onchange="renderLogs()"> Warn</label>
+                        <label><input type="checkbox" value="error" checked onchange="renderLogs()"> Error</label>
+                    </div>
+                    <input type="text" class="filter-input" id="log-search" placeholder="Search messages and fields (e.g. torrent_id=42)" oninput="renderLogs()">
+                    <div class="log-actions">
+                        <label id="log-level-control" style="display: none;">Log level
+                            <select class="filter-input" id="log-level-select" onchange="changeLogLevel(this.value)">
+                                <option value="debug">debug</option>
+                                <option value="info">info</option>
+                                <option value="warn">warn</option>
+                                <option value="error">error</option>
+                            </select>
+                        </label>
+                        <button class="btn btn-secondary" id="logs-pause" onclick="toggleLogsPause()">Pause</button>
+                        <button class="btn btn-secondary" onclick="downloadLogs()">Download</button>
+                    </div>
+                </div>
                 <div class="logs-container" id="logs-container">
                     <div class="log-entry">Connecting to log stream...</div>
                 </div>
@@ -458,17 +963,73 @@ func (s *Server) generateHTML(webRoot string) string {
     <script>
         const webRoot = '{{WEBROOT}}';
         const apiBase = webRoot === '/' ? '' : webRoot;
+        const apiV1 = apiBase + '/api/v1';
+        const csrfToken = '{{CSRF_TOKEN}}';
         let ws = null;
         let reconnectInterval = null;
+        const logBufferSize = {{LOG_BUFFER_SIZE}};
+        let logEntries = []; // Received log entries, oldest first
+        let logsPaused = false;
+        let pausedLogs = 0; // Entries received while paused
+        let eventSource = null;
+        let eventsConnected = false;
+        const pendingRefresh = {};
         let candidateIds = [];
+        let allTorrents = [];
+        let selectedIds = new Set();
+        let currentUser = { role: 'operator', auth_enabled: false };
+
+        // Fetch wrapper adding the CSRF token and redirecting to the login page when the session expired
+        async function apiFetch(url, options) {
+            options = options || {};
+            if (options.method && options.method !== 'GET') {
+                options.headers = Object.assign({ 'X-CSRF-Token': csrfToken }, options.headers || {});
+            }
+            const response = await fetch(url, options);
+            if (response.status === 401) {
+                window.location.href = apiBase + '/login';
+                throw new Error('Authentication required');
+            }
+            return response;
+        }
+
+        function canOperate() {
+            return currentUser.role === 'operator';
+        }
+
+        // Load current user
+        async function loadUser() {
+            try {
+                const response = await apiFetch(apiV1 + '/auth/me');
+                currentUser = await response.json();
+                if (currentUser.auth_enabled) {
+                    const userInfo = document.getElementById('user-info');
+                    userInfo.innerHTML = "👤 " + escapeHtml(currentUser.username) + " (" + currentUser.role + ")" +
+                        (currentUser.method === 'session' ? "<button onclick='logout()'>Logout</button>" : "");
+                    userInfo.style.display = 'block';
+                }
+                if (canOperate()) {
+                    document.getElementById('cleanup-controls').style.display = 'flex';
+                }
+            } catch (error) {
+                console.error('Failed to load user:', error);
+            }
+        }
+
+        // Logout
+        async function logout() {
+            await fetch(apiV1 + '/auth/logout', { method: 'POST', headers: { 'X-CSRF-Token': csrfToken } });
+            window.location.href = apiBase + '/login';
+        }
 
         // Load statistics
         async function loadStats() {
             try {
-                const response = await fetch(apiBase + '/api/stats');
+                const response = await apiFetch(apiV1 + '/stats');
                 const data = await response.json();
                 
                 document.getElementById('free-space').textContent = data.free_space_gb.toFixed(2) + ' GB';
+                renderProjected(data);
                 document.getElementById('min-space').textContent = data.min_free_space_gb.toFixed(2) + ' GB';
                 document.getElementById('total-torrents').textContent = data.total_torrents;
                 document.getElementById('total-space').textContent = data.total_space_gb.toFixed(2) + ' GB used';
@@ -488,6 +1049,10 @@ func (s *Server) generateHTML(webRoot string) string {
                     trackerList.innerHTML = "<div style='color: #999; text-align: center; padding: 20px;'>No trackers</div>";
                 }
                 
+                renderBlocked(data.blocked);
+                renderSchedule(data.schedule);
+                loadSpaceHistory();
+
                 const freeCard = document.getElementById('free-space-card');
                 if (data.needs_cleanup) {
                     freeCard.classList.add('warning');
@@ -513,38 +1078,172 @@ func (s *Server) generateHTML(webRoot string) string {
             }
         }
 
-        // Load candidates for deletion
-        async function loadCandidates() {
+        // Load the free space measured by the checks of the last day
+        async function loadSpaceHistory() {
             try {
-                const response = await fetch(apiBase + '/api/candidates');
-                candidateIds = await response.json();
-                // Refresh torrents to apply highlighting
-                loadTorrents();
+                const response = await apiFetch(apiV1 + '/stats/history?since=24h');
+                const data = await response.json();
+                renderSpaceChart(data.samples);
+                renderTrend(data.trend);
             } catch (error) {
-                console.error('Failed to load candidates:', error);
+                console.error('Failed to load free space history:', error);
             }
         }
 
-        // Load deletion history
-        async function loadHistory() {
-            try {
-                const response = await fetch(apiBase + '/api/history');
-                const history = await response.json();
-                
-                const historyList = document.getElementById('history-list');
-                if (history && history.length > 0) {
-                    historyList.innerHTML = history.slice(0, 20).map(h => {
-                        const date = new Date(h.deleted_at);
-                        const timeAgo = getTimeAgo(date);
-                        const reasonBadge = h.reason === 'auto' ? 
-                            "<span class='history-badge auto'>AUTO</span>" : 
-                            "<span class='history-badge manual'>MANUAL</span>";
-                        
-                        return "<div class='history-item'>" +
-                            "<span class='history-name' title='" + escapeHtml(h.name) + "'>" + 
-                            truncate(h.name, 40) + "</span>" +
+        // Draw the free space samples and the minimum free space as an SVG chart
+        function renderSpaceChart(samples) {
+            const chart = document.getElementById('space-chart');
+            if (!samples || samples.length < 2) {
+                chart.innerHTML = "<div style='color: #999; text-align: center; padding: 20px;'>Not enough checks yet</div>";
+                return;
+            }
+
+            const width = 600, height = 120, margin = 5;
+            const times = samples.map(s => new Date(s.time).getTime());
+            const first = times[0], last = times[times.length - 1];
+            const values = samples.flatMap(s => [s.free_space, s.min_free_space]);
+            const low = Math.min(...values);
+            const high = Math.max(...values) > low ? Math.max(...values) : low + 1;
+            const x = t => ((t - first) / ((last - first) || 1)) * width;
+            const y = v => margin + (1 - (v - low) / (high - low)) * (height - 2 * margin);
+            const points = key => samples.map((s, i) => x(times[i]).toFixed(1) + ',' + y(s[key]).toFixed(1)).join(' ');
+
+            chart.innerHTML = "<svg viewBox='0 0 " + width + " " + height + "' preserveAspectRatio='none' style='width: 100%; height: " + height + "px;'>" +
+                    "<polyline points='" + points('min_free_space') + "' fill='none' stroke='#e74c3c' stroke-width='1' stroke-dasharray='4 3' vector-effect='non-scaling-stroke'/>" +
+                    "<polyline points='" + points('free_space') + "' fill='none' stroke='#3498db' stroke-width='2' vector-effect='non-scaling-stroke'/>" +
+                "</svg>" +
+                "<div style='display: flex; justify-content: space-between; font-size: 12px; color: #999;'>" +
+                    "<span>" + new Date(first).toLocaleString() + "</span>" +
+                    "<span>" + formatGB(low) + " - " + formatGB(high) + " (dashed: minimum)</span>" +
+                    "<span>" + new Date(last).toLocaleString() + "</span>" +
+                "</div>";
+        }
+
+        // Show the fill rate and when free space drops below the minimum
+        function renderTrend(trend) {
+            const info = document.getElementById('trend-info');
+            if (!trend || trend.samples < 2) {
+                info.textContent = '';
+                return;
+            }
+            if (!trend.fill_rate_bytes_per_hour) {
+                info.textContent = 'Free space is not decreasing';
+                return;
+            }
+
+            let text = 'Filling at ' + formatGB(trend.fill_rate_bytes_per_hour) + '/h';
+            if (trend.time_to_threshold_seconds === 0) {
+                text += ' · below the minimum';
+            } else if (trend.threshold_at) {
+                text += ' · minimum reached in ' + formatDuration(trend.time_to_threshold_seconds) +
+                    ' (' + new Date(trend.threshold_at).toLocaleString() + ')';
+            }
+            info.textContent = text;
+        }
+
+        // Format a number of seconds as days, hours and minutes
+        function formatDuration(seconds) {
+            const minutes = Math.round(seconds / 60);
+            const days = Math.floor(minutes / 1440), hours = Math.floor((minutes % 1440) / 60);
+            if (days > 0) {
+                return days + 'd ' + hours + 'h';
+            }
+            if (hours > 0) {
+                return hours + 'h ' + (minutes % 60) + 'm';
+            }
+            return minutes + 'm';
+        }
+
+        // Show the free space left once the active downloads complete
+        function renderProjected(data) {
+            const projected = document.getElementById('projected-space');
+            if (!data.pending_download_bytes && !data.predictive) {
+                projected.textContent = '';
+                return;
+            }
+            let text = 'Projected: ' + data.projected_free_space_gb.toFixed(2) + ' GB (' +
+                (data.pending_download_bytes / (1024 * 1024 * 1024)).toFixed(2) + ' GB downloading)';
+            if (data.predictive) {
+                text += ' · predictive cleanup';
+            }
+            projected.textContent = text;
+        }
+
+        // Show the next daemon check, and when deletions are deferred to a window
+        function renderSchedule(schedule) {
+            const info = document.getElementById('schedule-info');
+            if (!schedule) {
+                info.textContent = '';
+                return;
+            }
+
+            let text = 'Next check: ' + new Date(schedule.next_run_at).toLocaleString();
+            if (!schedule.deletion_allowed) {
+                text += ' · deletions deferred';
+                if (schedule.next_deletion_at) {
+                    text += ' until ' + new Date(schedule.next_deletion_at).toLocaleString();
+                }
+            }
+            info.textContent = text;
+            info.title = 'Checks ' + schedule.schedule +
+                (schedule.emergency_free_space_bytes ? ', windows ignored below ' + formatGB(schedule.emergency_free_space_bytes) : '');
+        }
+
+        // Show why a cleanup is blocked, the message includes the suggested minimums
+        function renderBlocked(blocked) {
+            const banner = document.getElementById('blocked-banner');
+            if (!blocked) {
+                banner.style.display = 'none';
+                return;
+            }
+
+            let html = "<h3>⛔ Cleanup blocked: " + formatGB(blocked.shortfall_bytes) + " cannot be freed</h3>" +
+                "<div>" + escapeHtml(blocked.message) + "</div>";
+            if (blocked.constraints.length > 0) {
+                html += "<ul>" + blocked.constraints.map(c =>
+                    "<li><strong>" + escapeHtml(c.tracker) + "</strong>: minimum " + c.min_torrents +
+                    " keeps " + c.kept_torrents + " of " + c.torrents + " torrents (" + formatGB(c.kept_bytes) + ")</li>"
+                ).join('') + "</ul>";
+            }
+            banner.innerHTML = html;
+            banner.style.display = 'block';
+        }
+
+        // Load candidates for deletion
+        async function loadCandidates() {
+            try {
+                const response = await apiFetch(apiV1 + '/candidates');
+                const data = await response.json();
+                candidateIds = data.candidates.map(t => t.id);
+                // Re-render torrents to apply highlighting
+                renderTorrents();
+            } catch (error) {
+                console.error('Failed to load candidates:', error);
+            }
+        }
+
+        // Load deletion history
+        async function loadHistory() {
+            try {
+                const response = await apiFetch(apiV1 + '/history');
+                const history = (await response.json()).history;
+                
+                const historyList = document.getElementById('history-list');
+                if (history && history.length > 0) {
+                    historyList.innerHTML = history.slice(0, 20).map(h => {
+                        const date = new Date(h.deleted_at);
+                        const timeAgo = getTimeAgo(date);
+                        const reasonBadge = h.reason === 'auto' ? 
+                            "<span class='history-badge auto'>AUTO</span>" : 
+                            "<span class='history-badge manual'>MANUAL</span>";
+                        const runLink = h.run_id ?
+                            "<span class='history-badge run-link' title='Show cleanup run' data-run-id='" + escapeHtml(h.run_id) + "'>RUN</span>" : "";
+                        
+                        return "<div class='history-item'>" +
+                            "<span class='history-name' title='" + escapeHtml(h.name) + "'>" + 
+                            escapeHtml(truncate(h.name, 40)) + "</span>" +
                             "<div class='history-info'>" +
-                            reasonBadge +
+                            reasonBadge + runLink +
                             "<span>" + h.size_gb.toFixed(2) + " GB</span>" +
                             "<span style='color: #999;'>" + timeAgo + "</span>" +
                             "</div>" +
@@ -558,50 +1257,527 @@ func (s *Server) generateHTML(webRoot string) string {
             }
         }
 
-        // Load torrents
-        async function loadTorrents() {
+        // Tabs
+        function showTab(name) {
+            document.querySelectorAll('.tab').forEach(t => t.classList.toggle('active', t.dataset.tab === name));
+            document.querySelectorAll('.tab-panel').forEach(p => p.classList.toggle('active', p.id === 'tab-' + name));
+            if (name === 'runs') {
+                loadRuns();
+            }
+            if (name === 'settings') {
+                loadSettings(false);
+            }
+        }
+
+        function formatGB(bytes) {
+            return (bytes / (1024 * 1024 * 1024)).toFixed(2) + ' GB';
+        }
+
+        // Load cleanup runs
+        async function loadRuns() {
             try {
-                const response = await fetch(apiBase + '/api/torrents');
-                const torrents = await response.json();
-                
-                const tbody = document.getElementById('torrents-body');
-                
-                if (torrents.length === 0) {
-                    tbody.innerHTML = '<tr><td colspan="5" class="empty">No torrents found</td></tr>';
+                const response = await apiFetch(apiV1 + '/runs?limit=100');
+                const runs = (await response.json()).runs;
+                const tbody = document.getElementById('runs-body');
+
+                if (runs.length === 0) {
+                    tbody.innerHTML = '<tr><td colspan="8" class="empty">No cleanup runs yet</td></tr>';
+                } else {
+                    tbody.innerHTML = runs.map(r =>
+                        "<tr data-run-id='" + escapeHtml(r.run_id) + "'>" +
+                        "<td>" + new Date(r.started_at).toLocaleString() + "</td>" +
+                        "<td>" + escapeHtml(r.trigger || '-') + (r.dry_run ? " <span class='badge badge-warning'>dry run</span>" : "") +
+                            (r.deferred ? " <span class='badge badge-warning'>deferred</span>" : "") +
+                            (r.emergency ? " <span class='badge badge-warning'>emergency</span>" : "") + "</td>" +
+                        "<td class='run-status " + r.status + "'>" + r.status + (r.error_count > 0 ? " (" + r.error_count + " errors)" : "") + "</td>" +
+                        "<td>" + formatGB(r.initial_free_space) + " → " + formatGB(r.final_free_space) + "</td>" +
+                        "<td>" + r.candidates_considered + "</td>" +
+                        "<td>" + r.removed_count + " (" + formatGB(r.removed_size) + ")</td>" +
+                        "<td>" + r.skipped_count + "</td>" +
+                        "<td>" + r.duration_ms + " ms</td>" +
+                        "</tr>"
+                    ).join('');
+                }
+                document.getElementById('runs-update').textContent = 'Updated: ' + new Date().toLocaleTimeString();
+            } catch (error) {
+                console.error('Failed to load runs:', error);
+                document.getElementById('runs-body').innerHTML = '<tr><td colspan="8" class="empty">Failed to load runs</td></tr>';
+            }
+        }
+
+        // Show the details of a cleanup run
+        async function showRun(id) {
+            try {
+                const response = await apiFetch(apiV1 + '/runs/' + encodeURIComponent(id));
+                if (!response.ok) {
+                    alert('Run ' + id + ' is no longer recorded');
                     return;
                 }
-                
-                tbody.innerHTML = torrents.map(t => {
-                    const size = (t.totalSize / (1024 * 1024 * 1024)).toFixed(2);
-                    const date = new Date(t.addedDate).toLocaleDateString();
-                    const nameEscaped = escapeHtml(t.name).replace(/'/g, "&apos;");
-                    const isCandidate = candidateIds.includes(t.id);
-                    const candidateClass = isCandidate ? " class='candidate'" : "";
-                    return "<tr" + candidateClass + ">" +
-                        "<td title='" + escapeHtml(t.name) + "'>" + truncate(t.name, 60) + "</td>" +
-                        "<td><span class='badge badge-success'>" + t.normalizedTracker + "</span></td>" +
-                        "<td>" + size + " GB</td>" +
-                        "<td>" + date + "</td>" +
-                        "<td><button class='btn btn-danger' onclick='deleteTorrent(" + t.id + ", \"" + nameEscaped + "\")'>Delete</button></td>" +
-                        "</tr>";
-                }).join('');
-                
+                const r = await response.json();
+
+                let html = "<dl>" +
+                    "<dt>Trigger</dt><dd>" + escapeHtml(r.trigger || '-') + "</dd>" +
+                    "<dt>Status</dt><dd class='run-status " + r.status + "'>" + r.status + "</dd>" +
+                    "<dt>Started</dt><dd>" + new Date(r.started_at).toLocaleString() + "</dd>" +
+                    "<dt>Finished</dt><dd>" + new Date(r.finished_at).toLocaleString() + " (" + r.duration_ms + " ms)</dd>" +
+                    "<dt>Dry run</dt><dd>" + (r.dry_run ? 'yes' : 'no') + "</dd>" +
+                    (r.deferred ? "<dt>Deferred</dt><dd>outside the deletion windows" + (r.deferred_until ? ", until " + new Date(r.deferred_until).toLocaleString() : "") + "</dd>" : "") +
+                    (r.emergency ? "<dt>Emergency</dt><dd>free space below the emergency floor, removed outside the deletion windows</dd>" : "") +
+                    "<dt>Target free space</dt><dd>" + formatGB(r.min_free_space) + "</dd>" +
+                    "<dt>Free space</dt><dd>" + formatGB(r.initial_free_space) + " → " + formatGB(r.final_free_space) + "</dd>" +
+                    "<dt>Candidates considered</dt><dd>" + r.candidates_considered + "</dd>" +
+                    "</dl>";
+
+                html += "<h4>" + (r.dry_run ? "Would remove" : "Removed") + " (" + r.removed_count + ", " + formatGB(r.removed_size) + ")</h4>";
+                html += (r.removed_torrents && r.removed_torrents.length > 0) ?
+                    "<ul>" + r.removed_torrents.map(t =>
+                        "<li>[" + escapeHtml(t.normalizedTracker) + "] " + escapeHtml(t.name) + " (" + formatGB(t.totalSize) + ")</li>"
+                    ).join('') + "</ul>" : "<p>None</p>";
+
+                html += "<h4>Skipped (" + r.skipped.length + ")</h4>";
+                html += r.skipped.length > 0 ?
+                    "<ul>" + r.skipped.map(t =>
+                        "<li>" + escapeHtml(t.name) + " (" + formatGB(t.size_bytes) + "): " + escapeHtml(t.detail || t.reason) + "</li>"
+                    ).join('') + "</ul>" : "<p>None</p>";
+
+                if (r.errors.length > 0) {
+                    html += "<h4>Errors</h4><ul>" + r.errors.map(e => "<li>" + escapeHtml(e) + "</li>").join('') + "</ul>";
+                }
+
+                document.getElementById('run-modal-title').textContent = 'Cleanup run ' + r.run_id;
+                document.getElementById('run-modal-body').innerHTML = html;
+                document.getElementById('run-modal').classList.add('visible');
+            } catch (error) {
+                console.error('Failed to load run:', error);
+            }
+        }
+
+        function closeRunModal() {
+            document.getElementById('run-modal').classList.remove('visible');
+        }
+
+        // Run a what-if simulation
+        async function runSimulation() {
+            const body = {};
+            const minFree = document.getElementById('sim-min-free').value.trim();
+            if (minFree) {
+                body.min_free_space = minFree;
+            }
+            const minTorrents = document.getElementById('sim-min-torrents').value.trim();
+            if (minTorrents) {
+                body.min_torrents_per_tracker = parseInt(minTorrents, 10);
+            }
+            const strategy = document.getElementById('sim-strategy').value;
+            if (strategy) {
+                body.strategy = strategy;
+            }
+            const incoming = document.getElementById('sim-incoming').value.trim();
+            if (incoming) {
+                body.incoming_size = incoming;
+            }
+            const predictive = document.getElementById('sim-predictive').value;
+            if (predictive) {
+                body.predictive = predictive === 'true';
+            }
+            const trackerLines = document.getElementById('sim-trackers').value.split('\n').map(l => l.trim()).filter(l => l);
+            if (trackerLines.length > 0) {
+                body.tracker_minimums = {};
+                for (const line of trackerLines) {
+                    const parts = line.split('=');
+                    const min = parseInt(parts[1], 10);
+                    if (parts.length !== 2 || isNaN(min)) {
+                        alert('Invalid tracker minimum: ' + line);
+                        return;
+                    }
+                    body.tracker_minimums[parts[0].trim()] = min;
+                }
+            }
+
+            const container = document.getElementById('sim-result');
+            container.innerHTML = "<div class='loading'>Simulating...</div>";
+            try {
+                const response = await apiFetch(apiV1 + '/simulate', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const r = await response.json();
+                if (!response.ok) {
+                    container.innerHTML = "<div class='sim-summary insufficient'>" + escapeHtml(r.error ? r.error.message : response.statusText) + "</div>";
+                    return;
+                }
+                renderSimulation(r);
+            } catch (error) {
+                console.error('Simulation failed:', error);
+                container.innerHTML = "<div class='sim-summary insufficient'>Simulation failed</div>";
+            }
+        }
+
+        // Runtime settings
+        let settingsDirty = false;
+
+        // Fill the settings form, keeping unsaved edits unless force is set
+        async function loadSettings(force) {
+            if (settingsDirty && !force) return;
+            try {
+                const response = await apiFetch(apiV1 + '/config');
+                if (!response.ok) return;
+                const r = await response.json();
+                const st = r.settings;
+                document.getElementById('set-min-free').value = formatSize(st.min_free_space);
+                document.getElementById('set-min-torrents').value = st.min_torrents_per_tracker;
+                document.getElementById('set-strategy').value = st.strategy;
+                document.getElementById('set-interval').value = st.check_interval;
+                document.getElementById('set-log-level').value = st.log_level;
+                document.getElementById('set-dry-run').value = String(st.dry_run);
+                document.getElementById('set-trackers').value = Object.entries(st.tracker_minimums)
+                    .map(([tracker, min]) => tracker + '=' + min).join('\n');
+                document.getElementById('settings-file').textContent = r.file ? 'Config file: ' + r.file : 'No config file is used.';
+                document.getElementById('settings-persist-label').style.display = r.file ? '' : 'none';
+                document.getElementById('settings-controls').style.display = canOperate() ? 'flex' : 'none';
+                document.querySelectorAll('#tab-settings .filter-input').forEach(el => el.disabled = !canOperate());
+                settingsDirty = false;
+            } catch (error) {
+                console.error('Failed to load settings:', error);
+            }
+        }
+
+        // Size with the largest exact unit, as accepted by the server
+        function formatSize(bytes) {
+            const units = [['TB', 1024 ** 4], ['GB', 1024 ** 3], ['MB', 1024 ** 2], ['KB', 1024]];
+            for (const [suffix, size] of units) {
+                if (bytes !== 0 && bytes % size === 0) return (bytes / size) + suffix;
+            }
+            return String(bytes);
+        }
+
+        async function saveSettings() {
+            const body = {
+                min_free_space: document.getElementById('set-min-free').value.trim(),
+                min_torrents_per_tracker: parseInt(document.getElementById('set-min-torrents').value, 10),
+                strategy: document.getElementById('set-strategy').value,
+                check_interval: document.getElementById('set-interval').value.trim(),
+                log_level: document.getElementById('set-log-level').value,
+                dry_run: document.getElementById('set-dry-run').value === 'true',
+                tracker_minimums: {},
+                persist: document.getElementById('set-persist').checked
+            };
+            if (isNaN(body.min_torrents_per_tracker)) {
+                alert('Invalid min torrents per tracker');
+                return;
+            }
+            const trackerLines = document.getElementById('set-trackers').value.split('\n').map(l => l.trim()).filter(l => l);
+            for (const line of trackerLines) {
+                const parts = line.split('=');
+                const min = parseInt(parts[1], 10);
+                if (parts.length !== 2 || isNaN(min)) {
+                    alert('Invalid tracker minimum: ' + line);
+                    return;
+                }
+                body.tracker_minimums[parts[0].trim()] = min;
+            }
+
+            const container = document.getElementById('settings-result');
+            try {
+                const response = await apiFetch(apiV1 + '/config', {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const r = await response.json();
+                if (!response.ok) {
+                    container.innerHTML = "<div class='sim-summary insufficient'>" + escapeHtml(r.error ? r.error.message : response.statusText) + "</div>";
+                    return;
+                }
+                const changes = r.changes.length === 0 ? 'No changes.' :
+                    'Applied' + (r.persisted ? ' and saved' : '') + ': ' + r.changes.map(escapeHtml).join(', ');
+                container.innerHTML = "<div class='sim-summary ok'>" + changes + "</div>" +
+                    (r.warning ? "<div class='sim-summary insufficient'>" + escapeHtml(r.warning) + "</div>" : '');
+                settingsDirty = false;
+                loadSettings(true);
+                loadStats();
+            } catch (error) {
+                console.error('Failed to save settings:', error);
+                container.innerHTML = "<div class='sim-summary insufficient'>Failed to save settings</div>";
+            }
+        }
+
+        function renderSimulation(r) {
+            let summary;
+            if (!r.need_cleanup) {
+                summary = "<div class='sim-summary ok'>No cleanup needed: " + formatGB(r.projected_free_space) +
+                    " free (minimum " + formatGB(r.policy.min_free_space) + ")</div>";
+            } else {
+                summary = "<div class='sim-summary " + (r.sufficient ? "ok" : "insufficient") + "'>" +
+                    r.selected.length + " torrents would be removed, freeing " + formatGB(r.selected_size) +
+                    " of " + formatGB(r.space_needed) + " needed. Projected free space: " + formatGB(r.projected_free_space) +
+                    (r.sufficient ? "" : " (not enough: tracker minimums prevent further removals)") + "</div>";
+            }
+            summary += "<p style='font-size: 13px; color: #666; margin-bottom: 10px;'>Strategy: " + escapeHtml(r.policy.strategy) +
+                ", min torrents per tracker: " + r.policy.min_torrents_per_tracker +
+                (r.incoming_size > 0 ? ", incoming download: " + formatGB(r.incoming_size) : "") +
+                (r.policy.predictive ? ", active downloads: " + formatGB(r.pending_downloads) : "") +
+                ", torrents considered: " + r.considered + "</p>";
+
+            let html = summary;
+            if (r.selected.length > 0) {
+                html += "<h3 style='margin: 10px 0;'>Removal order</h3><div class='table-container'><table><thead><tr>" +
+                    "<th>#</th><th>Name</th><th>Tracker</th><th>Size</th><th>Added</th></tr></thead><tbody>" +
+                    r.selected.map((t, i) =>
+                        "<tr><td>" + (i + 1) + "</td>" +
+                        "<td title='" + escapeHtml(t.name) + "'>" + escapeHtml(truncate(t.name, 60)) + "</td>" +
+                        "<td><span class='badge badge-success'>" + escapeHtml(t.normalizedTracker) + "</span></td>" +
+                        "<td>" + formatGB(t.totalSize) + "</td>" +
+                        "<td>" + new Date(t.addedDate).toLocaleDateString() + "</td></tr>"
+                    ).join('') + "</tbody></table></div>";
+            }
+            if (r.skipped.length > 0) {
+                html += "<h3 style='margin: 20px 0 10px;'>Skipped</h3><div class='table-container'><table><thead><tr>" +
+                    "<th>Name</th><th>Tracker</th><th>Size</th><th>Reason</th></tr></thead><tbody>" +
+                    r.skipped.map(t =>
+                        "<tr><td title='" + escapeHtml(t.name) + "'>" + escapeHtml(truncate(t.name, 60)) + "</td>" +
+                        "<td><span class='badge badge-warning'>" + escapeHtml(t.tracker) + "</span></td>" +
+                        "<td>" + formatGB(t.size_bytes) + "</td>" +
+                        "<td>" + escapeHtml(t.detail || t.reason) + "</td></tr>"
+                    ).join('') + "</tbody></table></div>";
+            }
+            document.getElementById('sim-result').innerHTML = html;
+        }
+
+        // Load torrents
+        async function loadTorrents() {
+            try {
+                const response = await apiFetch(apiV1 + '/torrents');
+                allTorrents = (await response.json()).torrents;
+
+                // Forget selected torrents that no longer exist
+                const existing = new Set(allTorrents.map(t => t.id));
+                selectedIds = new Set([...selectedIds].filter(id => existing.has(id)));
+
+                renderTorrents();
                 document.getElementById('torrents-update').textContent = 'Updated: ' + new Date().toLocaleTimeString();
             } catch (error) {
                 console.error('Failed to load torrents:', error);
-                document.getElementById('torrents-body').innerHTML = '<tr><td colspan="5" class="empty">Failed to load torrents</td></tr>';
+                document.getElementById('torrents-body').innerHTML = '<tr><td colspan="6" class="empty">Failed to load torrents</td></tr>';
+            }
+        }
+
+        // Torrents matching the filter input
+        function filteredTorrents() {
+            const filter = document.getElementById('torrent-filter').value.trim().toLowerCase();
+            if (!filter) {
+                return allTorrents;
+            }
+            return allTorrents.filter(t =>
+                t.name.toLowerCase().includes(filter) || t.normalizedTracker.toLowerCase().includes(filter));
+        }
+
+        // Render the torrents table
+        function renderTorrents() {
+            const tbody = document.getElementById('torrents-body');
+            const torrents = filteredTorrents();
+
+            if (torrents.length === 0) {
+                tbody.innerHTML = '<tr><td colspan="6" class="empty">No torrents found</td></tr>';
+                updateBulkBar();
+                return;
+            }
+
+            tbody.innerHTML = torrents.map(t => {
+                const size = (t.totalSize / (1024 * 1024 * 1024)).toFixed(2);
+                const date = new Date(t.addedDate).toLocaleDateString();
+                const isCandidate = candidateIds.includes(t.id);
+                const candidateClass = isCandidate ? " class='candidate'" : "";
+                const checked = selectedIds.has(t.id) ? " checked" : "";
+                return "<tr" + candidateClass + ">" +
+                    "<td>" + (canOperate() ? "<input type='checkbox' class='select-torrent' data-id='" + t.id + "'" + checked + ">" : "") + "</td>" +
+                    "<td title='" + escapeHtml(t.name) + "'>" + escapeHtml(truncate(t.name, 60)) + "</td>" +
+                    "<td><span class='badge badge-success'>" + escapeHtml(t.normalizedTracker) + "</span></td>" +
+                    "<td>" + size + " GB</td>" +
+                    "<td>" + date + "</td>" +
+                    "<td>" + (canOperate() ? "<button class='btn btn-danger delete-torrent' data-id='" + t.id + "'>Delete</button>" : "") + "</td>" +
+                    "</tr>";
+            }).join('');
+
+            updateBulkBar();
+        }
+
+        // Selection handling
+        function toggleSelect(id, checked) {
+            if (checked) {
+                selectedIds.add(id);
+            } else {
+                selectedIds.delete(id);
+            }
+            updateBulkBar();
+        }
+
+        function toggleSelectAll(checked) {
+            filteredTorrents().forEach(t => {
+                if (checked) {
+                    selectedIds.add(t.id);
+                } else {
+                    selectedIds.delete(t.id);
+                }
+            });
+            renderTorrents();
+        }
+
+        function clearSelection() {
+            selectedIds.clear();
+            renderTorrents();
+        }
+
+        function selectedTorrents() {
+            return allTorrents.filter(t => selectedIds.has(t.id));
+        }
+
+        function updateBulkBar() {
+            const selected = selectedTorrents();
+            const totalSize = selected.reduce((sum, t) => sum + t.totalSize, 0);
+            const bar = document.getElementById('bulk-bar');
+            bar.classList.toggle('visible', canOperate() && selected.length > 0);
+            document.getElementById('bulk-summary').textContent = selected.length + " selected (" +
+                (totalSize / (1024 * 1024 * 1024)).toFixed(2) + " GB)";
+
+            const filtered = filteredTorrents();
+            const selectAll = document.getElementById('select-all');
+            selectAll.style.display = canOperate() ? '' : 'none';
+            selectAll.checked = filtered.length > 0 && filtered.every(t => selectedIds.has(t.id));
+        }
+
+        // Bulk delete confirmation dialog
+        function confirmBulkDelete() {
+            const selected = selectedTorrents();
+            if (selected.length === 0) {
+                return;
             }
+            const totalSize = selected.reduce((sum, t) => sum + t.totalSize, 0);
+            document.getElementById('bulk-modal-summary').textContent = selected.length + " torrents will be deleted, freeing " +
+                (totalSize / (1024 * 1024 * 1024)).toFixed(2) + " GB.";
+            document.getElementById('bulk-modal-list').innerHTML = selected.map(t =>
+                "<li>" + escapeHtml(t.name) + " (" + (t.totalSize / (1024 * 1024 * 1024)).toFixed(2) + " GB)</li>"
+            ).join('');
+            document.getElementById('bulk-modal').classList.add('visible');
+        }
+
+        function closeBulkModal() {
+            document.getElementById('bulk-modal').classList.remove('visible');
+        }
+
+        // Delete all selected torrents with a single request
+        async function bulkDelete() {
+            const button = document.getElementById('bulk-confirm');
+            button.disabled = true;
+            try {
+                const response = await apiFetch(apiV1 + '/torrents/delete', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ ids: [...selectedIds] })
+                });
+                const data = await response.json();
+                closeBulkModal();
+
+                if (!response.ok) {
+                    alert('Failed to delete torrents: ' + (data.error ? data.error.message : response.statusText));
+                    return;
+                }
+
+                let message = data.deleted + " torrents deleted (" + (data.freed_bytes / (1024 * 1024 * 1024)).toFixed(2) + " GB freed)";
+                const failures = data.results.filter(r => r.status !== 'deleted');
+                if (failures.length > 0) {
+                    message += "\n\nNot deleted:\n" + failures.map(r => (r.name || r.ref) + ": " + r.error).join("\n");
+                }
+                alert(message);
+
+                selectedIds.clear();
+                loadTorrents();
+                loadStats();
+                loadHistory();
+            } catch (error) {
+                console.error('Failed to delete torrents:', error);
+                alert('Failed to delete torrents');
+            } finally {
+                button.disabled = false;
+            }
+        }
+
+        // Start a cleanup run; its result arrives over the WebSocket
+        let pendingRunId = null;
+
+        async function runCleanup() {
+            const body = {};
+            if (document.getElementById('run-dry-run').checked) {
+                body.dry_run = true;
+            }
+            const target = document.getElementById('run-target').value.trim();
+            if (target) {
+                body.min_free_space = target;
+            }
+
+            const button = document.getElementById('run-cleanup');
+            button.disabled = true;
+            try {
+                const response = await apiFetch(apiV1 + '/cleanup/run', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const data = await response.json();
+                if (!response.ok) {
+                    button.disabled = false;
+                    alert('Failed to start cleanup: ' + (data.error ? data.error.message : response.statusText));
+                    return;
+                }
+                pendingRunId = data.run_id;
+                document.getElementById('cleanup-result').textContent = 'Cleanup ' + data.run_id + ' ' + data.status + '...';
+            } catch (error) {
+                button.disabled = false;
+                console.error('Failed to start cleanup:', error);
+                alert('Failed to start cleanup');
+            }
+        }
+
+        function showCleanupResult(msg) {
+            const el = document.getElementById('cleanup-result');
+            if (msg.run_id === pendingRunId) {
+                pendingRunId = null;
+                document.getElementById('run-cleanup').disabled = false;
+            }
+
+            if (msg.error) {
+                el.textContent = 'Cleanup ' + msg.run_id + ' failed: ' + msg.error;
+            } else {
+                const r = msg.result;
+                const gb = (r.removed_size / (1024 * 1024 * 1024)).toFixed(2);
+                let text = 'Cleanup ' + r.run_id + ': ';
+                if (!r.need_cleanup) {
+                    text += 'no cleanup needed';
+                } else if (r.dry_run) {
+                    text += 'would remove ' + r.removed_count + ' torrents (' + gb + ' GB)';
+                } else {
+                    text += 'removed ' + r.removed_count + ' torrents (' + gb + ' GB)';
+                }
+                el.textContent = text;
+            }
+
+            loadStats();
+            loadTorrents();
+            loadHistory();
+            loadRuns();
         }
 
         // Delete torrent
-        async function deleteTorrent(id, name) {
+        async function deleteTorrent(id) {
+            const torrent = allTorrents.find(t => t.id === id);
+            const name = torrent ? torrent.name : 'torrent ' + id;
             if (!confirm("Are you sure you want to delete \"" + name + "\"?")) {
                 return;
             }
             
             try {
-                const response = await fetch(apiBase + "/api/delete?id=" + id, {
-                    method: 'POST'
+                const response = await apiFetch(apiV1 + "/torrents/" + id, {
+                    method: 'DELETE'
                 });
                 
                 if (response.ok) {
@@ -621,28 +1797,126 @@ func (s *Server) generateHTML(webRoot string) string {
         // Load logs (fallback if WebSocket fails)
         async function loadLogs() {
             try {
-                const response = await fetch(apiBase + '/api/logs');
-                const logs = await response.json();
-                displayLogs(logs);
+                const response = await apiFetch(apiV1 + '/logs');
+                logEntries = (await response.json()).logs;
+                renderLogs();
             } catch (error) {
                 console.error('Failed to load logs:', error);
             }
         }
 
-        // Display logs
-        function displayLogs(logs) {
+        // Add a log entry received from the WebSocket
+        function addLogEntry(log) {
+            logEntries.push(log);
+            if (logEntries.length > logBufferSize) {
+                logEntries.splice(0, logEntries.length - logBufferSize);
+            }
+            if (logsPaused) {
+                pausedLogs++;
+                document.getElementById('logs-pause').textContent = 'Resume (' + pausedLogs + ' new)';
+                return;
+            }
+            if (!logMatches(log)) return;
             const container = document.getElementById('logs-container');
-            container.innerHTML = logs.map(log => {
-                const time = new Date(log.timestamp).toLocaleTimeString();
-                return "<div class='log-entry'>" +
-                    "<span class='log-time'>" + time + "</span>" +
-                    "<span class='log-level " + log.level + "'>[" + log.level.toUpperCase() + "]</span>" +
-                    "<span>" + escapeHtml(log.message) + "</span>" +
-                    "</div>";
-            }).join('');
+            container.insertAdjacentHTML('beforeend', formatLogEntry(log));
+            while (container.childElementCount > logBufferSize) {
+                container.removeChild(container.firstElementChild);
+            }
+            container.scrollTop = container.scrollHeight;
+        }
+
+        // Display the log entries selected by the level toggles and the search
+        function renderLogs() {
+            const container = document.getElementById('logs-container');
+            container.innerHTML = logEntries.filter(logMatches).map(formatLogEntry).join('');
             container.scrollTop = container.scrollHeight;
         }
 
+        // Whether a log entry is selected by the level toggles and the search,
+        // matched like the q parameter of /api/v1/logs
+        function logMatches(log) {
+            const levels = Array.from(document.querySelectorAll('#log-levels input:checked')).map(el => el.value);
+            let level = log.level;
+            if (level === 'trace') level = 'debug';
+            if (level === 'fatal' || level === 'panic') level = 'error';
+            if (!levels.includes(level)) return false;
+
+            const query = document.getElementById('log-search').value.trim().toLowerCase();
+            if (!query || log.message.toLowerCase().includes(query)) return true;
+            return Object.entries(log.fields || {}).some(([key, value]) => (key + '=' + value).toLowerCase().includes(query));
+        }
+
+        // Stop or restart appending the live entries, kept meanwhile
+        function toggleLogsPause() {
+            logsPaused = !logsPaused;
+            pausedLogs = 0;
+            document.getElementById('logs-pause').textContent = logsPaused ? 'Resume' : 'Pause';
+            if (!logsPaused) renderLogs();
+        }
+
+        // Download the displayed log entries as a text file
+        function downloadLogs() {
+            const lines = logEntries.filter(logMatches).map(log => {
+                const fields = Object.keys(log.fields || {}).sort().map(key => ' ' + key + '=' + log.fields[key]).join('');
+                return log.timestamp + ' ' + log.level.toUpperCase() + ' ' + log.message + fields;
+            });
+            const blob = new Blob([lines.join('\n') + '\n'], { type: 'text/plain' });
+            const link = document.createElement('a');
+            link.href = URL.createObjectURL(blob);
+            link.download = 'btcleaner-' + new Date().toISOString().replace(/[:.]/g, '-') + '.log';
+            document.body.appendChild(link);
+            link.click();
+            document.body.removeChild(link);
+            URL.revokeObjectURL(link.href);
+        }
+
+        // Show the runtime log level to the operators
+        async function loadLogLevel() {
+            if (!canOperate()) return;
+            try {
+                const response = await apiFetch(apiV1 + '/config');
+                if (!response.ok) return;
+                document.getElementById('log-level-select').value = (await response.json()).settings.log_level;
+                document.getElementById('log-level-control').style.display = '';
+            } catch (error) {
+                console.error('Failed to load log level:', error);
+            }
+        }
+
+        // Change the runtime log level, like the Settings tab
+        async function changeLogLevel(level) {
+            try {
+                const response = await apiFetch(apiV1 + '/config', {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ log_level: level })
+                });
+                if (!response.ok) {
+                    const r = await response.json();
+                    alert('Failed to change log level: ' + (r.error ? r.error.message : response.statusText));
+                }
+            } catch (error) {
+                console.error('Failed to change log level:', error);
+                alert('Failed to change log level');
+            }
+            loadLogLevel();
+            loadSettings();
+        }
+
+        // Format a log entry, with its structured fields as key=value
+        function formatLogEntry(log) {
+            const time = new Date(log.timestamp).toLocaleTimeString();
+            const fields = Object.keys(log.fields || {}).sort().map(key =>
+                "<span class='log-field'>" + escapeHtml(key + '=' + log.fields[key]) + "</span>"
+            ).join('');
+            return "<div class='log-entry'>" +
+                "<span class='log-time'>" + time + "</span>" +
+                "<span class='log-level " + log.level + "'>[" + log.level.toUpperCase() + "]</span>" +
+                "<span>" + escapeHtml(log.message) + "</span>" +
+                fields +
+                "</div>";
+        }
+
         // WebSocket for real-time logs
         function connectWebSocket() {
             const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
@@ -653,6 +1927,9 @@ func (s *Server) generateHTML(webRoot string) string {
             ws.onopen = () => {
                 console.log('WebSocket connected');
                 document.getElementById('logs-status').textContent = '🟢 Live';
+                // The server sends its buffered entries first
+                logEntries = [];
+                renderLogs();
                 if (reconnectInterval) {
                     clearInterval(reconnectInterval);
                     reconnectInterval = null;
@@ -661,15 +1938,11 @@ func (s *Server) generateHTML(webRoot string) string {
             
             ws.onmessage = (event) => {
                 const log = JSON.parse(event.data);
-                const container = document.getElementById('logs-container');
-                const time = new Date(log.timestamp).toLocaleTimeString();
-                const entry = "<div class='log-entry'>" +
-                    "<span class='log-time'>" + time + "</span>" +
-                    "<span class='log-level " + log.level + "'>[" + log.level.toUpperCase() + "]</span>" +
-                    "<span>" + escapeHtml(log.message) + "</span>" +
-                    "</div>";
-                container.innerHTML += entry;
-                container.scrollTop = container.scrollHeight;
+                if (log.type === 'cleanup_result') {
+                    showCleanupResult(log);
+                    return;
+                }
+                addLogEntry(log);
             };
             
             ws.onerror = (error) => {
@@ -691,15 +1964,72 @@ func (s *Server) generateHTML(webRoot string) string {
             };
         }
 
+        // Live updates: refresh the dashboard when events are received
+        function connectEvents() {
+            const topics = ['torrent.deleted', 'cleanup.finished', 'space.changed', 'candidates.changed', 'config.reloaded'];
+            eventSource = new EventSource(apiV1 + '/events?topics=' + topics.join(','));
+
+            eventSource.onopen = () => {
+                eventsConnected = true;
+            };
+
+            eventSource.onerror = () => {
+                // The browser reconnects automatically, polling takes over meanwhile
+                eventsConnected = false;
+            };
+
+            eventSource.addEventListener('torrent.deleted', () => {
+                scheduleRefresh(loadStats);
+                scheduleRefresh(loadTorrents);
+                scheduleRefresh(loadHistory);
+            });
+            eventSource.addEventListener('cleanup.finished', () => {
+                scheduleRefresh(loadStats);
+                scheduleRefresh(loadRuns);
+            });
+            eventSource.addEventListener('space.changed', () => {
+                scheduleRefresh(loadStats);
+                scheduleRefresh(loadTorrents);
+            });
+            eventSource.addEventListener('candidates.changed', (event) => {
+                const data = JSON.parse(event.data).data;
+                candidateIds = data.ids;
+                renderTorrents();
+                scheduleRefresh(loadStats);
+            });
+            eventSource.addEventListener('config.reloaded', () => {
+                scheduleRefresh(loadStats);
+                if (document.getElementById('tab-settings').classList.contains('active')) {
+                    loadSettings(false);
+                }
+            });
+        }
+
+        // Run fn once after a burst of events
+        function scheduleRefresh(fn) {
+            if (pendingRefresh[fn.name]) return;
+            pendingRefresh[fn.name] = setTimeout(() => {
+                delete pendingRefresh[fn.name];
+                fn();
+            }, 500);
+        }
+
+        // Poll only while live updates are unavailable
+        function poll(fn) {
+            return () => {
+                if (!eventsConnected) fn();
+            };
+        }
+
         // Utility functions
         function truncate(str, len) {
             return str.length > len ? str.substring(0, len) + '...' : str;
         }
 
+        // Escape text for HTML content and quoted attributes
         function escapeHtml(text) {
-            const div = document.createElement('div');
-            div.textContent = text;
-            return div.innerHTML;
+            const entities = { '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' };
+            return String(text == null ? '' : text).replace(/[&<>"']/g, c => entities[c]);
         }
 
         function getTimeAgo(date) {
@@ -714,20 +2044,184 @@ func (s *Server) generateHTML(webRoot string) string {
             return days + 'd ago';
         }
 
+        // Row actions are bound here and find their torrent or run by data
+        // attributes: handlers built from names would let them inject script
+        document.getElementById('torrents-body').addEventListener('click', event => {
+            const button = event.target.closest('button.delete-torrent');
+            if (button) {
+                deleteTorrent(Number(button.dataset.id));
+            }
+        });
+        document.getElementById('torrents-body').addEventListener('change', event => {
+            if (event.target.matches('input.select-torrent')) {
+                toggleSelect(Number(event.target.dataset.id), event.target.checked);
+            }
+        });
+        document.getElementById('runs-body').addEventListener('click', event => {
+            const row = event.target.closest('tr[data-run-id]');
+            if (row) {
+                showRun(row.dataset.runId);
+            }
+        });
+        document.getElementById('history-list').addEventListener('click', event => {
+            const link = event.target.closest('.run-link[data-run-id]');
+            if (link) {
+                showRun(link.dataset.runId);
+            }
+        });
+
         // Initialize
-        loadStats();
-        loadTorrents();
-        loadHistory();
-        connectWebSocket();
-
-        // Auto-refresh stats and torrents
-        setInterval(loadStats, 10000); // Every 10 seconds
-        setInterval(loadTorrents, 30000); // Every 30 seconds
-        setInterval(loadHistory, 30000); // Every 30 seconds
+        loadUser().then(() => {
+            loadStats();
+            loadTorrents();
+            loadHistory();
+            connectWebSocket();
+            connectEvents();
+            loadLogLevel();
+        });
+
+        // Auto-refresh stats and torrents when live updates are unavailable
+        setInterval(poll(loadStats), 10000); // Every 10 seconds
+        setInterval(poll(loadTorrents), 30000); // Every 30 seconds
+        setInterval(poll(loadHistory), 30000); // Every 30 seconds
     </script>
 </body>
 </html>`
 	
-	// Replace the placeholder with the actual webRoot
+	// Replace the placeholders with the actual webRoot, CSRF token and log buffer size
+	html = strings.ReplaceAll(html, "{{CSRF_TOKEN}}", csrfToken)
+	html = strings.ReplaceAll(html, "{{LOG_BUFFER_SIZE}}", strconv.Itoa(s.logger.BufferSize()))
+	return strings.ReplaceAll(html, "{{WEBROOT}}", webRoot)
+}
+
+// generateLoginHTML generates the login page of the web interface
+func (s *Server) generateLoginHTML(webRoot, csrfToken string) string {
+	html := `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>BTCleaner - Login</title>
+    <style>
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif;
+            background: #f5f5f5;
+            color: #333;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            min-height: 100vh;
+        }
+
+        .login-card {
+            background: white;
+            padding: 30px;
+            border-radius: 8px;
+            box-shadow: 0 2px 5px rgba(0,0,0,0.1);
+            width: 100%;
+            max-width: 360px;
+        }
+
+        h1 {
+            font-size: 22px;
+            color: #2c3e50;
+            margin-bottom: 20px;
+            text-align: center;
+        }
+
+        label {
+            display: block;
+            font-size: 14px;
+            color: #666;
+            margin-bottom: 5px;
+        }
+
+        input {
+            width: 100%;
+            padding: 10px;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+            font-size: 14px;
+            margin-bottom: 15px;
+        }
+
+        button {
+            width: 100%;
+            padding: 10px;
+            border: none;
+            border-radius: 4px;
+            background: #3498db;
+            color: white;
+            font-size: 14px;
+            font-weight: 500;
+            cursor: pointer;
+        }
+
+        button:hover {
+            background: #2980b9;
+        }
+
+        .error {
+            color: #e74c3c;
+            font-size: 13px;
+            margin-bottom: 15px;
+            display: none;
+        }
+    </style>
+</head>
+<body>
+    <form class="login-card" id="login-form">
+        <h1>🧹 BTCleaner</h1>
+        <div class="error" id="error"></div>
+        <label for="username">Username</label>
+        <input type="text" id="username" autocomplete="username" required autofocus>
+        <label for="password">Password</label>
+        <input type="password" id="password" autocomplete="current-password" required>
+        <button type="submit">Log in</button>
+    </form>
+
+    <script>
+        const webRoot = '{{WEBROOT}}';
+        const apiBase = webRoot === '/' ? '' : webRoot;
+        const csrfToken = '{{CSRF_TOKEN}}';
+
+        document.getElementById('login-form').addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const errorDiv = document.getElementById('error');
+            errorDiv.style.display = 'none';
+
+            try {
+                const response = await fetch(apiBase + '/api/v1/auth/login', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken },
+                    body: JSON.stringify({
+                        username: document.getElementById('username').value,
+                        password: document.getElementById('password').value
+                    })
+                });
+
+                if (response.ok) {
+                    window.location.href = webRoot;
+                    return;
+                }
+
+                const data = await response.json();
+                errorDiv.textContent = data.error ? data.error.message : 'Login failed';
+            } catch (error) {
+                errorDiv.textContent = 'Login failed';
+            }
+            errorDiv.style.display = 'block';
+        });
+    </script>
+</body>
+</html>`
+
+	html = strings.ReplaceAll(html, "{{CSRF_TOKEN}}", csrfToken)
 	return strings.ReplaceAll(html, "{{WEBROOT}}", webRoot)
 }
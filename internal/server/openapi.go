@@ -0,0 +1,797 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// handleOpenAPI serves the OpenAPI document describing the /api/v1 endpoints
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	spec := strings.ReplaceAll(openAPISpec, "{{VERSION}}", s.version)
+	spec = strings.ReplaceAll(spec, "{{API_BASE}}", s.apiPath(""))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(spec))
+}
+
+// openAPISpec is the OpenAPI 3 document of the /api/v1 endpoints
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "BTCleaner API",
+    "description": "REST API of BTCleaner, the automatic Transmission seedbox cleaner. Browser requests that change state (POST, PUT, DELETE) must send the CSRF token of the page in the X-CSRF-Token header; requests authenticated with a bearer token are exempt.",
+    "version": "{{VERSION}}"
+  },
+  "servers": [
+    {"url": "{{API_BASE}}"}
+  ],
+  "security": [
+    {"bearerAuth": []},
+    {"sessionCookie": []}
+  ],
+  "paths": {
+    "/stats": {
+      "get": {
+        "operationId": "getStats",
+        "summary": "Current disk and torrent statistics",
+        "responses": {
+          "200": {"description": "Statistics", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Stats"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/stats/history": {
+      "get": {
+        "operationId": "getStatsHistory",
+        "summary": "Free space measured by the checks, with the fill rate and time to threshold",
+        "description": "One sample per check and after each cleanup, the last 1440 samples are kept (persisted in data_dir when set). The fill rate is the average decrease of free space over the last 6 hours of samples, increases being ignored.",
+        "parameters": [
+          {"name": "since", "in": "query", "required": false, "description": "Only the samples of this last duration, e.g. 6h", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Samples and trend", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SpaceHistoryResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/torrents": {
+      "get": {
+        "operationId": "listTorrents",
+        "summary": "List all torrents",
+        "responses": {
+          "200": {"description": "Torrents", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TorrentsResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/torrents/{id}": {
+      "parameters": [
+        {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}
+      ],
+      "get": {
+        "operationId": "getTorrent",
+        "summary": "Get a torrent",
+        "responses": {
+          "200": {"description": "Torrent", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Torrent"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteTorrent",
+        "summary": "Delete a torrent and its data",
+        "responses": {
+          "200": {"description": "Deleted torrent", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/DeleteResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/torrents/delete": {
+      "post": {
+        "operationId": "deleteTorrents",
+        "summary": "Delete several torrents by ID or hash with a single Transmission call",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BulkDeleteRequest"}}}},
+        "responses": {
+          "200": {"description": "Result for each requested torrent", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BulkDeleteResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/cleanup/run": {
+      "post": {
+        "operationId": "runCleanup",
+        "summary": "Start a cleanup run now",
+        "description": "The run is serialized with the daemon checks. Only one requested run is queued: requesting another one returns the queued run, or 409 if its options differ. Its result is sent to the /ws/logs WebSocket clients as a message of type cleanup_result.",
+        "requestBody": {"required": false, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CleanupRunRequest"}}}},
+        "responses": {
+          "202": {"description": "Run started or queued", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CleanupRunResponse"}}}},
+          "409": {"$ref": "#/components/responses/Error"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/simulate": {
+      "post": {
+        "operationId": "simulateCleanup",
+        "summary": "Compute what a cleanup would remove with hypothetical settings",
+        "description": "Uses the current free space and torrents. Nothing is removed and the live configuration is not changed. Requires the viewer role.",
+        "requestBody": {"required": false, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SimulateRequest"}}}},
+        "responses": {
+          "200": {"description": "Simulation result", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SimulationResult"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config": {
+      "get": {
+        "operationId": "getConfig",
+        "summary": "Runtime settings and the full configuration, secrets redacted",
+        "responses": {
+          "200": {"description": "Configuration", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ConfigResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "operationId": "updateSettings",
+        "summary": "Change runtime settings without restart",
+        "description": "Settings are validated with the rules of the config file and applied to the next cleanup checks. Unset fields keep their value. With persist, they are also written to the config file, keeping its comments; settings set by environment variables or flags cannot be persisted (400). Requires the operator role.",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SettingsRequest"}}}},
+        "responses": {
+          "200": {"description": "Applied settings", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SettingsResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/runs": {
+      "get": {
+        "operationId": "listRuns",
+        "summary": "Recorded cleanup runs, newest first",
+        "parameters": [
+          {"name": "limit", "in": "query", "required": false, "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "responses": {
+          "200": {"description": "Run summaries", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RunsResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/runs/{id}": {
+      "parameters": [
+        {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+      ],
+      "get": {
+        "operationId": "getRun",
+        "summary": "Full record of a cleanup run",
+        "responses": {
+          "200": {"description": "Run record", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RunRecord"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/candidates": {
+      "get": {
+        "operationId": "listCandidates",
+        "summary": "Torrents that would be deleted by a cleanup now",
+        "responses": {
+          "200": {"description": "Candidates", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CandidatesResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/history": {
+      "get": {
+        "operationId": "getHistory",
+        "summary": "Recently deleted torrents",
+        "responses": {
+          "200": {"description": "Deletion history", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/HistoryResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/logs": {
+      "get": {
+        "operationId": "getLogs",
+        "summary": "In-memory application logs, oldest first",
+        "description": "The last log_buffer_size entries are kept in memory. Filters combine: an entry is returned if it matches all of them.",
+        "parameters": [
+          {"name": "level", "in": "query", "required": false, "description": "Comma-separated levels of the entries, e.g. warn,error", "schema": {"type": "string"}},
+          {"name": "since", "in": "query", "required": false, "description": "Entries of this last duration (e.g. 15m) or since this RFC 3339 time", "schema": {"type": "string"}},
+          {"name": "q", "in": "query", "required": false, "description": "Case-insensitive text searched in the message and in the fields as key=value", "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "required": false, "description": "Number of most recent matching entries", "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "responses": {
+          "200": {"description": "Logs", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/LogsResponse"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/events": {
+      "get": {
+        "operationId": "streamEvents",
+        "summary": "Stream events as Server-Sent Events",
+        "description": "Each SSE message has the event ID, the topic as event name and an Event as data. Send Last-Event-ID to replay missed events. The same events are available on the <webroot>/ws/events WebSocket, one Event JSON object per message.",
+        "parameters": [
+          {"name": "topics", "in": "query", "description": "Comma separated topics, prefix.* wildcards allowed (e.g. cleanup.*). All topics if omitted.", "schema": {"type": "string"}},
+          {"name": "Last-Event-ID", "in": "header", "description": "ID of the last event received, to replay the missed ones", "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Event stream", "content": {"text/event-stream": {"schema": {"$ref": "#/components/schemas/Event"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/auth/login": {
+      "post": {
+        "operationId": "login",
+        "summary": "Log in with a local user and get a session cookie",
+        "security": [],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/LoginRequest"}}}},
+        "responses": {
+          "200": {"description": "Logged in", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/AuthInfo"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/auth/logout": {
+      "post": {
+        "operationId": "logout",
+        "summary": "Close the current session",
+        "security": [],
+        "responses": {
+          "204": {"description": "Logged out"}
+        }
+      }
+    },
+    "/auth/me": {
+      "get": {
+        "operationId": "getCurrentUser",
+        "summary": "Identity and role of the caller",
+        "responses": {
+          "200": {"description": "Current user", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/AuthInfo"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "operationId": "getOpenAPI",
+        "summary": "This document",
+        "security": [],
+        "responses": {
+          "200": {"description": "OpenAPI document", "content": {"application/json": {"schema": {"type": "object"}}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {"type": "http", "scheme": "bearer", "description": "API token (viewer or operator role)"},
+      "sessionCookie": {"type": "apiKey", "in": "cookie", "name": "btcleaner_session"}
+    },
+    "responses": {
+      "Error": {
+        "description": "Error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ErrorResponse"}}}
+      }
+    },
+    "schemas": {
+      "ErrorResponse": {
+        "type": "object",
+        "required": ["error"],
+        "properties": {
+          "error": {
+            "type": "object",
+            "required": ["code", "message"],
+            "properties": {
+              "code": {"type": "string", "example": "not_found"},
+              "message": {"type": "string"}
+            }
+          }
+        }
+      },
+      "Torrent": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"},
+          "hash": {"type": "string"},
+          "addedDate": {"type": "string", "format": "date-time"},
+          "totalSize": {"type": "integer", "format": "int64"},
+          "trackers": {"type": "array", "items": {"type": "string"}},
+          "normalizedTracker": {"type": "string"},
+          "status": {"type": "integer"},
+          "percentDone": {"type": "number"},
+          "sizeWhenDone": {"type": "integer", "format": "int64", "description": "Bytes of the wanted files"},
+          "leftUntilDone": {"type": "integer", "format": "int64", "description": "Bytes still to download"}
+        }
+      },
+      "TrackerStat": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "count": {"type": "integer"},
+          "size_bytes": {"type": "integer", "format": "int64"},
+          "size_gb": {"type": "number"}
+        }
+      },
+      "Stats": {
+        "type": "object",
+        "properties": {
+          "free_space_bytes": {"type": "integer", "format": "int64"},
+          "free_space_gb": {"type": "number"},
+          "min_free_space_bytes": {"type": "integer", "format": "int64"},
+          "min_free_space_gb": {"type": "number"},
+          "total_torrents": {"type": "integer"},
+          "total_space_bytes": {"type": "integer", "format": "int64"},
+          "total_space_gb": {"type": "number"},
+          "tracker_stats": {"type": "array", "items": {"$ref": "#/components/schemas/TrackerStat"}},
+          "needs_cleanup": {"type": "boolean"},
+          "candidates_count": {"type": "integer"},
+          "space_to_recover_bytes": {"type": "integer", "format": "int64"},
+          "space_to_recover_gb": {"type": "number"},
+          "pending_download_bytes": {"type": "integer", "format": "int64", "description": "Bytes left to download by the active torrents"},
+          "projected_free_space_bytes": {"type": "integer", "format": "int64", "description": "Free space once the active downloads complete"},
+          "projected_free_space_gb": {"type": "number"},
+          "predictive": {"type": "boolean", "description": "Cleanups make room for the active downloads: needs_cleanup uses the projected free space"},
+          "blocked": {"$ref": "#/components/schemas/Blocked"},
+          "schedule": {"$ref": "#/components/schemas/ScheduleState"},
+          "trend": {"$ref": "#/components/schemas/SpaceTrend"}
+        }
+      },
+      "SpaceSample": {
+        "type": "object",
+        "properties": {
+          "time": {"type": "string", "format": "date-time"},
+          "free_space": {"type": "integer", "format": "int64"},
+          "min_free_space": {"type": "integer", "format": "int64", "description": "Configured minimum at the time"}
+        }
+      },
+      "SpaceTrend": {
+        "type": "object",
+        "properties": {
+          "samples": {"type": "integer", "description": "Samples within the trend window"},
+          "window_seconds": {"type": "integer", "format": "int64", "description": "Time between the first and last of these samples"},
+          "fill_rate_bytes_per_hour": {"type": "integer", "format": "int64", "description": "Average decrease of free space, increases (cleanups, deletions) ignored"},
+          "time_to_threshold_seconds": {"type": "integer", "format": "int64", "description": "Until free space drops below the minimum, 0 if it already is, absent if it does not decrease"},
+          "threshold_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "SpaceHistoryResponse": {
+        "type": "object",
+        "properties": {
+          "samples": {"type": "array", "items": {"$ref": "#/components/schemas/SpaceSample"}, "description": "Oldest first"},
+          "trend": {"$ref": "#/components/schemas/SpaceTrend"}
+        }
+      },
+      "ScheduleState": {
+        "type": "object",
+        "description": "Daemon schedule, absent outside daemon mode",
+        "properties": {
+          "schedule": {"type": "string", "description": "every <interval> or cron <expression>"},
+          "next_run_at": {"type": "string", "format": "date-time"},
+          "deletion_allowed": {"type": "boolean", "description": "False within a blocked window or outside the delete windows"},
+          "next_deletion_at": {"type": "string", "format": "date-time", "description": "Start of the next delete window, when deletions are not allowed now"},
+          "emergency_free_space_bytes": {"type": "integer", "format": "int64", "description": "Windows are ignored below this free space"}
+        }
+      },
+      "TorrentsResponse": {
+        "type": "object",
+        "properties": {
+          "torrents": {"type": "array", "items": {"$ref": "#/components/schemas/Torrent"}},
+          "count": {"type": "integer"}
+        }
+      },
+      "CandidatesResponse": {
+        "type": "object",
+        "properties": {
+          "candidates": {"type": "array", "items": {"$ref": "#/components/schemas/Torrent"}},
+          "count": {"type": "integer"},
+          "total_size_bytes": {"type": "integer", "format": "int64"}
+        }
+      },
+      "DeletedTorrent": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"},
+          "size": {"type": "integer", "format": "int64"},
+          "size_gb": {"type": "number"},
+          "tracker": {"type": "string"},
+          "deleted_at": {"type": "string", "format": "date-time"},
+          "reason": {"type": "string", "enum": ["auto", "manual"]},
+          "run_id": {"type": "string", "description": "Cleanup run that deleted the torrent (auto deletions)"}
+        }
+      },
+      "HistoryResponse": {
+        "type": "object",
+        "properties": {
+          "history": {"type": "array", "items": {"$ref": "#/components/schemas/DeletedTorrent"}}
+        }
+      },
+      "LogEntry": {
+        "type": "object",
+        "properties": {
+          "timestamp": {"type": "string", "format": "date-time"},
+          "level": {"type": "string"},
+          "message": {"type": "string"},
+          "fields": {
+            "type": "object",
+            "description": "Structured fields of the entry, e.g. run_id, torrent_id, hash, tracker and bytes",
+            "additionalProperties": true
+          }
+        }
+      },
+      "LogsResponse": {
+        "type": "object",
+        "properties": {
+          "logs": {"type": "array", "items": {"$ref": "#/components/schemas/LogEntry"}},
+          "buffer_size": {"type": "integer", "description": "Entries kept in memory"}
+        }
+      },
+      "Event": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "topic": {"type": "string", "enum": ["torrent.deleted", "cleanup.started", "cleanup.finished", "cleanup.blocked", "space.changed", "candidates.changed", "config.reloaded"]},
+          "time": {"type": "string", "format": "date-time"},
+          "data": {
+            "oneOf": [
+              {"$ref": "#/components/schemas/TorrentDeletedEvent"},
+              {"$ref": "#/components/schemas/CleanupStartedEvent"},
+              {"$ref": "#/components/schemas/CleanupFinishedEvent"},
+              {"$ref": "#/components/schemas/CleanupBlockedEvent"},
+              {"$ref": "#/components/schemas/SpaceChangedEvent"},
+              {"$ref": "#/components/schemas/CandidatesChangedEvent"},
+              {"$ref": "#/components/schemas/ConfigReloadedEvent"}
+            ]
+          }
+        }
+      },
+      "TorrentDeletedEvent": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"},
+          "hash": {"type": "string"},
+          "tracker": {"type": "string"},
+          "size_bytes": {"type": "integer", "format": "int64"},
+          "reason": {"type": "string", "enum": ["auto", "manual"]},
+          "run_id": {"type": "string"}
+        }
+      },
+      "CleanupStartedEvent": {
+        "type": "object",
+        "properties": {
+          "run_id": {"type": "string"},
+          "trigger": {"type": "string"},
+          "dry_run": {"type": "boolean"},
+          "min_free_space": {"type": "integer", "format": "int64"}
+        }
+      },
+      "CleanupFinishedEvent": {
+        "type": "object",
+        "properties": {
+          "run_id": {"type": "string"},
+          "trigger": {"type": "string"},
+          "status": {"type": "string", "enum": ["success", "partial", "failed"]},
+          "dry_run": {"type": "boolean"},
+          "need_cleanup": {"type": "boolean"},
+          "initial_free_space": {"type": "integer", "format": "int64"},
+          "final_free_space": {"type": "integer", "format": "int64"},
+          "removed_count": {"type": "integer"},
+          "removed_size": {"type": "integer", "format": "int64"},
+          "duration_ms": {"type": "integer", "format": "int64"},
+          "space_shortfall": {"type": "integer", "format": "int64", "description": "Bytes still missing to reach the minimum free space"},
+          "error": {"type": "string"}
+        }
+      },
+      "CleanupBlockedEvent": {
+        "type": "object",
+        "properties": {
+          "run_id": {"type": "string"},
+          "dry_run": {"type": "boolean"},
+          "shortfall_bytes": {"type": "integer", "format": "int64"},
+          "trackers": {"type": "array", "items": {"type": "string"}, "description": "Trackers whose minimums kept torrents"},
+          "resolvable": {"type": "boolean"},
+          "message": {"type": "string"}
+        }
+      },
+      "SpaceChangedEvent": {
+        "type": "object",
+        "properties": {
+          "free_space": {"type": "integer", "format": "int64"},
+          "previous_free_space": {"type": "integer", "format": "int64"},
+          "min_free_space": {"type": "integer", "format": "int64"},
+          "needs_cleanup": {"type": "boolean"}
+        }
+      },
+      "CandidatesChangedEvent": {
+        "type": "object",
+        "properties": {
+          "ids": {"type": "array", "items": {"type": "integer"}},
+          "count": {"type": "integer"},
+          "total_size_bytes": {"type": "integer", "format": "int64"}
+        }
+      },
+      "ConfigReloadedEvent": {
+        "type": "object",
+        "properties": {
+          "source": {"type": "string"},
+          "changes": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "LoginRequest": {
+        "type": "object",
+        "required": ["username", "password"],
+        "properties": {
+          "username": {"type": "string"},
+          "password": {"type": "string", "format": "password"}
+        }
+      },
+      "AuthInfo": {
+        "type": "object",
+        "properties": {
+          "auth_enabled": {"type": "boolean"},
+          "username": {"type": "string"},
+          "role": {"type": "string", "enum": ["viewer", "operator"]},
+          "method": {"type": "string", "enum": ["none", "session", "token", "trusted_header"]}
+        }
+      },
+      "DeleteResponse": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"},
+          "size_bytes": {"type": "integer", "format": "int64"},
+          "deleted": {"type": "boolean"}
+        }
+      },
+      "CleanupRunRequest": {
+        "type": "object",
+        "properties": {
+          "dry_run": {"type": "boolean", "description": "Overrides the configured dry-run mode"},
+          "min_free_space": {"oneOf": [{"type": "integer"}, {"type": "string", "example": "500GB"}], "description": "Overrides the target free space (bytes or size with unit)"}
+        }
+      },
+      "CleanupRunResponse": {
+        "type": "object",
+        "properties": {
+          "run_id": {"type": "string"},
+          "status": {"type": "string", "enum": ["started", "queued"]}
+        }
+      },
+      "CleanupResult": {
+        "type": "object",
+        "properties": {
+          "run_id": {"type": "string"},
+          "dry_run": {"type": "boolean"},
+          "min_free_space": {"type": "integer", "format": "int64"},
+          "initial_free_space": {"type": "integer", "format": "int64"},
+          "final_free_space": {"type": "integer", "format": "int64"},
+          "removed_count": {"type": "integer"},
+          "removed_size": {"type": "integer", "format": "int64"},
+          "removed_torrents": {"type": "array", "items": {"$ref": "#/components/schemas/Torrent"}},
+          "need_cleanup": {"type": "boolean"},
+          "candidates_considered": {"type": "integer"},
+          "skipped": {"type": "array", "items": {"$ref": "#/components/schemas/SkippedTorrent"}},
+          "errors": {"type": "array", "items": {"type": "string"}},
+          "blocked": {"$ref": "#/components/schemas/Blocked"},
+          "deferred": {"type": "boolean", "description": "Outside the deletion windows: the torrents to remove were selected but not removed"},
+          "deferred_until": {"type": "string", "format": "date-time"},
+          "emergency": {"type": "boolean", "description": "Removed outside the deletion windows, free space being below the emergency floor"},
+          "pending_downloads": {"type": "integer", "format": "int64", "description": "Bytes left to download by the active torrents, subtracted from the free space by predictive cleanups"}
+        }
+      },
+      "Blocked": {
+        "type": "object",
+        "description": "Set when the minimum free space cannot be reached, usually because of tracker minimums",
+        "properties": {
+          "shortfall_bytes": {"type": "integer", "format": "int64", "description": "Space still missing after removing every removable torrent"},
+          "constraints": {
+            "type": "array",
+            "description": "Tracker minimums that kept torrents, most kept space first",
+            "items": {
+              "type": "object",
+              "properties": {
+                "tracker": {"type": "string"},
+                "min_torrents": {"type": "integer"},
+                "torrents": {"type": "integer"},
+                "kept_torrents": {"type": "integer"},
+                "kept_bytes": {"type": "integer", "format": "int64"}
+              }
+            }
+          },
+          "suggestions": {
+            "type": "array",
+            "description": "Tracker minimums to lower to reach the minimum free space",
+            "items": {
+              "type": "object",
+              "properties": {
+                "tracker": {"type": "string"},
+                "current": {"type": "integer"},
+                "suggested": {"type": "integer"},
+                "freed_bytes": {"type": "integer", "format": "int64"}
+              }
+            }
+          },
+          "resolvable": {"type": "boolean", "description": "Whether the suggestions free enough space"},
+          "message": {"type": "string"}
+        }
+      },
+      "Size": {
+        "oneOf": [{"type": "integer", "format": "int64"}, {"type": "string", "example": "500GB"}],
+        "description": "Bytes, or size with unit (KB, MB, GB, TB)"
+      },
+      "Policy": {
+        "type": "object",
+        "properties": {
+          "min_free_space": {"type": "integer", "format": "int64"},
+          "min_torrents_per_tracker": {"type": "integer"},
+          "tracker_minimums": {"type": "object", "additionalProperties": {"type": "integer"}},
+          "strategy": {"type": "string", "enum": ["oldest", "largest"]},
+          "predictive": {"type": "boolean", "description": "Subtract the bytes left to download by the active torrents from the free space"}
+        }
+      },
+      "SimulateRequest": {
+        "type": "object",
+        "properties": {
+          "min_free_space": {"$ref": "#/components/schemas/Size"},
+          "min_torrents_per_tracker": {"type": "integer", "minimum": 0},
+          "tracker_minimums": {"type": "object", "additionalProperties": {"type": "integer", "minimum": 0}, "description": "Replaces the configured per-tracker minimums"},
+          "strategy": {"type": "string", "enum": ["oldest", "largest"]},
+          "predictive": {"type": "boolean", "description": "Whether the active downloads must fit"},
+          "incoming_size": {"$ref": "#/components/schemas/Size"}
+        }
+      },
+      "Settings": {
+        "type": "object",
+        "properties": {
+          "min_free_space": {"type": "integer", "format": "int64"},
+          "min_torrents_per_tracker": {"type": "integer"},
+          "tracker_minimums": {"type": "object", "additionalProperties": {"type": "integer"}},
+          "strategy": {"type": "string", "enum": ["oldest", "largest"]},
+          "check_interval": {"type": "string", "description": "Go duration, e.g. 5m0s"},
+          "dry_run": {"type": "boolean"},
+          "log_level": {"type": "string"}
+        }
+      },
+      "ConfigResponse": {
+        "type": "object",
+        "properties": {
+          "settings": {"$ref": "#/components/schemas/Settings"},
+          "file": {"type": "string", "description": "Config file, absent if none"},
+          "config": {"type": "object", "additionalProperties": true, "description": "Full configuration with the keys of the config file, secrets and notification URLs redacted"}
+        }
+      },
+      "SettingsRequest": {
+        "type": "object",
+        "properties": {
+          "min_free_space": {"$ref": "#/components/schemas/Size"},
+          "min_torrents_per_tracker": {"type": "integer", "minimum": 0},
+          "tracker_minimums": {"type": "object", "additionalProperties": {"type": "integer", "minimum": 0}, "description": "Replaces all per-tracker minimums, {} to remove them"},
+          "strategy": {"type": "string", "enum": ["oldest", "largest"]},
+          "check_interval": {"type": "string", "description": "Go duration, e.g. 10m"},
+          "dry_run": {"type": "boolean"},
+          "log_level": {"type": "string", "enum": ["debug", "info", "warn", "error"]},
+          "persist": {"type": "boolean", "description": "Also write the settings to the config file"}
+        }
+      },
+      "SettingsResponse": {
+        "type": "object",
+        "properties": {
+          "settings": {"$ref": "#/components/schemas/Settings"},
+          "changes": {"type": "array", "items": {"type": "string"}, "description": "Changed settings, as key: old -> new"},
+          "persisted": {"type": "boolean"},
+          "warning": {"type": "string", "description": "Set when changes are applied without persist: they are lost on restart and when the configuration is reloaded"}
+        }
+      },
+      "SimulationResult": {
+        "type": "object",
+        "properties": {
+          "policy": {"$ref": "#/components/schemas/Policy"},
+          "free_space": {"type": "integer", "format": "int64"},
+          "incoming_size": {"type": "integer", "format": "int64"},
+          "pending_downloads": {"type": "integer", "format": "int64", "description": "Bytes left to download by the active torrents, counted with a predictive policy"},
+          "space_needed": {"type": "integer", "format": "int64"},
+          "need_cleanup": {"type": "boolean"},
+          "selected": {"type": "array", "items": {"$ref": "#/components/schemas/Torrent"}, "description": "In removal order"},
+          "selected_size": {"type": "integer", "format": "int64"},
+          "projected_free_space": {"type": "integer", "format": "int64"},
+          "sufficient": {"type": "boolean"},
+          "considered": {"type": "integer"},
+          "skipped": {"type": "array", "items": {"$ref": "#/components/schemas/SkippedTorrent"}},
+          "blocked": {"$ref": "#/components/schemas/Blocked"}
+        }
+      },
+      "SkippedTorrent": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"},
+          "tracker": {"type": "string"},
+          "size_bytes": {"type": "integer", "format": "int64"},
+          "reason": {"type": "string", "enum": ["tracker_minimum", "remove_failed"]},
+          "detail": {"type": "string"}
+        }
+      },
+      "RunRecord": {
+        "allOf": [
+          {"$ref": "#/components/schemas/CleanupResult"},
+          {
+            "type": "object",
+            "properties": {
+              "trigger": {"type": "string", "enum": ["startup", "timer", "api", "event", "cli"]},
+              "status": {"type": "string", "enum": ["success", "partial", "failed"]},
+              "started_at": {"type": "string", "format": "date-time"},
+              "finished_at": {"type": "string", "format": "date-time"},
+              "duration_ms": {"type": "integer", "format": "int64"}
+            }
+          }
+        ]
+      },
+      "RunSummary": {
+        "type": "object",
+        "properties": {
+          "run_id": {"type": "string"},
+          "trigger": {"type": "string", "enum": ["startup", "timer", "api", "event", "cli"]},
+          "status": {"type": "string", "enum": ["success", "partial", "failed"]},
+          "started_at": {"type": "string", "format": "date-time"},
+          "finished_at": {"type": "string", "format": "date-time"},
+          "duration_ms": {"type": "integer", "format": "int64"},
+          "dry_run": {"type": "boolean"},
+          "deferred": {"type": "boolean"},
+          "emergency": {"type": "boolean"},
+          "need_cleanup": {"type": "boolean"},
+          "initial_free_space": {"type": "integer", "format": "int64"},
+          "final_free_space": {"type": "integer", "format": "int64"},
+          "candidates_considered": {"type": "integer"},
+          "removed_count": {"type": "integer"},
+          "removed_size": {"type": "integer", "format": "int64"},
+          "skipped_count": {"type": "integer"},
+          "error_count": {"type": "integer"}
+        }
+      },
+      "RunsResponse": {
+        "type": "object",
+        "properties": {
+          "runs": {"type": "array", "items": {"$ref": "#/components/schemas/RunSummary"}},
+          "count": {"type": "integer"}
+        }
+      },
+      "BulkDeleteRequest": {
+        "type": "object",
+        "properties": {
+          "ids": {"type": "array", "items": {"type": "integer"}},
+          "hashes": {"type": "array", "items": {"type": "string"}},
+          "delete_data": {"type": "boolean", "default": true}
+        }
+      },
+      "DeleteResult": {
+        "type": "object",
+        "properties": {
+          "ref": {"type": "string", "description": "Requested ID or hash"},
+          "id": {"type": "integer"},
+          "hash": {"type": "string"},
+          "name": {"type": "string"},
+          "size_bytes": {"type": "integer", "format": "int64"},
+          "status": {"type": "string", "enum": ["deleted", "not_found", "failed"]},
+          "error": {"type": "string"}
+        }
+      },
+      "BulkDeleteResponse": {
+        "type": "object",
+        "properties": {
+          "results": {"type": "array", "items": {"$ref": "#/components/schemas/DeleteResult"}},
+          "deleted": {"type": "integer"},
+          "not_found": {"type": "integer"},
+          "failed": {"type": "integer"},
+          "freed_bytes": {"type": "integer", "format": "int64"}
+        }
+      }
+    }
+  }
+}
+`
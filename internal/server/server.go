@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/events"
 	"github.com/Celedhrim/btcleaner/internal/logger"
 	"github.com/Celedhrim/btcleaner/internal/transmission"
 	"github.com/gorilla/websocket"
@@ -19,20 +22,37 @@ import (
 
 // Server represents the web server
 type Server struct {
-	port        int
-	webRoot     string
-	version     string
-	cleaner     *cleaner.Cleaner
-	client      *transmission.Client
-	logger      *logger.Logger
-	srv         *http.Server
-	upgrader    websocket.Upgrader
-	logClients  map[*websocket.Conn]bool
-	clientMutex sync.RWMutex
+	port           int
+	webRoot        string
+	ui             bool
+	metrics        http.Handler
+	version        string
+	cleaner        *cleaner.Cleaner
+	client         *transmission.Client
+	logger         *logger.Logger
+	auth           *authenticator
+	allowedOrigins map[string]bool
+	srv            *http.Server
+	upgrader       websocket.Upgrader
+	wsConfig       config.WebSocketConfig
+	wsClients      map[*wsClient]struct{}
+	wsWG           sync.WaitGroup // Running WebSocket writers
+	clientMutex    sync.RWMutex
+	events         *events.Bus
+	done           chan struct{} // Closed when the server stops, ends event streams
+	readyMaxAge    time.Duration
+	configErr      error           // Configuration reload that was rejected, reported by /readyz
+	settings       SettingsManager // nil without runtime settings
+	queueMutex     sync.Mutex
+	queuedRun      *cleaner.RunOptions // Cleanup run requested through the API and not started yet
+	healthMutex    sync.RWMutex
+	ready          readyState
+	stopOnce       sync.Once
 }
 
 // New creates a new server instance
-func New(port int, webRoot string, version string, cleaner *cleaner.Cleaner, client *transmission.Client, log *logger.Logger) *Server {
+func New(cfg config.ServerConfig, version string, cleaner *cleaner.Cleaner, client *transmission.Client, log *logger.Logger) (*Server, error) {
+	webRoot := cfg.WebRoot
 	if !strings.HasPrefix(webRoot, "/") {
 		webRoot = "/" + webRoot
 	}
@@ -40,16 +60,33 @@ func New(port int, webRoot string, version string, cleaner *cleaner.Cleaner, cli
 		webRoot = webRoot + "/"
 	}
 
-	return &Server{
-		port:       port,
-		webRoot:    webRoot,
-		version:    version,
-		cleaner:    cleaner,
-		client:     client,
-		logger:     log,
-		upgrader:   websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
-		logClients: make(map[*websocket.Conn]bool),
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedOrigins, err := parseAllowedOrigins(cfg.AllowedOrigins)
+	if err != nil {
+		return nil, err
 	}
+
+	s := &Server{
+		port:           cfg.Port,
+		webRoot:        webRoot,
+		ui:             cfg.UI,
+		version:        version,
+		cleaner:        cleaner,
+		client:         client,
+		logger:         log,
+		auth:           auth,
+		allowedOrigins: allowedOrigins,
+		wsConfig:       cfg.WebSocket,
+		wsClients:      make(map[*wsClient]struct{}),
+		done:           make(chan struct{}),
+	}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkWebSocketOrigin}
+
+	return s, nil
 }
 
 // Start starts the web server
@@ -64,37 +101,77 @@ func (s *Server) Start() error {
 		stripPrefix = strings.TrimSuffix(stripPrefix, "/")
 	}
 
-	// API endpoints
-	mux.HandleFunc(stripPrefix+"/api/stats", s.handleStats)
-	mux.HandleFunc(stripPrefix+"/api/torrents", s.handleTorrents)
-	mux.HandleFunc(stripPrefix+"/api/logs", s.handleLogs)
-	mux.HandleFunc(stripPrefix+"/api/delete", s.handleDelete)
-	mux.HandleFunc(stripPrefix+"/api/candidates", s.handleCandidates)
-	mux.HandleFunc(stripPrefix+"/api/history", s.handleHistory)
-	mux.HandleFunc(stripPrefix+"/ws/logs", s.handleWebSocketLogs)
+	// Versioned API endpoints
+	s.registerAPIv1(mux, stripPrefix)
+
+	// Legacy API endpoints (deprecated, kept for compatibility)
+	mux.HandleFunc(stripPrefix+"/api/stats", s.protect(s.deprecated("/stats", s.handleStats)))
+	mux.HandleFunc(stripPrefix+"/api/torrents", s.protect(s.deprecated("/torrents", s.handleTorrents)))
+	mux.HandleFunc(stripPrefix+"/api/logs", s.protect(s.deprecated("/logs", s.handleLogs)))
+	mux.HandleFunc(stripPrefix+"/api/delete", s.protect(s.deprecated("/torrents/{id}", s.handleDelete)))
+	mux.HandleFunc(stripPrefix+"/api/candidates", s.protect(s.deprecated("/candidates", s.handleCandidates)))
+	mux.HandleFunc(stripPrefix+"/api/history", s.protect(s.deprecated("/history", s.handleHistory)))
+	mux.HandleFunc(stripPrefix+"/api/stats/history", s.protect(s.deprecated("/stats/history", s.handleV1StatsHistory)))
+	mux.HandleFunc(stripPrefix+"/api/torrents/delete", s.protect(s.deprecated("/torrents/delete", s.handleV1BulkDelete)))
+	mux.HandleFunc(stripPrefix+"/api/cleanup/run", s.protect(s.deprecated("/cleanup/run", s.handleV1CleanupRun)))
+	mux.HandleFunc(stripPrefix+"/api/simulate", s.requireRole(config.RoleViewer, s.deprecated("/simulate", s.handleV1Simulate)))
+	mux.HandleFunc(stripPrefix+"/api/runs", s.protect(s.deprecated("/runs", s.handleV1Runs)))
+	mux.HandleFunc(stripPrefix+"/api/runs/{id}", s.protect(s.deprecated("/runs/{id}", s.handleV1Run)))
+	mux.HandleFunc(stripPrefix+"/api/config", s.protect(s.deprecated("/config", s.handleV1Config)))
+	mux.HandleFunc(stripPrefix+"/ws/logs", s.protect(s.handleWebSocketLogs))
+	mux.HandleFunc(stripPrefix+"/ws/events", s.protect(s.handleWebSocketEvents))
+
+	// Health checks for container orchestration, without authentication
+	mux.HandleFunc(stripPrefix+"/healthz", s.handleHealthz)
+	mux.HandleFunc(stripPrefix+"/readyz", s.handleReadyz)
+
+	// Prometheus metrics
+	if s.metrics != nil {
+		mux.HandleFunc(stripPrefix+"/metrics", s.protect(s.metrics.ServeHTTP))
+	}
 
-	// Static files and root
-	mux.HandleFunc(stripPrefix+"/", s.handleRoot)
+	// Login page, static files and root
+	if s.ui {
+		mux.HandleFunc(stripPrefix+"/login", s.handleLoginPage)
+		mux.HandleFunc(stripPrefix+"/", s.handleRoot)
+	} else {
+		s.logger.Info("Web UI is disabled, only the API and metrics are served")
+	}
+
+	if !s.auth.cfg.Enabled {
+		s.logger.Warn("Web UI authentication is disabled: anyone who can reach the web server can delete torrents")
+	}
 
 	s.srv = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.port),
 		Handler: mux,
 	}
 
+	go s.watchChanges()
+
 	s.logger.Infof("Starting web server on http://0.0.0.0:%d%s", s.port, stripPrefix)
 
 	return s.srv.ListenAndServe()
 }
 
+// SetMetricsHandler sets the handler serving Prometheus metrics on <webroot>/metrics
+func (s *Server) SetMetricsHandler(h http.Handler) {
+	s.metrics = h
+}
+
 // Stop stops the web server gracefully
 func (s *Server) Stop() error {
 	if s.srv == nil {
 		return nil
 	}
+	s.stopOnce.Do(func() { close(s.done) })
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Hijacked WebSocket connections are not closed by Shutdown
+	s.closeWebSockets(ctx)
+
 	return s.srv.Shutdown(ctx)
 }
 
@@ -141,7 +218,12 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logs := s.logger.GetLogs()
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logs := s.logger.Query(filter)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(logs)
@@ -166,42 +248,20 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get torrent info first
-	torrents, err := s.client.GetTorrents()
-	if err != nil {
-		http.Error(w, "Failed to get torrent info", http.StatusInternalServerError)
+	torrent, err := s.deleteTorrent(id, true)
+	if errors.Is(err, errTorrentNotFound) {
+		http.Error(w, "Torrent not found", http.StatusNotFound)
 		return
 	}
-
-	var torrentName string
-	var torrentTracker string
-	var torrentSize int64
-	for _, t := range torrents {
-		if t.ID == id {
-			torrentName = t.Name
-			torrentTracker = t.NormalizedTracker
-			torrentSize = t.TotalSize
-			break
-		}
-	}
-
-	// Delete torrent
-	err = s.client.RemoveTorrent(id, true)
 	if err != nil {
-		s.logger.Errorf("Failed to delete torrent %d: %v", id, err)
 		http.Error(w, "Failed to delete torrent", http.StatusInternalServerError)
 		return
 	}
 
-	// Add to history
-	s.cleaner.AddManualDeletion(id, torrentName, torrentTracker, torrentSize)
-
-	s.logger.Infof("Manually deleted torrent: %s (ID: %d)", torrentName, id)
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": fmt.Sprintf("Torrent %d deleted successfully", id),
+		"message": fmt.Sprintf("Torrent %d deleted successfully", torrent.ID),
 	})
 }
 
@@ -242,62 +302,14 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(history)
 }
 
-// handleWebSocketLogs handles WebSocket connections for real-time logs
-func (s *Server) handleWebSocketLogs(w http.ResponseWriter, r *http.Request) {
-	conn, err := s.upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		s.logger.Errorf("WebSocket upgrade failed: %v", err)
-		return
-	}
-
-	s.clientMutex.Lock()
-	s.logClients[conn] = true
-	s.clientMutex.Unlock()
-
-	s.logger.Debug("New WebSocket client connected")
-
-	// Send existing logs
-	logs := s.logger.GetLogs()
-	for _, log := range logs {
-		if err := conn.WriteJSON(log); err != nil {
-			break
-		}
-	}
-
-	// Keep connection alive and handle close
-	defer func() {
-		s.clientMutex.Lock()
-		delete(s.logClients, conn)
-		s.clientMutex.Unlock()
-		conn.Close()
-		s.logger.Debug("WebSocket client disconnected")
-	}()
-
-	// Read messages (just to detect disconnect)
-	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
-		}
-	}
-}
-
-// BroadcastLog broadcasts a log entry to all connected WebSocket clients
-func (s *Server) BroadcastLog(log logger.LogEntry) {
-	s.clientMutex.RLock()
-	defer s.clientMutex.RUnlock()
-
-	for client := range s.logClients {
-		err := client.WriteJSON(log)
-		if err != nil {
-			s.logger.Debugf("Failed to send log to WebSocket client: %v", err)
-		}
-	}
-}
-
 // handleRoot serves the HTML interface
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
-	// Serve index.html for root
+	// Serve index.html for root, redirecting to the login page if needed
 	if r.URL.Path == s.webRoot || r.URL.Path == strings.TrimSuffix(s.webRoot, "/") {
+		if s.auth.authenticate(r) == nil {
+			http.Redirect(w, r, strings.TrimSuffix(s.webRoot, "/")+"/login", http.StatusFound)
+			return
+		}
 		s.serveHTML(w, r)
 		return
 	}
@@ -309,14 +321,19 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 
 // serveHTML serves the main HTML interface
 func (s *Server) serveHTML(w http.ResponseWriter, r *http.Request) {
+	csrfToken := s.ensureCSRFToken(w, r)
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	
-	// Get webroot for use in HTML
+
+	html := s.generateHTML(s.htmlWebRoot(), csrfToken)
+	w.Write([]byte(html))
+}
+
+// htmlWebRoot returns the webroot as used in the HTML pages ("/" or "/prefix")
+func (s *Server) htmlWebRoot() string {
 	webRoot := strings.TrimSuffix(s.webRoot, "/")
 	if webRoot == "" {
 		webRoot = "/"
 	}
-
-	html := s.generateHTML(webRoot)
-	w.Write([]byte(html))
+	return webRoot
 }
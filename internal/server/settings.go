@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/config"
+)
+
+// SettingsManager reads and changes the configuration of the running process
+type SettingsManager interface {
+	Config() config.Config
+	// UpdateSettings changes the current settings with update, atomically with
+	// respect to other updates, and returns the new settings and the changes
+	UpdateSettings(update func(*config.Settings), persist bool) (config.Settings, []string, error)
+}
+
+// SetSettingsManager enables the /api/v1/config endpoint
+func (s *Server) SetSettingsManager(m SettingsManager) {
+	s.settings = m
+}
+
+// SettingsView holds the runtime settings in API form
+type SettingsView struct {
+	MinFreeSpace          int64          `json:"min_free_space"` // Bytes
+	MinTorrentsPerTracker int            `json:"min_torrents_per_tracker"`
+	TrackerMinimums       map[string]int `json:"tracker_minimums"`
+	Strategy              string         `json:"strategy"`
+	CheckInterval         string         `json:"check_interval"` // Go duration (e.g. "5m0s")
+	DryRun                bool           `json:"dry_run"`
+	LogLevel              string         `json:"log_level"`
+}
+
+// ConfigResponse is returned by GET /api/v1/config
+type ConfigResponse struct {
+	Settings SettingsView           `json:"settings"`
+	File     string                 `json:"file,omitempty"` // Config file, empty if none
+	Config   map[string]interface{} `json:"config"`         // Full configuration, secrets redacted
+}
+
+// SettingsRequest is the body of PUT /api/v1/config. Unset fields keep their value.
+type SettingsRequest struct {
+	MinFreeSpace          interface{}    `json:"min_free_space,omitempty"` // Bytes or size with unit ("500GB")
+	MinTorrentsPerTracker *int           `json:"min_torrents_per_tracker,omitempty"`
+	TrackerMinimums       map[string]int `json:"tracker_minimums,omitempty"` // Replaces all minimums, {} to remove them
+	Strategy              string         `json:"strategy,omitempty"`
+	CheckInterval         string         `json:"check_interval,omitempty"` // Go duration (e.g. "10m")
+	DryRun                *bool          `json:"dry_run,omitempty"`
+	LogLevel              string         `json:"log_level,omitempty"`
+	Persist               bool           `json:"persist,omitempty"` // Also write the settings to the config file
+}
+
+// SettingsResponse is returned by PUT /api/v1/config
+type SettingsResponse struct {
+	Settings  SettingsView `json:"settings"`
+	Changes   []string     `json:"changes"` // Changed settings, as "key: old -> new"
+	Persisted bool         `json:"persisted"`
+	Warning   string       `json:"warning,omitempty"` // Set when the changes are not saved to the config file
+}
+
+// unsavedWarning warns that changes not written to the config file are temporary
+const unsavedWarning = "Not saved to the config file: these changes are lost on restart and when the configuration is reloaded (config file change or SIGHUP)"
+
+// newSettingsView converts runtime settings to their API form
+func newSettingsView(s config.Settings) SettingsView {
+	minimums := make(map[string]int, len(s.TrackerMinimums))
+	for _, tm := range s.TrackerMinimums {
+		minimums[tm.Tracker] = tm.MinTorrents
+	}
+	return SettingsView{
+		MinFreeSpace:          s.MinFreeSpace,
+		MinTorrentsPerTracker: s.MinTorrentsPerTracker,
+		TrackerMinimums:       minimums,
+		Strategy:              s.Strategy,
+		CheckInterval:         s.CheckInterval.String(),
+		DryRun:                s.DryRun,
+		LogLevel:              s.LogLevel,
+	}
+}
+
+// handleV1Config returns the configuration or changes the runtime settings
+func (s *Server) handleV1Config(w http.ResponseWriter, r *http.Request) {
+	if s.settings == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Settings are not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cfg := s.settings.Config()
+		writeJSON(w, http.StatusOK, ConfigResponse{
+			Settings: newSettingsView(cfg.Settings()),
+			File:     cfg.File,
+			Config:   cfg.Redacted(),
+		})
+
+	case http.MethodPut:
+		var req SettingsRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 65536)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
+			return
+		}
+
+		var minFreeSpace int64
+		if req.MinFreeSpace != nil {
+			size, err := parseSizeValue(req.MinFreeSpace)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid min_free_space")
+				return
+			}
+			minFreeSpace = size
+		}
+		var interval time.Duration
+		if req.CheckInterval != "" {
+			d, err := time.ParseDuration(req.CheckInterval)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid check_interval")
+				return
+			}
+			interval = d
+		}
+
+		// Merged into the current settings by the manager, so that concurrent
+		// requests do not revert each other's changes
+		update := func(settings *config.Settings) {
+			if req.MinFreeSpace != nil {
+				settings.MinFreeSpace = minFreeSpace
+			}
+			if req.MinTorrentsPerTracker != nil {
+				settings.MinTorrentsPerTracker = *req.MinTorrentsPerTracker
+			}
+			if req.TrackerMinimums != nil {
+				settings.TrackerMinimums = make([]config.TrackerMinimum, 0, len(req.TrackerMinimums))
+				for tracker, minimum := range req.TrackerMinimums {
+					settings.TrackerMinimums = append(settings.TrackerMinimums, config.TrackerMinimum{Tracker: tracker, MinTorrents: minimum})
+				}
+				sort.Slice(settings.TrackerMinimums, func(i, j int) bool {
+					return settings.TrackerMinimums[i].Tracker < settings.TrackerMinimums[j].Tracker
+				})
+			}
+			if req.Strategy != "" {
+				settings.Strategy = req.Strategy
+			}
+			if req.CheckInterval != "" {
+				settings.CheckInterval = interval
+			}
+			if req.DryRun != nil {
+				settings.DryRun = *req.DryRun
+			}
+			if req.LogLevel != "" {
+				settings.LogLevel = req.LogLevel
+			}
+		}
+
+		settings, changes, err := s.settings.UpdateSettings(update, req.Persist)
+		if errors.Is(err, config.ErrInvalidSettings) {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to save settings: %v", err)
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to save settings to the config file")
+			return
+		}
+		if changes == nil {
+			changes = []string{}
+		}
+
+		resp := SettingsResponse{
+			Settings:  newSettingsView(settings),
+			Changes:   changes,
+			Persisted: req.Persist,
+		}
+		if !req.Persist && len(changes) > 0 {
+			resp.Warning = unsavedWarning
+		}
+		writeJSON(w, http.StatusOK, resp)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
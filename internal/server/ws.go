@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a message to a client
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is the time allowed to read the next pong from a client
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is the interval of pings, shorter than wsPongWait
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageSize is the maximum size of a message read from a client
+	wsMaxMessageSize = 4096
+)
+
+// wsClient is a WebSocket connection with its own send queue. A writer
+// goroutine drains the queue, so broadcasting never blocks on a slow client.
+type wsClient struct {
+	conn        *websocket.Conn
+	send        chan []byte
+	backlog     [][]byte // Messages sent before the queue, not subject to the slow client policy
+	broadcast   bool     // Receives the messages of broadcast (logs, cleanup results)
+	policy      string
+	done        chan struct{} // Closed when the client is closed
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+	dropped     atomic.Uint64
+}
+
+// addWSClient registers a new WebSocket client and starts its writer
+func (s *Server) addWSClient(conn *websocket.Conn, broadcast bool, backlog [][]byte) *wsClient {
+	client := &wsClient{
+		conn:      conn,
+		send:      make(chan []byte, s.wsConfig.SendQueueSize),
+		backlog:   backlog,
+		broadcast: broadcast,
+		policy:    s.wsConfig.SlowClientPolicy,
+		done:      make(chan struct{}),
+	}
+
+	s.clientMutex.Lock()
+	s.wsClients[client] = struct{}{}
+	s.clientMutex.Unlock()
+
+	s.wsWG.Add(1)
+	go s.writePump(client)
+
+	return client
+}
+
+// removeWSClient unregisters and closes a WebSocket client
+func (s *Server) removeWSClient(client *wsClient) {
+	s.clientMutex.Lock()
+	delete(s.wsClients, client)
+	s.clientMutex.Unlock()
+	client.close(websocket.CloseNormalClosure, "")
+}
+
+// close stops the client writer, which sends a close message with the given code
+func (c *wsClient) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// enqueue queues a message without blocking. When the queue is full, the
+// message is dropped or the client disconnected depending on the policy.
+func (c *wsClient) enqueue(msg []byte) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+	default:
+		if c.policy == config.SlowClientDisconnect {
+			c.close(websocket.ClosePolicyViolation, "client too slow")
+			return
+		}
+		c.dropped.Add(1)
+	}
+}
+
+// writePump writes the queued messages and pings to the connection.
+// It is the only goroutine writing messages to the connection.
+func (s *Server) writePump(c *wsClient) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close(websocket.CloseAbnormalClosure, "") // No-op if already closed
+		c.conn.Close()
+		s.wsWG.Done()
+		if c.closeCode == websocket.ClosePolicyViolation {
+			s.logger.Warnf("Disconnected WebSocket client %s: too slow", c.conn.RemoteAddr())
+		}
+		if dropped := c.dropped.Load(); dropped > 0 {
+			s.logger.Warnf("WebSocket client %s was too slow, %d messages dropped", c.conn.RemoteAddr(), dropped)
+		}
+	}()
+
+	write := func(messageType int, data []byte) error {
+		c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return c.conn.WriteMessage(messageType, data)
+	}
+
+	for _, msg := range c.backlog {
+		if err := write(websocket.TextMessage, msg); err != nil {
+			c.close(websocket.CloseAbnormalClosure, "")
+			return
+		}
+	}
+	c.backlog = nil
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := write(websocket.TextMessage, msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := write(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				msg := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+			}
+			return
+		}
+	}
+}
+
+// readPump reads from the connection until it is closed, keeping the read
+// deadline up to date with the client pongs. Messages are ignored.
+func (s *Server) readPump(c *wsClient) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// handleWebSocketLogs handles WebSocket connections for real-time logs
+func (s *Server) handleWebSocketLogs(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Errorf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	// Send existing logs first, then the broadcast messages
+	var backlog [][]byte
+	for _, log := range s.logger.GetLogs() {
+		if msg, err := json.Marshal(log); err == nil {
+			backlog = append(backlog, msg)
+		}
+	}
+	client := s.addWSClient(conn, true, backlog)
+	s.logger.Debug("New WebSocket client connected")
+
+	s.readPump(client)
+
+	s.removeWSClient(client)
+	s.logger.Debug("WebSocket client disconnected")
+}
+
+// BroadcastLog broadcasts a log entry to all connected WebSocket clients
+func (s *Server) BroadcastLog(log logger.LogEntry) {
+	s.broadcast(log)
+}
+
+// broadcast queues a JSON message for all log WebSocket clients. It never
+// blocks and never logs, since it is called for every log entry.
+func (s *Server) broadcast(v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	s.clientMutex.RLock()
+	defer s.clientMutex.RUnlock()
+	for client := range s.wsClients {
+		if client.broadcast {
+			client.enqueue(msg)
+		}
+	}
+}
+
+// closeWebSockets sends a close message to all WebSocket clients and waits
+// for their writers to finish, until the context expires
+func (s *Server) closeWebSockets(ctx context.Context) {
+	s.clientMutex.RLock()
+	for client := range s.wsClients {
+		client.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	s.clientMutex.RUnlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.wsWG.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+}
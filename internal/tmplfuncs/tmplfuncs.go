@@ -0,0 +1,20 @@
+// Package tmplfuncs holds the functions of the webhook templates, shared by
+// the configuration validation and the notification sinks so that both accept
+// the same templates
+package tmplfuncs
+
+import (
+	"encoding/json"
+	"text/template"
+)
+
+// Funcs returns the functions available in webhook templates
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		// json encodes a value, e.g. {"text": {{json .Message}}}
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+}
@@ -7,18 +7,28 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Celedhrim/btcleaner/pkg/models"
 )
 
+// CredentialSource returns up-to-date credentials, e.g. re-read from a secret file
+type CredentialSource func() (username, password string, err error)
+
+// RequestObserver is called after each RPC request with its method, duration and error
+type RequestObserver func(method string, duration time.Duration, err error)
+
 // Client is a Transmission RPC client
 type Client struct {
-	url      string
-	username string
-	password string
-	client   *http.Client
-	sessionID string
+	url         string
+	username    string
+	password    string
+	client      *http.Client
+	sessionID   string
+	credentials CredentialSource
+	observer    RequestObserver
+	mu          sync.RWMutex
 }
 
 // NewClient creates a new Transmission client
@@ -31,6 +41,54 @@ func NewClient(url, username, password string) *Client {
 	}
 }
 
+// SetCredentials replaces the credentials used for subsequent requests
+func (c *Client) SetCredentials(username, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username = username
+	c.password = password
+}
+
+// SetCredentialSource sets a source queried to refresh credentials when
+// Transmission rejects the current ones (HTTP 401)
+func (c *Client) SetCredentialSource(source CredentialSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = source
+}
+
+// SetRequestObserver sets a function called after each RPC request, e.g. to record metrics
+func (c *Client) SetRequestObserver(observer RequestObserver) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observer = observer
+}
+
+// refreshCredentials reloads credentials from the credential source.
+// It returns true if the credentials changed.
+func (c *Client) refreshCredentials() bool {
+	c.mu.RLock()
+	source := c.credentials
+	c.mu.RUnlock()
+	if source == nil {
+		return false
+	}
+
+	username, password, err := source()
+	if err != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if username == c.username && password == c.password {
+		return false
+	}
+	c.username = username
+	c.password = password
+	return true
+}
+
 // RPCRequest represents a Transmission RPC request
 type RPCRequest struct {
 	Method    string                 `json:"method"`
@@ -47,6 +105,20 @@ type RPCResponse struct {
 
 // doRequest performs an RPC request
 func (c *Client) doRequest(req *RPCRequest) (*RPCResponse, error) {
+	c.mu.RLock()
+	observer := c.observer
+	c.mu.RUnlock()
+
+	start := time.Now()
+	resp, err := c.doRequestRetry(req, true)
+	if observer != nil {
+		observer(req.Method, time.Since(start), err)
+	}
+	return resp, err
+}
+
+// doRequestRetry performs an RPC request, refreshing credentials once on 401 if allowed
+func (c *Client) doRequestRetry(req *RPCRequest, retryAuth bool) (*RPCResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -57,12 +129,16 @@ func (c *Client) doRequest(req *RPCRequest) (*RPCResponse, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	c.mu.RLock()
+	username, password, sessionID := c.username, c.password, c.sessionID
+	c.mu.RUnlock()
+
 	httpReq.Header.Set("Content-Type", "application/json")
-	if c.username != "" {
-		httpReq.SetBasicAuth(c.username, c.password)
+	if username != "" {
+		httpReq.SetBasicAuth(username, password)
 	}
-	if c.sessionID != "" {
-		httpReq.Header.Set("X-Transmission-Session-Id", c.sessionID)
+	if sessionID != "" {
+		httpReq.Header.Set("X-Transmission-Session-Id", sessionID)
 	}
 
 	resp, err := c.client.Do(httpReq)
@@ -73,8 +149,15 @@ func (c *Client) doRequest(req *RPCRequest) (*RPCResponse, error) {
 
 	// Handle 409 Conflict (session ID required)
 	if resp.StatusCode == 409 {
+		c.mu.Lock()
 		c.sessionID = resp.Header.Get("X-Transmission-Session-Id")
-		return c.doRequest(req) // Retry with session ID
+		c.mu.Unlock()
+		return c.doRequestRetry(req, retryAuth) // Retry with session ID
+	}
+
+	// Handle 401 Unauthorized (credentials may have been rotated)
+	if resp.StatusCode == 401 && retryAuth && c.refreshCredentials() {
+		return c.doRequestRetry(req, false)
 	}
 
 	if resp.StatusCode != 200 {
@@ -126,17 +209,26 @@ func (c *Client) GetSession() (map[string]interface{}, error) {
 	return resp.Arguments, nil
 }
 
-// GetFreeSpace returns free space in bytes for the download directory
-func (c *Client) GetFreeSpace() (int64, error) {
-	// First get the download directory
+// GetDownloadDir returns the download directory of Transmission
+func (c *Client) GetDownloadDir() (string, error) {
 	session, err := c.GetSession()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get session: %w", err)
+		return "", fmt.Errorf("failed to get session: %w", err)
 	}
 
 	downloadDir, ok := session["download-dir"].(string)
 	if !ok {
-		return 0, fmt.Errorf("download-dir not found in session")
+		return "", fmt.Errorf("download-dir not found in session")
+	}
+	return downloadDir, nil
+}
+
+// GetFreeSpace returns free space in bytes for the download directory
+func (c *Client) GetFreeSpace() (int64, error) {
+	// First get the download directory
+	downloadDir, err := c.GetDownloadDir()
+	if err != nil {
+		return 0, err
 	}
 
 	// Get free space for the download directory
@@ -162,6 +254,34 @@ func (c *Client) GetFreeSpace() (int64, error) {
 
 // GetTorrents returns all torrents with their metadata
 func (c *Client) GetTorrents() ([]models.Torrent, error) {
+	return c.getTorrents(nil)
+}
+
+// GetRecentlyActive returns the torrents added or active during the last
+// minute, as reported by Transmission
+func (c *Client) GetRecentlyActive() ([]models.Torrent, error) {
+	return c.getTorrents("recently-active")
+}
+
+// GetTorrentsByIDs returns the torrents matching the given IDs or hashes.
+// Unknown IDs and hashes are ignored.
+func (c *Client) GetTorrentsByIDs(ids []int, hashes []string) ([]models.Torrent, error) {
+	refs := make([]interface{}, 0, len(ids)+len(hashes))
+	for _, id := range ids {
+		refs = append(refs, id)
+	}
+	for _, hash := range hashes {
+		refs = append(refs, hash)
+	}
+	if len(refs) == 0 {
+		return []models.Torrent{}, nil
+	}
+	return c.getTorrents(refs)
+}
+
+// getTorrents fetches torrents, restricted to the given IDs/hashes (a list) or
+// to the "recently-active" ones if ids is not nil
+func (c *Client) getTorrents(ids interface{}) ([]models.Torrent, error) {
 	req := &RPCRequest{
 		Method: "torrent-get",
 		Arguments: map[string]interface{}{
@@ -174,9 +294,14 @@ func (c *Client) GetTorrents() ([]models.Torrent, error) {
 				"trackers",
 				"status",
 				"percentDone",
+				"sizeWhenDone",
+				"leftUntilDone",
 			},
 		},
 	}
+	if ids != nil {
+		req.Arguments["ids"] = ids
+	}
 
 	resp, err := c.doRequest(req)
 	if err != nil {
@@ -204,6 +329,12 @@ func (c *Client) GetTorrents() ([]models.Torrent, error) {
 			Status:      int(torrentMap["status"].(float64)),
 			PercentDone: torrentMap["percentDone"].(float64),
 		}
+		if v, ok := torrentMap["sizeWhenDone"].(float64); ok {
+			torrent.SizeWhenDone = int64(v)
+		}
+		if v, ok := torrentMap["leftUntilDone"].(float64); ok {
+			torrent.LeftUntilDone = int64(v)
+		}
 
 		// Extract tracker URLs
 		if trackersData, ok := torrentMap["trackers"].([]interface{}); ok {
@@ -0,0 +1,241 @@
+// Package watch starts cleanup checks on activity rather than on a timer:
+// files created in the download directory and torrents added to Transmission
+package watch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Celedhrim/btcleaner/internal/cleaner"
+	"github.com/Celedhrim/btcleaner/internal/config"
+	"github.com/Celedhrim/btcleaner/internal/logger"
+	"github.com/Celedhrim/btcleaner/internal/transmission"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher forecasts the free space whenever downloads start, and asks for a
+// cleanup check as soon as the pending downloads would bring it below the
+// minimum free space
+type Watcher struct {
+	client   *transmission.Client
+	clean    *cleaner.Cleaner
+	cfg      config.WatchConfig
+	logger   *logger.Logger
+	triggers chan struct{}
+
+	mu           sync.Mutex
+	lastActivity time.Time
+
+	known map[int]bool // Torrents already seen, owned by the watch loop
+}
+
+// New creates a watcher, started with Start
+func New(client *transmission.Client, clean *cleaner.Cleaner, cfg config.WatchConfig, log *logger.Logger) *Watcher {
+	return &Watcher{
+		client:   client,
+		clean:    clean,
+		cfg:      cfg,
+		logger:   log,
+		triggers: make(chan struct{}, 1),
+	}
+}
+
+// Triggers receives a value whenever a cleanup check should run
+func (w *Watcher) Triggers() <-chan struct{} {
+	return w.triggers
+}
+
+// LastActivity returns when a download was last seen starting or progressing,
+// zero if none was
+func (w *Watcher) LastActivity() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastActivity
+}
+
+// markActivity records download activity
+func (w *Watcher) markActivity() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastActivity = time.Now()
+}
+
+// Start watches the download directory and polls Transmission until the
+// returned function is called
+func (w *Watcher) Start() func() {
+	fsWatcher := w.watchDir()
+	var fsEvents <-chan fsnotify.Event
+	var fsErrors <-chan error
+	if fsWatcher != nil {
+		fsEvents, fsErrors = fsWatcher.Events, fsWatcher.Errors
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if fsWatcher != nil {
+			defer fsWatcher.Close()
+		}
+
+		poll := time.NewTicker(w.cfg.PollInterval)
+		defer poll.Stop()
+		// Armed when activity happened and the forecast is pending
+		debounce := time.NewTimer(w.cfg.Debounce)
+		defer debounce.Stop()
+		armed := true // Forecast once on start
+		arm := func() {
+			if !armed {
+				armed = true
+				debounce.Reset(w.cfg.Debounce)
+			}
+		}
+
+		w.poll(arm)
+		for {
+			select {
+			case event, ok := <-fsEvents:
+				if !ok {
+					fsEvents = nil
+					continue
+				}
+				if event.Op&fsnotify.Create != 0 {
+					w.logger.Debugf("New file in download directory: %s", event.Name)
+					w.markActivity()
+					arm()
+				}
+
+			case err, ok := <-fsErrors:
+				if !ok {
+					fsErrors = nil
+					continue
+				}
+				w.logger.Warnf("Download directory watch error: %v", err)
+
+			case <-poll.C:
+				w.poll(arm)
+
+			case <-debounce.C:
+				armed = false
+				w.forecast()
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// watchDir watches the top level of the download directory, the configured one
+// or Transmission's. It returns nil if the directory cannot be watched, e.g.
+// when Transmission runs on another host: torrents are still polled.
+func (w *Watcher) watchDir() *fsnotify.Watcher {
+	dir := w.cfg.DownloadDir
+	if dir == "" {
+		var err error
+		if dir, err = w.client.GetDownloadDir(); err != nil {
+			w.logger.Warnf("Cannot get the download directory, watching Transmission only: %v", err)
+			return nil
+		}
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		w.logger.Warnf("Cannot create download directory watcher, watching Transmission only: %v", err)
+		return nil
+	}
+	if err := fsWatcher.Add(dir); err != nil {
+		fsWatcher.Close()
+		w.logger.Infof("Download directory %s is not accessible, watching Transmission only: %v", dir, err)
+		return nil
+	}
+	w.logger.Infof("Watching download directory %s and torrents added to Transmission", dir)
+	return fsWatcher
+}
+
+// poll asks Transmission for the recently active torrents. New torrents arm
+// the forecast, torrents downloading count as activity.
+func (w *Watcher) poll(arm func()) {
+	if w.known == nil {
+		// Only torrents added from now on are new
+		torrents, err := w.client.GetTorrents()
+		if err != nil {
+			w.logger.Warnf("Failed to list torrents: %v", err)
+			return
+		}
+		w.known = make(map[int]bool, len(torrents))
+		for _, t := range torrents {
+			w.known[t.ID] = true
+		}
+		return
+	}
+
+	torrents, err := w.client.GetRecentlyActive()
+	if err != nil {
+		w.logger.Warnf("Failed to get recently active torrents: %v", err)
+		return
+	}
+	for _, t := range torrents {
+		if !w.known[t.ID] {
+			w.known[t.ID] = true
+			w.logger.Debugf("Torrent added: %s (%.2f GB)", t.Name, float64(t.TotalSize)/(1024*1024*1024))
+			w.markActivity()
+			arm()
+		} else if t.Downloading() {
+			w.markActivity()
+		}
+	}
+}
+
+// forecast compares the free space left once the current downloads complete
+// to the minimum free space, and triggers a check if it is below
+func (w *Watcher) forecast() {
+	free, pending, err := w.pending()
+	if err != nil {
+		w.logger.Warnf("Free space forecast failed: %v", err)
+		return
+	}
+
+	minFree := w.clean.Policy().MinFreeSpace
+	forecast := free - pending
+	w.logger.Debugf("Free space forecast: %.2f GB free, %.2f GB pending downloads",
+		float64(free)/(1024*1024*1024), float64(pending)/(1024*1024*1024))
+	if forecast >= minFree {
+		return
+	}
+
+	w.logger.Infof("Free space forecast %.2f GB is below the minimum %.2f GB, starting a cleanup check",
+		float64(forecast)/(1024*1024*1024), float64(minFree)/(1024*1024*1024))
+	select {
+	case w.triggers <- struct{}{}:
+	default: // A check is already pending
+	}
+}
+
+// pending returns the free space and the bytes the active downloads still
+// have to write. It also forgets the torrents that were removed.
+func (w *Watcher) pending() (free, pending int64, err error) {
+	free, err = w.clean.FreeSpace()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get free space: %w", err)
+	}
+	torrents, err := w.client.GetTorrents()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list torrents: %w", err)
+	}
+
+	known := make(map[int]bool, len(torrents))
+	for _, t := range torrents {
+		known[t.ID] = true
+	}
+	if w.known != nil {
+		w.known = known
+	}
+	return free, cleaner.PendingDownloads(torrents), nil
+}
@@ -13,6 +13,24 @@ type Torrent struct {
 	NormalizedTracker string `json:"normalizedTracker"`
 	Status       int       `json:"status"`
 	PercentDone  float64   `json:"percentDone"`
+	SizeWhenDone int64     `json:"sizeWhenDone"`  // Bytes of the wanted files
+	LeftUntilDone int64    `json:"leftUntilDone"` // Bytes still to download
+}
+
+// Transmission torrent statuses
+const (
+	StatusStopped      = 0
+	StatusCheckWait    = 1
+	StatusCheck        = 2
+	StatusDownloadWait = 3 // Queued to download
+	StatusDownload     = 4
+	StatusSeedWait     = 5
+	StatusSeed         = 6
+)
+
+// Downloading reports whether the torrent is downloading or queued to download
+func (t Torrent) Downloading() bool {
+	return (t.Status == StatusDownload || t.Status == StatusDownloadWait) && t.LeftUntilDone > 0
 }
 
 // TorrentsByAge implements sort.Interface for []Torrent based on AddedDate